@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"itv-task/config"
 	"itv-task/internal/handlers"
+	"itv-task/internal/models"
 	"itv-task/internal/repositories"
 	"itv-task/internal/services"
 	"itv-task/pkg/logger"
 	utils "itv-task/pkg/middleware"
+	pkgutils "itv-task/pkg/utils"
 	"log"
 	"net/http"
 	"os"
@@ -27,32 +29,219 @@ import (
 // @securityDefinitions.apikey ApiKeyAuth
 // @in header
 // @name Authorization
-func NewRouter(cfg *config.Config, movieHandler *handlers.MovieHandler, authHandler *handlers.AuthHandler) *gin.Engine {
+// @securityDefinitions.apikey ApiKeyHeader
+// @in header
+// @name X-API-Key
+func NewRouter(cfg *config.Config, keys *pkgutils.KeySet, revocations repositories.TokenRevocationStore, apiKeyService *services.APIKeyService,
+	movieHandler *handlers.MovieHandler, authHandler *handlers.AuthHandler, userHandler *handlers.UserHandler,
+	apiKeyHandler *handlers.APIKeyHandler, genreHandler *handlers.GenreHandler, personHandler *handlers.PersonHandler,
+	reviewHandler *handlers.ReviewHandler, listHandler *handlers.ListHandler, facetHandler *handlers.FacetHandler) (*gin.Engine, error) {
 	r := gin.Default()
 
+	// ClientIP feeds the login lockout, so forwarded headers only count from known proxies.
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+
 	// Middleware
 	r.Use(gin.Recovery())     // Handles panics
+	r.Use(utils.RequestID())  // Tags requests for the audit trail
 	r.Use(utils.AuthLogger()) // Example logging middleware
 
 	// Public Routes
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("swagger/doc.json")))
 	r.GET("/movies", movieHandler.GetAllMovies)
+	r.GET("/movies/search", movieHandler.SearchMovies)
+	r.GET("/movies/search/fuzzy", movieHandler.FuzzySearchMovies)
+	r.GET("/movies/facets", facetHandler.GetMovieFacets)
 	r.GET("/movies/:id", movieHandler.GetMovieByID)
+	r.GET("/movies/:id/credits", movieHandler.GetMovieCredits)
+	r.GET("/movies/:id/reviews", reviewHandler.GetMovieReviews)
+	r.GET("/reviews/:id", reviewHandler.GetReviewByID)
+	r.GET("/genres", genreHandler.GetAllGenres)
+	r.GET("/genres/:id", genreHandler.GetGenreByID)
+	r.GET("/people", personHandler.GetAllPeople)
+	r.GET("/people/:id", personHandler.GetPersonByID)
+	r.GET("/people/:id/filmography", personHandler.GetFilmography)
 	r.POST("/auth/login", authHandler.Login)
 	r.POST("/auth/refresh", authHandler.RefreshToken)
+	r.POST("/auth/register", authHandler.Register)
+	r.POST("/auth/2fa/verify", authHandler.VerifyTwoFactor)
+	r.GET("/.well-known/jwks.json", authHandler.JWKS)
+	r.GET("/auth/oidc/login", authHandler.OIDCLogin)
+	r.GET("/auth/oidc/callback", authHandler.OIDCCallback)
+
+	// Accepts a Bearer access token or an X-API-Key header
+	requireAuth := utils.AuthMiddleware(keys, revocations, apiKeyService)
 
 	// Protected Routes (Require Auth)
 	authRoutes := r.Group("/movies")
-	fmt.Println("cfg: ", cfg.JWTAccessSecret)
-	authRoutes.Use(utils.AuthMiddleware(cfg)) // Apply token validation
+	authRoutes.Use(requireAuth) // Apply token validation
+	{
+		authRoutes.POST("/", utils.RequirePermission(models.PermMoviesWrite), movieHandler.CreateMovie)
+		authRoutes.PUT("/:id", utils.RequirePermission(models.PermMoviesWrite), movieHandler.UpdateMovie)
+		authRoutes.PATCH("/:id", utils.RequirePermission(models.PermMoviesWrite), movieHandler.PatchMovie)
+		authRoutes.DELETE("/:id", utils.RequirePermission(models.PermMoviesDelete), movieHandler.DeleteMovie)
+		authRoutes.POST("/bulk-insert", utils.RequirePermission(models.PermMoviesBulk), movieHandler.BulkInsertMovies)
+		authRoutes.POST("/import", utils.RequirePermission(models.PermMoviesBulk), movieHandler.ImportMovies)
+		authRoutes.GET("/trash", utils.RequirePermission(models.PermMoviesDelete), movieHandler.GetTrash)
+		authRoutes.GET("/duplicates", utils.RequireRole(models.RoleAdmin), movieHandler.GetDuplicateClusters)
+		authRoutes.POST("/:id/restore", utils.RequirePermission(models.PermMoviesDelete), movieHandler.RestoreMovie)
+		authRoutes.GET("/:id/history", utils.RequirePermission(models.PermMoviesWrite), movieHandler.GetMovieHistory)
+		authRoutes.GET("/:id/history/:rev", utils.RequirePermission(models.PermMoviesWrite), movieHandler.GetMovieRevision)
+		authRoutes.POST("/:id/rollback", utils.RequirePermission(models.PermMoviesWrite), movieHandler.RollbackMovie)
+		authRoutes.PUT("/:id/credits", utils.RequirePermission(models.PermMoviesWrite), movieHandler.ReplaceMovieCredits)
+		authRoutes.POST("/:id/reviews", utils.RequirePermission(models.PermMoviesRead), reviewHandler.ReviewMovie)
+	}
+
+	genreRoutes := r.Group("/genres")
+	genreRoutes.Use(requireAuth)
+	{
+		genreRoutes.POST("", utils.RequirePermission(models.PermMoviesWrite), genreHandler.CreateGenre)
+		genreRoutes.PUT("/:id", utils.RequirePermission(models.PermMoviesWrite), genreHandler.UpdateGenre)
+		genreRoutes.DELETE("/:id", utils.RequirePermission(models.PermMoviesDelete), genreHandler.DeleteGenre)
+	}
+
+	personRoutes := r.Group("/people")
+	personRoutes.Use(requireAuth)
 	{
-		authRoutes.POST("/", movieHandler.CreateMovie)
-		authRoutes.PUT("/:id", movieHandler.UpdateMovie)
-		authRoutes.DELETE("/:id", movieHandler.DeleteMovie)
-		authRoutes.POST("/bulk-insert", movieHandler.BulkInsertMovies)
+		personRoutes.POST("", utils.RequirePermission(models.PermMoviesWrite), personHandler.CreatePerson)
+		personRoutes.PUT("/:id", utils.RequirePermission(models.PermMoviesWrite), personHandler.UpdatePerson)
+		personRoutes.DELETE("/:id", utils.RequirePermission(models.PermMoviesDelete), personHandler.DeletePerson)
 	}
 
-	return r
+	// Any signed-in user can review; the service only lets authors change their reviews
+	reviewRoutes := r.Group("/reviews")
+	reviewRoutes.Use(requireAuth)
+	{
+		reviewRoutes.PUT("/:id", reviewHandler.UpdateReview)
+		reviewRoutes.DELETE("/:id", reviewHandler.DeleteReview)
+	}
+
+	// Lists and the watch log belong to the signed-in user; the service checks ownership
+	listRoutes := r.Group("/lists")
+	listRoutes.Use(requireAuth)
+	{
+		listRoutes.GET("", listHandler.GetLists)
+		listRoutes.POST("", listHandler.CreateList)
+		listRoutes.GET("/:id", listHandler.GetList)
+		listRoutes.PUT("/:id", listHandler.UpdateList)
+		listRoutes.DELETE("/:id", listHandler.DeleteList)
+		listRoutes.POST("/:id/entries", listHandler.AddListEntry)
+		listRoutes.DELETE("/:id/entries/:movie_id", listHandler.RemoveListEntry)
+		listRoutes.PUT("/:id/order", listHandler.ReorderList)
+	}
+
+	watchedRoutes := r.Group("/watched")
+	watchedRoutes.Use(requireAuth)
+	{
+		watchedRoutes.GET("", listHandler.GetWatched)
+		watchedRoutes.POST("", listHandler.LogWatched)
+		watchedRoutes.DELETE("/:id", listHandler.DeleteWatched)
+	}
+
+	accountRoutes := r.Group("/auth")
+	accountRoutes.Use(requireAuth)
+	{
+		accountRoutes.POST("/change-password", authHandler.ChangePassword)
+		accountRoutes.POST("/logout", authHandler.Logout)
+		accountRoutes.POST("/logout-all", authHandler.LogoutAll)
+		accountRoutes.POST("/2fa/enroll", authHandler.EnrollTwoFactor)
+		accountRoutes.POST("/2fa/confirm", authHandler.ConfirmTwoFactor)
+		accountRoutes.POST("/2fa/disable", authHandler.DisableTwoFactor)
+		accountRoutes.GET("/audit-log", utils.RequireRole(models.RoleAdmin), authHandler.GetAuditLog)
+	}
+
+	// Admin Routes (Require Auth + admin role)
+	userRoutes := r.Group("/users")
+	userRoutes.Use(requireAuth, utils.RequireRole(models.RoleAdmin))
+	{
+		userRoutes.GET("", userHandler.GetAllUsers)
+		userRoutes.POST("", userHandler.CreateUser)
+		userRoutes.GET("/:id", userHandler.GetUserByID)
+		userRoutes.PUT("/:id", userHandler.UpdateUser)
+		userRoutes.PUT("/:id/role", userHandler.AssignRole)
+		userRoutes.DELETE("/:id", userHandler.DeleteUser)
+		userRoutes.POST("/:id/revoke-tokens", authHandler.RevokeUserTokens)
+	}
+
+	tokenRoutes := r.Group("/tokens")
+	tokenRoutes.Use(requireAuth, utils.RequireRole(models.RoleAdmin))
+	{
+		tokenRoutes.POST("/revoke", authHandler.RevokeToken)
+	}
+
+	apiKeyRoutes := r.Group("/api-keys")
+	apiKeyRoutes.Use(requireAuth, utils.RequireRole(models.RoleAdmin))
+	{
+		apiKeyRoutes.GET("", apiKeyHandler.GetAllAPIKeys)
+		apiKeyRoutes.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeyRoutes.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
+	}
+
+	return r, nil
+}
+
+// StartTokenRevocationCleanup periodically evicts expired denylist entries
+func StartTokenRevocationCleanup(lc fx.Lifecycle, store repositories.TokenRevocationStore) {
+	ticker := time.NewTicker(config.RevocationCleanupInterval)
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				for {
+					select {
+					case <-ticker.C:
+						if err := store.DeleteExpired(); err != nil {
+							log.Printf("❌ Failed to clean up revoked tokens: %v", err)
+						}
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			ticker.Stop()
+			close(done)
+			return nil
+		},
+	})
+}
+
+// StartTrashPurge periodically removes movies that have been in the trash for longer than
+// MOVIE_TRASH_RETENTION. A zero retention keeps them forever.
+func StartTrashPurge(lc fx.Lifecycle, cfg *config.Config, movieService *services.MovieService) {
+	if cfg.MovieTrashRetention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(config.TrashPurgeInterval)
+	done := make(chan struct{})
+
+	lc.Append(fx.Hook{
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				for {
+					select {
+					case <-ticker.C:
+						if _, err := movieService.PurgeTrash(cfg.MovieTrashRetention); err != nil {
+							log.Printf("❌ Failed to purge movie trash: %v", err)
+						}
+					case <-done:
+						return
+					}
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			ticker.Stop()
+			close(done)
+			return nil
+		},
+	})
 }
 
 // StartServer starts the HTTP server with Uber FX lifecycle
@@ -81,21 +270,61 @@ func StartServer(lc fx.Lifecycle, router *gin.Engine) {
 	})
 }
 
-func main() {
-	app := fx.New(
-		fx.Provide(
-			func() *config.Config { cfg := config.Load(); return &cfg }, // Provide config first
-		),
+// appOptions wires the application around the configuration, everything but the HTTP
+// server. The tests start it on its own and serve the router with httptest.
+func appOptions(cfg *config.Config) fx.Option {
+	return fx.Options(
+		fx.Supply(cfg),
 		config.DatabaseModule, // Ensure database module comes after config
 		fx.Provide(
 			func() logger.Logger { log := logger.New("itv", "Movies"); return log },
+			pkgutils.NewKeySet,
+			repositories.NewGenreRepository,
+			repositories.NewPersonRepository,
 			repositories.NewMovieRepository,
+			repositories.NewMovieRevisionRepository,
+			repositories.NewReviewRepository,
+			repositories.NewMovieListRepository,
+			repositories.NewWatchHistoryRepository,
 			services.NewMovieService,
 			handlers.NewMovieHandler,
+			services.NewFacetService,
+			handlers.NewFacetHandler,
+			services.NewGenreService,
+			handlers.NewGenreHandler,
+			services.NewPersonService,
+			handlers.NewPersonHandler,
+			services.NewReviewService,
+			handlers.NewReviewHandler,
+			services.NewListService,
+			handlers.NewListHandler,
+			repositories.NewUserRepository,
+			repositories.NewAuthTokenRepository,
+			repositories.NewTokenRevocationStore,
+			repositories.NewAuthAuditRepository,
+			services.NewLoginThrottle,
+			repositories.NewRecoveryCodeRepository,
+			services.NewTwoFactorService,
 			services.NewAuthService,
+			services.NewOIDCService,
 			handlers.NewAuthHandler,
+			services.NewUserService,
+			handlers.NewUserHandler,
+			repositories.NewAPIKeyRepository,
+			services.NewAPIKeyService,
+			handlers.NewAPIKeyHandler,
 			NewRouter,
 		),
+		fx.Invoke(func(userService *services.UserService) error { return userService.EnsureDefaultAdmin() }),
+		fx.Invoke(StartTokenRevocationCleanup),
+		fx.Invoke(StartTrashPurge),
+	)
+}
+
+func main() {
+	cfg := config.Load()
+	app := fx.New(
+		appOptions(&cfg),
 		fx.Invoke(StartServer), // Start server
 	)
 
@@ -0,0 +1,490 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"itv-task/config"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/fx"
+	"go.uber.org/fx/fxtest"
+	"gorm.io/gorm"
+)
+
+const (
+	testAdminUsername = "admin"
+	testAdminPassword = "password123"
+)
+
+// testConfig keeps every store in memory, so the tests need no database.
+func testConfig() *config.Config {
+	return &config.Config{
+		JWTAccessSecret:         "test-access-secret",
+		JWTRefreshSecret:        "test-refresh-secret",
+		JWTSigningAlgorithm:     "HS256",
+		AdminUsername:           testAdminUsername,
+		AdminPassword:           testAdminPassword,
+		LoginMaxFailures:        5,
+		LoginIPMaxFailures:      20,
+		LoginLockoutBase:        30 * time.Second,
+		LoginLockoutMax:         15 * time.Minute,
+		LoginFailureWindow:      15 * time.Minute,
+		TOTPIssuer:              "Movies",
+		MovieDuplicateThreshold: 0.6,
+		MovieFacetsCacheTTL:     30 * time.Second,
+		CursorSecret:            "test-cursor-secret",
+		MovieExpandMaxDepth:     2,
+		OIDCScopes:              "openid profile email groups",
+		OIDCGroupsClaim:         "groups",
+		OIDCDefaultRole:         "viewer",
+		MovieStore:              "memory",
+		TokenRevocationStore:    "memory",
+		ServiceName:             "movies_service",
+	}
+}
+
+// newTestServer starts the application as main wires it, minus the HTTP server, and
+// serves its router with httptest.
+func newTestServer(t *testing.T, cfg *config.Config) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	var router *gin.Engine
+	var db *gorm.DB
+	app := fxtest.New(t, fx.NopLogger, appOptions(cfg), fx.Populate(&router, &db))
+	app.RequireStart()
+	t.Cleanup(app.RequireStop)
+
+	if db != nil {
+		t.Fatal("memory stores connected to a database")
+	}
+
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return server
+}
+
+type testResponse struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// decode unmarshals the response body, failing the test if it is not JSON.
+func (r testResponse) decode(t *testing.T, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		t.Fatalf("decode %s: %v", r.body, err)
+	}
+}
+
+// call sends a JSON request; headers are given as name, value pairs.
+func call(t *testing.T, server *httptest.Server, method, path, token string, body interface{}, headers ...string) testResponse {
+	t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, server.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testResponse{status: resp.StatusCode, header: resp.Header, body: data}
+}
+
+// expectStatus fails the test unless the response has the given status.
+func expectStatus(t *testing.T, resp testResponse, status int) {
+	t.Helper()
+	if resp.status != status {
+		t.Fatalf("expected status %d, got %d: %s", status, resp.status, resp.body)
+	}
+}
+
+// login returns an access token for the credentials.
+func login(t *testing.T, server *httptest.Server, username, password string) string {
+	t.Helper()
+	resp := call(t, server, http.MethodPost, "/auth/login", "", map[string]string{"username": username, "password": password})
+	expectStatus(t, resp, http.StatusOK)
+
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	resp.decode(t, &tokens)
+	if tokens.AccessToken == "" {
+		t.Fatalf("login returned no access token: %s", resp.body)
+	}
+	return tokens.AccessToken
+}
+
+type testMovie struct {
+	ID       uint     `json:"id"`
+	Title    string   `json:"title"`
+	Director string   `json:"director"`
+	Year     int      `json:"year"`
+	Version  uint     `json:"version"`
+	Genres   []string `json:"genres"`
+}
+
+// createMovie creates a movie and looks it up again, since creating one does not return its ID.
+func createMovie(t *testing.T, server *httptest.Server, token, title string, year int) testMovie {
+	t.Helper()
+	resp := call(t, server, http.MethodPost, "/movies/", token,
+		map[string]interface{}{"title": title, "director": "Test Director", "year": year})
+	expectStatus(t, resp, http.StatusCreated)
+
+	var list struct {
+		Movies []testMovie `json:"movies"`
+	}
+	resp = call(t, server, http.MethodGet, "/movies?filter="+url.QueryEscape(fmt.Sprintf("title=%q", title)), "", nil)
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &list)
+	if len(list.Movies) != 1 {
+		t.Fatalf("expected to find %q once, got %s", title, resp.body)
+	}
+	return list.Movies[0]
+}
+
+func TestMovieLifecycle(t *testing.T) {
+	server := newTestServer(t, testConfig())
+	token := login(t, server, testAdminUsername, testAdminPassword)
+
+	movie := createMovie(t, server, token, "Inception", 2010)
+	createMovie(t, server, token, "Heat", 1995)
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/", token,
+		map[string]interface{}{"title": "Inception", "director": "Someone Else", "year": 2011}), http.StatusBadRequest)
+
+	resp := call(t, server, http.MethodGet, fmt.Sprintf("/movies/%d", movie.ID), "", nil)
+	expectStatus(t, resp, http.StatusOK)
+	etag := resp.header.Get("ETag")
+	if etag != `"1"` {
+		t.Fatalf("expected ETag \"1\", got %q", etag)
+	}
+	expectStatus(t, call(t, server, http.MethodGet, fmt.Sprintf("/movies/%d", movie.ID), "", nil, "If-None-Match", etag),
+		http.StatusNotModified)
+
+	update := map[string]interface{}{"title": "Inception", "director": "Christopher Nolan", "year": 2010}
+	resp = call(t, server, http.MethodPut, fmt.Sprintf("/movies/%d", movie.ID), token, update, "If-Match", etag)
+	expectStatus(t, resp, http.StatusOK)
+	if resp.header.Get("ETag") != `"2"` {
+		t.Fatalf("expected the update to return ETag \"2\", got %q", resp.header.Get("ETag"))
+	}
+	expectStatus(t, call(t, server, http.MethodPut, fmt.Sprintf("/movies/%d", movie.ID), token, update, "If-Match", etag),
+		http.StatusPreconditionFailed)
+
+	var list struct {
+		Movies []testMovie `json:"movies"`
+		Count  int         `json:"count"`
+	}
+	resp = call(t, server, http.MethodGet, "/movies?sort=-year&limit=1", "", nil)
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &list)
+	if list.Count != 2 || len(list.Movies) != 1 || list.Movies[0].Title != "Inception" {
+		t.Fatalf("unexpected first page: %s", resp.body)
+	}
+	resp = call(t, server, http.MethodGet, "/movies?filter="+url.QueryEscape(`director~"nolan"`), "", nil)
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &list)
+	if list.Count != 1 || list.Movies[0].ID != movie.ID {
+		t.Fatalf("unexpected filtered list: %s", resp.body)
+	}
+
+	expectStatus(t, call(t, server, http.MethodDelete, fmt.Sprintf("/movies/%d", movie.ID), token, nil), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodGet, fmt.Sprintf("/movies/%d", movie.ID), "", nil), http.StatusNotFound)
+
+	resp = call(t, server, http.MethodGet, "/movies/trash", token, nil)
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &list)
+	if list.Count != 1 || list.Movies[0].ID != movie.ID {
+		t.Fatalf("unexpected trash: %s", resp.body)
+	}
+	expectStatus(t, call(t, server, http.MethodGet, "/movies/trash?limit=0", token, nil), http.StatusBadRequest)
+	expectStatus(t, call(t, server, http.MethodGet, "/movies/trash?offset=-1", token, nil), http.StatusBadRequest)
+
+	// Only movies outside the trash hold on to their titles.
+	recreated := createMovie(t, server, token, "Inception", 2010)
+	expectStatus(t, call(t, server, http.MethodPost, fmt.Sprintf("/movies/%d/restore", movie.ID), token, nil), http.StatusConflict)
+	expectStatus(t, call(t, server, http.MethodDelete, fmt.Sprintf("/movies/%d?hard=true", recreated.ID), token, nil), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodPost, fmt.Sprintf("/movies/%d/restore", movie.ID), token, nil), http.StatusOK)
+
+	var history struct {
+		Revisions []struct {
+			Revision uint   `json:"revision"`
+			Action   string `json:"action"`
+			Actor    string `json:"actor_name"`
+		} `json:"revisions"`
+		Count int `json:"count"`
+	}
+	resp = call(t, server, http.MethodGet, fmt.Sprintf("/movies/%d/history", movie.ID), token, nil)
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &history)
+	if history.Count != 4 {
+		t.Fatalf("expected create, update, delete and restore revisions, got %s", resp.body)
+	}
+	for _, revision := range history.Revisions {
+		if revision.Actor != testAdminUsername {
+			t.Fatalf("revision %d was not attributed to the admin: %s", revision.Revision, resp.body)
+		}
+	}
+}
+
+func TestAccessControl(t *testing.T) {
+	server := newTestServer(t, testConfig())
+
+	movie := map[string]interface{}{"title": "Alien", "director": "Ridley Scott", "year": 1979}
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/", "", movie), http.StatusUnauthorized)
+
+	credentials := map[string]string{"username": "viewer", "password": "viewerpass"}
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/register", "", credentials), http.StatusCreated)
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/register", "", credentials), http.StatusConflict)
+
+	viewer := login(t, server, "viewer", "viewerpass")
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/", viewer, movie), http.StatusForbidden)
+	expectStatus(t, call(t, server, http.MethodGet, "/users", viewer, nil), http.StatusForbidden)
+
+	admin := login(t, server, testAdminUsername, testAdminPassword)
+	resp := call(t, server, http.MethodGet, "/users", admin, nil)
+	expectStatus(t, resp, http.StatusOK)
+	var users struct {
+		Count int `json:"count"`
+	}
+	resp.decode(t, &users)
+	if users.Count != 2 {
+		t.Fatalf("expected the admin and the viewer, got %s", resp.body)
+	}
+
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/login", "",
+		map[string]string{"username": "viewer", "password": "wrong-password"}), http.StatusUnauthorized)
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/login", "",
+		map[string]string{"username": "nobody", "password": "wrong-password"}), http.StatusUnauthorized)
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	server := newTestServer(t, testConfig())
+
+	resp := call(t, server, http.MethodPost, "/auth/login", "",
+		map[string]string{"username": testAdminUsername, "password": testAdminPassword})
+	expectStatus(t, resp, http.StatusOK)
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	resp.decode(t, &tokens)
+
+	first := tokens.RefreshToken
+	resp = call(t, server, http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": first})
+	expectStatus(t, resp, http.StatusOK)
+	resp.decode(t, &tokens)
+	if tokens.RefreshToken == first {
+		t.Fatal("refresh did not rotate the refresh token")
+	}
+
+	// Replaying the rotated token revokes the whole family, including its replacement.
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": first}),
+		http.StatusUnauthorized)
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}),
+		http.StatusUnauthorized)
+
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/logout-all", tokens.AccessToken, nil), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodGet, "/lists", tokens.AccessToken, nil), http.StatusUnauthorized)
+
+	// Logging in again right away, within the same second as the logout, must work.
+	fresh := login(t, server, testAdminUsername, testAdminPassword)
+	expectStatus(t, call(t, server, http.MethodGet, "/lists", fresh, nil), http.StatusOK)
+}
+
+func TestAccountChangesRevokeTokens(t *testing.T) {
+	server := newTestServer(t, testConfig())
+	admin := login(t, server, testAdminUsername, testAdminPassword)
+
+	createUser := func(username string) uint {
+		t.Helper()
+		resp := call(t, server, http.MethodPost, "/users", admin,
+			map[string]string{"username": username, "password": "userpass1", "role": "admin"})
+		expectStatus(t, resp, http.StatusCreated)
+		var user struct {
+			ID uint `json:"id"`
+		}
+		resp.decode(t, &user)
+		return user.ID
+	}
+
+	// A demoted admin loses admin access right away, not when the token expires.
+	demoted := createUser("demoted")
+	token := login(t, server, "demoted", "userpass1")
+	expectStatus(t, call(t, server, http.MethodGet, "/users", token, nil), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodPut, fmt.Sprintf("/users/%d/role", demoted), admin,
+		map[string]string{"role": "viewer"}), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodGet, "/users", token, nil), http.StatusUnauthorized)
+
+	reset := createUser("reset")
+	token = login(t, server, "reset", "userpass1")
+	expectStatus(t, call(t, server, http.MethodPut, fmt.Sprintf("/users/%d", reset), admin,
+		map[string]string{"password": "userpass2"}), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodGet, "/users", token, nil), http.StatusUnauthorized)
+
+	deleted := createUser("deleted")
+	resp := call(t, server, http.MethodPost, "/auth/login", "", map[string]string{"username": "deleted", "password": "userpass1"})
+	expectStatus(t, resp, http.StatusOK)
+	var tokens struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	resp.decode(t, &tokens)
+	expectStatus(t, call(t, server, http.MethodDelete, fmt.Sprintf("/users/%d", deleted), admin, nil), http.StatusOK)
+	expectStatus(t, call(t, server, http.MethodGet, "/users", tokens.AccessToken, nil), http.StatusUnauthorized)
+	expectStatus(t, call(t, server, http.MethodPost, "/auth/refresh", "", map[string]string{"refresh_token": tokens.RefreshToken}),
+		http.StatusUnauthorized)
+}
+
+func TestAPIKeys(t *testing.T) {
+	server := newTestServer(t, testConfig())
+	admin := login(t, server, testAdminUsername, testAdminPassword)
+
+	resp := call(t, server, http.MethodPost, "/api-keys", admin,
+		map[string]interface{}{"name": "ingest", "scopes": []string{"movies:read", "movies:write"}})
+	expectStatus(t, resp, http.StatusCreated)
+	var key struct {
+		ID  uint   `json:"id"`
+		Key string `json:"key"`
+	}
+	resp.decode(t, &key)
+
+	movie := map[string]interface{}{"title": "Arrival", "director": "Denis Villeneuve", "year": 2016}
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/", "", movie, "X-API-Key", key.Key), http.StatusCreated)
+	expectStatus(t, call(t, server, http.MethodDelete, "/movies/1", "", nil, "X-API-Key", key.Key), http.StatusForbidden)
+
+	expectStatus(t, call(t, server, http.MethodDelete, fmt.Sprintf("/api-keys/%d", key.ID), admin, nil), http.StatusOK)
+	movie["title"] = "Sicario"
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/", "", movie, "X-API-Key", key.Key), http.StatusUnauthorized)
+}
+
+// failLogins sends wrong-password logins for fresh usernames, so only the IP counter grows,
+// each claiming a different client in X-Forwarded-For.
+func failLogins(t *testing.T, server *httptest.Server, count int, prefix string) testResponse {
+	t.Helper()
+	var resp testResponse
+	for i := 0; i < count; i++ {
+		resp = call(t, server, http.MethodPost, "/auth/login", "",
+			map[string]string{"username": fmt.Sprintf("%s-%d", prefix, i), "password": "wrong-password"},
+			"X-Forwarded-For", fmt.Sprintf("203.0.113.%d", i+1))
+	}
+	return resp
+}
+
+func TestLoginIPThrottle(t *testing.T) {
+	t.Run("forwarded headers from clients are ignored", func(t *testing.T) {
+		cfg := testConfig()
+		server := newTestServer(t, cfg)
+
+		expectStatus(t, failLogins(t, server, cfg.LoginIPMaxFailures, "spoofed"), http.StatusUnauthorized)
+		resp := failLogins(t, server, 1, "spoofed-again")
+		expectStatus(t, resp, http.StatusTooManyRequests)
+		if resp.header.Get("Retry-After") == "" {
+			t.Fatal("lockout response has no Retry-After header")
+		}
+	})
+
+	t.Run("forwarded headers from trusted proxies count", func(t *testing.T) {
+		cfg := testConfig()
+		cfg.TrustedProxies = []string{"127.0.0.1/32", "::1"}
+		server := newTestServer(t, cfg)
+
+		expectStatus(t, failLogins(t, server, cfg.LoginIPMaxFailures+1, "proxied"), http.StatusUnauthorized)
+	})
+}
+
+func TestPossibleDuplicates(t *testing.T) {
+	server := newTestServer(t, testConfig())
+	token := login(t, server, testAdminUsername, testAdminPassword)
+	createMovie(t, server, token, "The Matrix", 1999)
+
+	var created struct {
+		Title              string `json:"title"`
+		PossibleDuplicates []struct {
+			Title   string      `json:"title"`
+			Matches []testMovie `json:"matches"`
+		} `json:"possible_duplicates"`
+	}
+	resp := call(t, server, http.MethodPost, "/movies/", token,
+		map[string]interface{}{"title": "Matrix, The", "director": "Lana Wachowski", "year": 1999})
+	expectStatus(t, resp, http.StatusCreated)
+	resp.decode(t, &created)
+	if created.Title != "Matrix, The" || len(created.PossibleDuplicates) != 1 ||
+		created.PossibleDuplicates[0].Matches[0].Title != "The Matrix" {
+		t.Fatalf("expected a warning about The Matrix, got %s", resp.body)
+	}
+
+	resp = call(t, server, http.MethodPost, "/movies/", token,
+		map[string]interface{}{"title": "Heat", "director": "Michael Mann", "year": 1995})
+	expectStatus(t, resp, http.StatusCreated)
+	if bytes.Contains(resp.body, []byte("possible_duplicates")) {
+		t.Fatalf("expected no warning for a new title, got %s", resp.body)
+	}
+
+	resp = call(t, server, http.MethodPost, "/movies/?reject_duplicates=true", token,
+		map[string]interface{}{"title": "Matrix The", "director": "Lana Wachowski", "year": 1999})
+	expectStatus(t, resp, http.StatusConflict)
+	if !bytes.Contains(resp.body, []byte(`"duplicates"`)) {
+		t.Fatalf("expected the near matches in the conflict, got %s", resp.body)
+	}
+	expectStatus(t, call(t, server, http.MethodPost, "/movies/?reject_duplicates=maybe", token,
+		map[string]interface{}{"title": "Thief", "director": "Michael Mann", "year": 1981}), http.StatusBadRequest)
+
+	var inserted struct {
+		Message            string            `json:"message"`
+		PossibleDuplicates []json.RawMessage `json:"possible_duplicates"`
+	}
+	resp = call(t, server, http.MethodPost, "/movies/bulk-insert", token, map[string]interface{}{"movies": []map[string]interface{}{
+		{"title": "Heat 2", "director": "Michael Mann", "year": 2025},
+		{"title": "Collateral", "director": "Michael Mann", "year": 2004},
+	}})
+	expectStatus(t, resp, http.StatusCreated)
+	resp.decode(t, &inserted)
+	if inserted.Message == "" || len(inserted.PossibleDuplicates) != 1 {
+		t.Fatalf("expected one warning for Heat 2, got %s", resp.body)
+	}
+
+	expectStatus(t, call(t, server, http.MethodGet, "/movies/search/fuzzy?title=matrix&limit=0", "", nil), http.StatusBadRequest)
+	expectStatus(t, call(t, server, http.MethodGet, "/movies/duplicates?offset=-1", token, nil), http.StatusBadRequest)
+	resp = call(t, server, http.MethodGet, "/movies/duplicates?limit=1", token, nil)
+	expectStatus(t, resp, http.StatusOK)
+	var clusters struct {
+		Clusters []json.RawMessage `json:"clusters"`
+		Count    int               `json:"count"`
+	}
+	resp.decode(t, &clusters)
+	if clusters.Count != 2 || len(clusters.Clusters) != 1 {
+		t.Fatalf("expected the first of two clusters, got %s", resp.body)
+	}
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"itv-task/config"
+	"itv-task/pkg/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testOIDCClientID    = "movies"
+	testOIDCRedirectURL = "http://localhost/auth/oidc/callback"
+	testOIDCKeyID       = "idp-key"
+)
+
+// testIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint that
+// checks the PKCE verifier. The authorization endpoint is simulated by authorize.
+type testIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu sync.Mutex
+	// signingKey signs ID tokens; set it to another key to forge signatures.
+	signingKey *rsa.PrivateKey
+	// nonce replaces the nonce of the authorization request when set.
+	nonce    string
+	subject  string
+	username string
+	groups   []interface{}
+	codes    map[string]testIdPCode
+}
+
+type testIdPCode struct {
+	challenge string
+	nonce     string
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	idp := &testIdP{t: t, key: key, signingKey: key, codes: map[string]testIdPCode{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// user sets who signs in at the provider next.
+func (idp *testIdP) user(subject, username string, groups ...interface{}) {
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.subject, idp.username, idp.groups = subject, username, groups
+}
+
+func (idp *testIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(oidc.Discovery{
+		Issuer:                idp.server.URL,
+		AuthorizationEndpoint: idp.server.URL + "/authorize",
+		TokenEndpoint:         idp.server.URL + "/token",
+		JWKSURI:               idp.server.URL + "/jwks",
+	})
+}
+
+func (idp *testIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": testOIDCKeyID,
+		"use": "sig",
+		"n":   encode(idp.key.N.Bytes()),
+		"e":   encode(big.NewInt(int64(idp.key.E)).Bytes()),
+	}}})
+}
+
+// authorize plays the provider's login page: it checks the authorization request and
+// returns the code the browser is redirected back with.
+func (idp *testIdP) authorize(query url.Values) string {
+	idp.t.Helper()
+	if query.Get("client_id") != testOIDCClientID || query.Get("redirect_uri") != testOIDCRedirectURL {
+		idp.t.Fatalf("unexpected client in authorization request: %v", query)
+	}
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") == "" {
+		idp.t.Fatalf("authorization request is not a PKCE code request: %v", query)
+	}
+
+	code := "code-" + query.Get("state")
+	idp.mu.Lock()
+	idp.codes[code] = testIdPCode{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+	return code
+}
+
+func (idp *testIdP) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != testOIDCClientID || r.PostForm.Get("redirect_uri") != testOIDCRedirectURL {
+		http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+		return
+	}
+
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	code, ok := idp.codes[r.PostForm.Get("code")]
+	delete(idp.codes, r.PostForm.Get("code"))
+	if !ok || oidc.CodeChallenge(r.PostForm.Get("code_verifier")) != code.challenge {
+		http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+		return
+	}
+
+	nonce := code.nonce
+	if idp.nonce != "" {
+		nonce = idp.nonce
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                testOIDCClientID,
+		"sub":                idp.subject,
+		"preferred_username": idp.username,
+		"groups":             idp.groups,
+		"nonce":              nonce,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = testOIDCKeyID
+	idToken, err := token.SignedString(idp.signingKey)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(oidc.TokenResponse{AccessToken: "idp-access-token", TokenType: "Bearer", IDToken: idToken})
+}
+
+func testOIDCConfig(idp *testIdP) *config.Config {
+	cfg := testConfig()
+	cfg.OIDCIssuerURL = idp.server.URL
+	cfg.OIDCClientID = testOIDCClientID
+	cfg.OIDCClientSecret = "secret"
+	cfg.OIDCRedirectURL = testOIDCRedirectURL
+	cfg.OIDCRoleMapping = "movies-editors=editor,movies-admins=admin"
+	return cfg
+}
+
+// startOIDCLogin opens /auth/oidc/login without following the redirect and returns the
+// authorization request and the state cookie it set.
+func startOIDCLogin(t *testing.T, server *httptest.Server) (url.Values, *http.Cookie) {
+	t.Helper()
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(server.URL + "/auth/oidc/login")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect to the provider, got %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == "oidc_state" {
+			return location.Query(), cookie
+		}
+	}
+	t.Fatal("login did not set the oidc_state cookie")
+	return nil, nil
+}
+
+// oidcCallback returns to the service as the provider's redirect would; cookie may be nil.
+func oidcCallback(t *testing.T, server *httptest.Server, state, code string, cookie *http.Cookie) testResponse {
+	t.Helper()
+	path := "/auth/oidc/callback?" + url.Values{"state": {state}, "code": {code}}.Encode()
+	if cookie == nil {
+		return call(t, server, http.MethodGet, path, "", nil)
+	}
+	return call(t, server, http.MethodGet, path, "", nil, "Cookie", cookie.Name+"="+cookie.Value)
+}
+
+// oidcLogin runs the whole flow and returns the callback response.
+func oidcLogin(t *testing.T, server *httptest.Server, idp *testIdP) testResponse {
+	t.Helper()
+	query, cookie := startOIDCLogin(t, server)
+	code := idp.authorize(query)
+	return oidcCallback(t, server, query.Get("state"), code, cookie)
+}
+
+// tokenRole reads the role claim of an access token issued by the service.
+func tokenRole(t *testing.T, resp testResponse) (string, string) {
+	t.Helper()
+	var tokens struct {
+		AccessToken string `json:"access_token"`
+	}
+	resp.decode(t, &tokens)
+
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokens.AccessToken, claims); err != nil {
+		t.Fatalf("parse access token %q: %v", tokens.AccessToken, err)
+	}
+	role, _ := claims["role"].(string)
+	return tokens.AccessToken, role
+}
+
+func TestOIDCLogin(t *testing.T) {
+	idp := newTestIdP(t)
+	server := newTestServer(t, testOIDCConfig(idp))
+
+	t.Run("state cookie", func(t *testing.T) {
+		_, cookie := startOIDCLogin(t, server)
+		if !cookie.HttpOnly || cookie.Path != "/auth/oidc" || cookie.SameSite != http.SameSiteLaxMode {
+			t.Fatalf("state cookie is not HttpOnly, SameSite=Lax and scoped to /auth/oidc: %+v", cookie)
+		}
+	})
+
+	t.Run("groups map to roles", func(t *testing.T) {
+		idp.user("alice-subject", "alice", "staff", "movies-editors")
+		resp := oidcLogin(t, server, idp)
+		expectStatus(t, resp, http.StatusOK)
+		token, role := tokenRole(t, resp)
+		if role != "editor" {
+			t.Fatalf("expected the editor role, got %q", role)
+		}
+		expectStatus(t, call(t, server, http.MethodPost, "/movies/", token,
+			map[string]interface{}{"title": "Arrival", "director": "Denis Villeneuve", "year": 2016}), http.StatusCreated)
+
+		// The highest mapped role wins, and it is re-synced on the next login.
+		idp.user("alice-subject", "alice", "movies-editors", "movies-admins")
+		resp = oidcLogin(t, server, idp)
+		expectStatus(t, resp, http.StatusOK)
+		if _, role := tokenRole(t, resp); role != "admin" {
+			t.Fatalf("expected the admin role after the group change, got %q", role)
+		}
+	})
+
+	t.Run("unmapped groups get the default role", func(t *testing.T) {
+		idp.user("bob-subject", "bob", "staff")
+		resp := oidcLogin(t, server, idp)
+		expectStatus(t, resp, http.StatusOK)
+		token, role := tokenRole(t, resp)
+		if role != "viewer" {
+			t.Fatalf("expected the default viewer role, got %q", role)
+		}
+		expectStatus(t, call(t, server, http.MethodPost, "/movies/", token,
+			map[string]interface{}{"title": "Sicario", "director": "Denis Villeneuve", "year": 2015}), http.StatusForbidden)
+	})
+
+	t.Run("local accounts are not taken over", func(t *testing.T) {
+		idp.user("admin-subject", testAdminUsername, "staff")
+		resp := oidcLogin(t, server, idp)
+		expectStatus(t, resp, http.StatusOK)
+		if _, role := tokenRole(t, resp); role != "viewer" {
+			t.Fatalf("IdP user named like the local admin got role %q", role)
+		}
+		login(t, server, testAdminUsername, testAdminPassword)
+	})
+
+	t.Run("missing state cookie", func(t *testing.T) {
+		idp.user("alice-subject", "alice", "movies-editors")
+		query, _ := startOIDCLogin(t, server)
+		code := idp.authorize(query)
+		expectStatus(t, oidcCallback(t, server, query.Get("state"), code, nil), http.StatusBadRequest)
+		// The state was used up by the rejected callback.
+		_, cookie := startOIDCLogin(t, server)
+		expectStatus(t, oidcCallback(t, server, query.Get("state"), code, cookie), http.StatusBadRequest)
+	})
+
+	t.Run("login CSRF", func(t *testing.T) {
+		// The attacker starts a login and signs in at the provider, then makes the victim's
+		// browser, which holds the cookie of its own login, follow the attacker's callback.
+		idp.user("mallory-subject", "mallory", "movies-editors")
+		attackerQuery, _ := startOIDCLogin(t, server)
+		attackerCode := idp.authorize(attackerQuery)
+		_, victimCookie := startOIDCLogin(t, server)
+		expectStatus(t, oidcCallback(t, server, attackerQuery.Get("state"), attackerCode, victimCookie), http.StatusBadRequest)
+	})
+
+	t.Run("unknown state", func(t *testing.T) {
+		cookie := &http.Cookie{Name: "oidc_state", Value: "0123456789abcdef"}
+		expectStatus(t, oidcCallback(t, server, cookie.Value, "code", cookie), http.StatusBadRequest)
+	})
+
+	t.Run("code from another login fails PKCE", func(t *testing.T) {
+		idp.user("alice-subject", "alice", "movies-editors")
+		first, _ := startOIDCLogin(t, server)
+		code := idp.authorize(first)
+		second, cookie := startOIDCLogin(t, server)
+		expectStatus(t, oidcCallback(t, server, second.Get("state"), code, cookie), http.StatusUnauthorized)
+	})
+
+	t.Run("nonce mismatch", func(t *testing.T) {
+		idp.user("alice-subject", "alice", "movies-editors")
+		idp.mu.Lock()
+		idp.nonce = "replayed-nonce"
+		idp.mu.Unlock()
+		defer func() {
+			idp.mu.Lock()
+			idp.nonce = ""
+			idp.mu.Unlock()
+		}()
+		expectStatus(t, oidcLogin(t, server, idp), http.StatusUnauthorized)
+	})
+
+	t.Run("forged signature", func(t *testing.T) {
+		forger, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		idp.user("alice-subject", "alice", "movies-admins")
+		idp.mu.Lock()
+		idp.signingKey = forger
+		idp.mu.Unlock()
+		defer func() {
+			idp.mu.Lock()
+			idp.signingKey = idp.key
+			idp.mu.Unlock()
+		}()
+		expectStatus(t, oidcLogin(t, server, idp), http.StatusUnauthorized)
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		expectStatus(t, call(t, server, http.MethodGet, "/auth/oidc/callback?error=access_denied", "", nil), http.StatusUnauthorized)
+	})
+}
+
+func TestOIDCWithoutDefaultRole(t *testing.T) {
+	idp := newTestIdP(t)
+	cfg := testOIDCConfig(idp)
+	cfg.OIDCDefaultRole = ""
+	server := newTestServer(t, cfg)
+
+	idp.user("carol-subject", "carol", "staff")
+	expectStatus(t, oidcLogin(t, server, idp), http.StatusForbidden)
+
+	idp.user("carol-subject", "carol", "staff", "movies-editors")
+	resp := oidcLogin(t, server, idp)
+	expectStatus(t, resp, http.StatusOK)
+	if _, role := tokenRole(t, resp); role != "editor" {
+		t.Fatalf("expected the editor role, got %q", role)
+	}
+}
+
+func TestOIDCDiscovery(t *testing.T) {
+	idp := newTestIdP(t)
+
+	// The issuer in the metadata has to match the configured one exactly.
+	cfg := testOIDCConfig(idp)
+	cfg.OIDCIssuerURL = idp.server.URL + "/"
+	server := newTestServer(t, cfg)
+	expectStatus(t, call(t, server, http.MethodGet, "/auth/oidc/login", "", nil), http.StatusBadGateway)
+
+	cfg = testOIDCConfig(idp)
+	cfg.OIDCIssuerURL = ""
+	server = newTestServer(t, cfg)
+	expectStatus(t, call(t, server, http.MethodGet, "/auth/oidc/login", "", nil), http.StatusNotFound)
+
+	server = newTestServer(t, testOIDCConfig(idp))
+	query, _ := startOIDCLogin(t, server)
+	if query.Get("scope") != "openid profile email groups" || query.Get("state") == "" || query.Get("nonce") == "" {
+		t.Fatalf("unexpected authorization request: %v", query)
+	}
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/cast"
@@ -18,6 +20,64 @@ type Config struct {
 	JWTAccessSecret  string
 	JWTRefreshSecret string
 
+	// JWTSigningAlgorithm is HS256 (shared secrets), RS256 or EdDSA (PEM key files).
+	JWTSigningAlgorithm string
+	JWTSigningKeyFile   string
+	JWTSigningKeyID     string
+	// JWTVerificationKeys lists extra public keys as "kid=path,kid=path" for key rotation.
+	JWTVerificationKeys string
+
+	AdminUsername string
+	AdminPassword string
+
+	// Brute-force protection for /auth/login
+	LoginMaxFailures   int           // Failures per username before it is locked
+	LoginIPMaxFailures int           // Failures per client IP before it is locked
+	LoginLockoutBase   time.Duration // First lockout; doubles with every further failure
+	LoginLockoutMax    time.Duration
+	LoginFailureWindow time.Duration // Failures are forgotten after this long without one
+
+	// TrustedProxies lists the proxy IPs or CIDRs whose X-Forwarded-For / X-Real-IP headers
+	// name the client. Empty trusts none, so the client IP is always the connection's peer;
+	// otherwise anyone could pick a fresh IP per request and dodge the IP lockout.
+	TrustedProxies []string
+
+	TOTPIssuer string // Account issuer shown in authenticator apps
+
+	// MovieTrashRetention is how long deleted movies stay in the trash before they are
+	// purged; zero keeps them forever.
+	MovieTrashRetention time.Duration
+	// MovieDuplicateThreshold is the title similarity, from 0 to 1, from which a new movie
+	// is reported as a possible duplicate of an existing one.
+	MovieDuplicateThreshold float64
+	// MovieFacetsCacheTTL is how long facet counts are cached per filter; zero disables the cache.
+	MovieFacetsCacheTTL time.Duration
+	// CursorSecret signs the pagination cursors of movie listings.
+	CursorSecret string
+	// MovieExpandMaxDepth is how many levels deep ?expand= can embed related data, as in
+	// credits.person; zero disables expansion.
+	MovieExpandMaxDepth int
+
+	// OIDC login is enabled when OIDCIssuerURL is set.
+	OIDCIssuerURL    string
+	OIDCClientID     string
+	OIDCClientSecret string
+	OIDCRedirectURL  string
+	OIDCScopes       string
+	OIDCGroupsClaim  string
+	// OIDCRoleMapping maps IdP groups to local roles as "group=role,group=role".
+	OIDCRoleMapping string
+	// OIDCDefaultRole is given to users without a mapped group; empty denies them.
+	OIDCDefaultRole string
+
+	// MovieStore selects where movies and the rest of the application data, accounts and
+	// tokens included, are kept: "postgres" (default) or "memory", which needs no database
+	// and loses everything on restart.
+	MovieStore string
+	// TokenRevocationStore selects the access token denylist backend: "postgres" or
+	// "memory". It defaults to MovieStore.
+	TokenRevocationStore string
+
 	ServiceName string
 }
 
@@ -29,6 +89,8 @@ func Load() Config {
 		fmt.Println("Successfully loaded .env file")
 	}
 
+	movieStore := cast.ToString(getOrDefault("MOVIE_STORE", "postgres"))
+
 	return Config{
 		PostgresHost:     cast.ToString(getOrDefault("POSTGRES_HOST", "localhost1")),
 		PostgresPort:     cast.ToInt(getOrDefault("POSTGRES_PORT", 5432)),
@@ -38,10 +100,51 @@ func Load() Config {
 		JWTAccessSecret:  cast.ToString(getOrDefault("JWT_ACCESS_SECRET", "access_secret")),
 		JWTRefreshSecret: cast.ToString(getOrDefault("JWT_REFRESH_SECRET", "refresh_secret")),
 
+		JWTSigningAlgorithm: cast.ToString(getOrDefault("JWT_SIGNING_ALGORITHM", "HS256")),
+		JWTSigningKeyFile:   cast.ToString(getOrDefault("JWT_SIGNING_KEY_FILE", "")),
+		JWTSigningKeyID:     cast.ToString(getOrDefault("JWT_SIGNING_KEY_ID", "")),
+		JWTVerificationKeys: cast.ToString(getOrDefault("JWT_VERIFICATION_KEYS", "")),
+
+		AdminUsername: cast.ToString(getOrDefault("ADMIN_USERNAME", "admin")),
+		AdminPassword: cast.ToString(getOrDefault("ADMIN_PASSWORD", "password123")),
+
+		LoginMaxFailures:   cast.ToInt(getOrDefault("LOGIN_MAX_FAILURES", 5)),
+		LoginIPMaxFailures: cast.ToInt(getOrDefault("LOGIN_IP_MAX_FAILURES", 20)),
+		LoginLockoutBase:   cast.ToDuration(getOrDefault("LOGIN_LOCKOUT_BASE", "30s")),
+		LoginLockoutMax:    cast.ToDuration(getOrDefault("LOGIN_LOCKOUT_MAX", "15m")),
+		LoginFailureWindow: cast.ToDuration(getOrDefault("LOGIN_FAILURE_WINDOW", "15m")),
+
+		TrustedProxies: splitList(cast.ToString(getOrDefault("TRUSTED_PROXIES", ""))),
+
+		TOTPIssuer: cast.ToString(getOrDefault("TOTP_ISSUER", "Movies")),
+
+		MovieTrashRetention:     cast.ToDuration(getOrDefault("MOVIE_TRASH_RETENTION", "720h")),
+		MovieDuplicateThreshold: cast.ToFloat64(getOrDefault("MOVIE_DUPLICATE_THRESHOLD", 0.6)),
+		MovieFacetsCacheTTL:     cast.ToDuration(getOrDefault("MOVIE_FACETS_CACHE_TTL", "30s")),
+		CursorSecret:            cast.ToString(getOrDefault("CURSOR_SECRET", "cursor_secret")),
+		MovieExpandMaxDepth:     cast.ToInt(getOrDefault("MOVIE_EXPAND_MAX_DEPTH", 2)),
+
+		OIDCIssuerURL:    cast.ToString(getOrDefault("OIDC_ISSUER_URL", "")),
+		OIDCClientID:     cast.ToString(getOrDefault("OIDC_CLIENT_ID", "")),
+		OIDCClientSecret: cast.ToString(getOrDefault("OIDC_CLIENT_SECRET", "")),
+		OIDCRedirectURL:  cast.ToString(getOrDefault("OIDC_REDIRECT_URL", "http://localhost:8080/auth/oidc/callback")),
+		OIDCScopes:       cast.ToString(getOrDefault("OIDC_SCOPES", "openid profile email groups")),
+		OIDCGroupsClaim:  cast.ToString(getOrDefault("OIDC_GROUPS_CLAIM", "groups")),
+		OIDCRoleMapping:  cast.ToString(getOrDefault("OIDC_ROLE_MAPPING", "")),
+		OIDCDefaultRole:  cast.ToString(getOrDefault("OIDC_DEFAULT_ROLE", "viewer")),
+
+		MovieStore:           movieStore,
+		TokenRevocationStore: cast.ToString(getOrDefault("TOKEN_REVOCATION_STORE", movieStore)),
+
 		ServiceName: cast.ToString(getOrDefault("SERVICE_NAME", "movies_service")),
 	}
 }
 
+// UsesDatabase reports whether any store is configured to live in Postgres.
+func (c *Config) UsesDatabase() bool {
+	return c.MovieStore != "memory" || c.TokenRevocationStore != "memory"
+}
+
 // getOrDefault returns environment variable value or a default
 func getOrDefault(key string, defaultValue interface{}) interface{} {
 	if value := os.Getenv(key); value != "" {
@@ -49,3 +152,14 @@ func getOrDefault(key string, defaultValue interface{}) interface{} {
 	}
 	return defaultValue
 }
+
+// splitList parses a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -5,4 +5,13 @@ import "time"
 const (
 	AccessTokenTTL  = time.Hour * 24
 	RefreshTokenTTL = time.Hour * 24 * 7
+
+	// TwoFactorChallengeTTL is how long a user has to enter the TOTP code after the password.
+	TwoFactorChallengeTTL = time.Minute * 5
+
+	// RevocationCleanupInterval is how often expired denylist entries are evicted.
+	RevocationCleanupInterval = time.Minute * 10
+
+	// TrashPurgeInterval is how often movies past MOVIE_TRASH_RETENTION are purged.
+	TrashPurgeInterval = time.Hour
 )
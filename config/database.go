@@ -18,16 +18,28 @@ var DatabaseModule = fx.Module("database",
 	fx.Provide(NewDatabase),
 )
 
-// NewDatabase initializes the database connection
+// NewDatabase initializes the database connection. It connects and migrates only if a
+// store is configured to use Postgres, and returns nil otherwise.
 func NewDatabase(cfg *Config) *gorm.DB {
+	if !cfg.UsesDatabase() {
+		log.Println("ℹ️ All stores are in memory, not connecting to a database")
+		return nil
+	}
+
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%v sslmode=disable",
 		cfg.PostgresHost, cfg.PostgresUser, cfg.PostgresPassword, cfg.PostgresDatabase, cfg.PostgresPort)
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true}) // Unique violations become gorm.ErrDuplicatedKey
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to database: %v", err)
 	}
-	db.AutoMigrate(models.Movie{})
+	db.AutoMigrate(models.Movie{}, models.Person{}, models.MovieCredit{}, models.User{}, models.AuthToken{}, models.RevokedToken{}, models.UserTokenCutoff{}, models.APIKey{}, models.AuthAuditLog{}, models.RecoveryCode{}, models.MovieRevision{}, models.Genre{}, models.MovieGenre{}, models.Review{},
+		models.MovieList{}, models.MovieListEntry{}, models.WatchedEntry{})
+	migrateUserRoles(db)
+	migrateMovieTitleIndex(db)
+	migrateDirectorCredits(db)
+	migrateMovieSearch(db)
+	migrateMovieTitleTrigrams(db)
 
 	log.Println("✅ Connected to database")
 	DB = db
@@ -42,3 +54,82 @@ func NewDatabase(cfg *Config) *gorm.DB {
 	sqlDB.SetConnMaxLifetime(5 * time.Minute) // Time a connection can be reused
 	return db
 }
+
+// migrateUserRoles converts the legacy is_admin flag into the role column.
+func migrateUserRoles(db *gorm.DB) {
+	if !db.Migrator().HasColumn(&models.User{}, "is_admin") {
+		return
+	}
+	if err := db.Exec("UPDATE users SET role = ? WHERE is_admin", models.RoleAdmin).Error; err != nil {
+		log.Fatalf("❌ Failed to migrate user roles: %v", err)
+	}
+	if err := db.Migrator().DropColumn(&models.User{}, "is_admin"); err != nil {
+		log.Fatalf("❌ Failed to drop is_admin column: %v", err)
+	}
+}
+
+// migrateMovieTitleIndex drops the old title index, which also covered soft-deleted movies,
+// now that idx_movies_title_active enforces uniqueness outside the trash.
+func migrateMovieTitleIndex(db *gorm.DB) {
+	if !db.Migrator().HasIndex(&models.Movie{}, "idx_movies_title") {
+		return
+	}
+	if err := db.Migrator().DropIndex(&models.Movie{}, "idx_movies_title"); err != nil {
+		log.Fatalf("❌ Failed to drop idx_movies_title: %v", err)
+	}
+}
+
+// migrateDirectorCredits turns director names into people with director credits, for
+// movies written before credits existed. Movies that already have a director credit are
+// skipped, so it is a no-op once every director has been carried over.
+func migrateDirectorCredits(db *gorm.DB) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`INSERT INTO people (name, bio, created_at, updated_at)
+			SELECT DISTINCT m.director, '', NOW(), NOW() FROM movies m
+			WHERE m.director <> ''
+				AND NOT EXISTS (SELECT 1 FROM people p WHERE p.name = m.director)
+				AND NOT EXISTS (SELECT 1 FROM movie_credits c WHERE c.movie_id = m.id AND c.role = ?)`,
+			models.CreditDirector).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO movie_credits (movie_id, person_id, role, character, billing)
+			SELECT m.id, (SELECT MIN(p.id) FROM people p WHERE p.name = m.director), ?, '', 0 FROM movies m
+			WHERE m.director <> ''
+				AND NOT EXISTS (SELECT 1 FROM movie_credits c WHERE c.movie_id = m.id AND c.role = ?)`,
+			models.CreditDirector, models.CreditDirector).Error
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to migrate directors to credits: %v", err)
+	}
+}
+
+// migrateMovieSearch adds the full-text search vector of movies, generated from the title,
+// director and plot weighted in that order, and its GIN index.
+func migrateMovieSearch(db *gorm.DB) {
+	err := db.Exec(`ALTER TABLE movies ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(director, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(plot, '')), 'C')
+		) STORED`).Error
+	if err != nil {
+		log.Fatalf("❌ Failed to add movie search vector: %v", err)
+	}
+	if err := db.Exec("CREATE INDEX IF NOT EXISTS idx_movies_search ON movies USING GIN (search_vector)").Error; err != nil {
+		log.Fatalf("❌ Failed to create idx_movies_search: %v", err)
+	}
+}
+
+// migrateMovieTitleTrigrams enables pg_trgm and indexes the titles of movies outside the
+// trash for similarity matching.
+func migrateMovieTitleTrigrams(db *gorm.DB) {
+	if err := db.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm").Error; err != nil {
+		log.Fatalf("❌ Failed to enable pg_trgm: %v", err)
+	}
+	err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_movies_title_trgm ON movies
+		USING GIN (title gin_trgm_ops) WHERE deleted_at IS NULL`).Error
+	if err != nil {
+		log.Fatalf("❌ Failed to create idx_movies_title_trgm: %v", err)
+	}
+}
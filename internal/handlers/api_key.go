@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyHandler struct {
+	service *services.APIKeyService
+}
+
+func NewAPIKeyHandler(service *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// CreateAPIKey creates a new API key
+// @Summary Create an API key
+// @Description Create a scoped API key for machine clients (admin only). The key is only returned in this response.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Param key body models.CreateAPIKeyRequest true "API key data"
+// @Success 201 {object} models.CreateAPIKeyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys [post]
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var request models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Name is required and scopes must be movies:read, movies:write, movies:delete or movies:bulk")
+		return
+	}
+	request.CreatedByID, _ = utils.CurrentUserID(c)
+
+	key, err := h.service.CreateAPIKey(request)
+	if err != nil {
+		if errors.Is(err, services.ErrAPIKeyExpiryInPast) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid expires_at", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create API key")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, key)
+}
+
+// @Security ApiKeyAuth
+// GetAllAPIKeys lists API keys
+// @Summary Get all API keys
+// @Description List API keys without their secret values (admin only)
+// @Tags api-keys
+// @Produce json
+// @Success 200 {object} models.APIKeyListResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys [get]
+func (h *APIKeyHandler) GetAllAPIKeys(c *gin.Context) {
+	keys, err := h.service.GetAllAPIKeys()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+// @Security ApiKeyAuth
+// RevokeAPIKey revokes an API key
+// @Summary Revoke an API key
+// @Description Revoke an API key so it can no longer be used (admin only)
+// @Tags api-keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /api-keys/{id} [delete]
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "API key ID must be a positive integer")
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(uint(id)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "API key not found", "No active API key found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to revoke API key")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
@@ -1,7 +1,11 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"itv-task/internal/models"
 	"itv-task/internal/services"
@@ -11,16 +15,19 @@ import (
 )
 
 type AuthHandler struct {
-	AuthService *services.AuthService
+	AuthService      *services.AuthService
+	OIDCService      *services.OIDCService
+	TwoFactorService *services.TwoFactorService
 }
 
-func NewAuthHandler(authService *services.AuthService) *AuthHandler {
-	return &AuthHandler{AuthService: authService}
+func NewAuthHandler(authService *services.AuthService, oidcService *services.OIDCService, twoFactorService *services.TwoFactorService) *AuthHandler {
+	return &AuthHandler{AuthService: authService, OIDCService: oidcService, TwoFactorService: twoFactorService}
 }
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user with hardcoded credentials
+// @Description Authenticate user with username and password. Users with two-factor authentication
+// @Description get `two_factor_required` and a `challenge_token` to complete at /auth/2fa/verify instead of tokens.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -28,6 +35,7 @@ func NewAuthHandler(authService *services.AuthService) *AuthHandler {
 // @Success 200 {object} models.LoginResponse
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse "Locked out; see the Retry-After header"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
@@ -36,16 +44,102 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
 		return
 	}
+	request.IP = c.ClientIP()
+	request.UserAgent = c.Request.UserAgent()
 
 	response, err := h.AuthService.Login(request)
 	if err != nil {
-		utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", err.Error())
+		var lockErr *services.LoginLockedError
+		if errors.As(err, &lockErr) {
+			sendLoginLocked(c, lockErr)
+		} else if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to log in")
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
 
+// Register godoc
+// @Summary Register user
+// @Description Create a new regular user account
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.RegisterRequest true "Account credentials"
+// @Success 201 {object} models.UserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/register [post]
+func (h *AuthHandler) Register(c *gin.Context) {
+	var request models.RegisterRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Username must be 3-255 characters and password 8-72 characters")
+		return
+	}
+
+	user, err := h.AuthService.Register(request)
+	if err != nil {
+		if errors.Is(err, services.ErrUsernameTaken) {
+			utils.SendErrorResponse(c, http.StatusConflict, "Username taken", "A user with the same username already exists")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to register user")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the password of the authenticated user
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse "Locked out; see the Retry-After header"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/change-password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	var request models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "New password must be 8-72 characters")
+		return
+	}
+
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+	request.UserID = userID
+	request.IP = c.ClientIP()
+	request.UserAgent = c.Request.UserAgent()
+
+	if err := h.AuthService.ChangePassword(request); err != nil {
+		var lockErr *services.LoginLockedError
+		if errors.As(err, &lockErr) {
+			sendLoginLocked(c, lockErr)
+		} else if errors.Is(err, services.ErrInvalidCredentials) {
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", "Current password is incorrect")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to change password")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed"})
+}
+
 // RefreshToken godoc
 // @Summary Refresh access token
 // @Description Generate a new access token using the refresh token
@@ -73,3 +167,269 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	c.JSON(http.StatusOK, response)
 }
+
+// Logout godoc
+// @Summary Logout
+// @Description Revoke the session the given refresh token belongs to
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.LogoutRequest true "Refresh token of the session"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var request models.LogoutRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Failed to parse request body")
+		return
+	}
+
+	claims, _ := utils.ClaimsFromContext(c)
+	userID, ok := utils.UserIDFromClaims(claims)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+	request.UserID = userID
+	request.AccessTokenJTI, _ = claims["jti"].(string)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		request.AccessTokenExpiresAt = exp.Time
+	}
+
+	if err := h.AuthService.Logout(request); err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid refresh token", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to log out")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll godoc
+// @Summary Logout all sessions
+// @Description Revoke every refresh token of the authenticated user
+// @Tags Auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+
+	if err := h.AuthService.LogoutAll(userID); err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to log out")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out of all sessions"})
+}
+
+// RevokeToken godoc
+// @Summary Revoke an access token
+// @Description Denylist a single access token by its jti (admin only)
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.RevokeTokenRequest true "Token to revoke"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /tokens/revoke [post]
+func (h *AuthHandler) RevokeToken(c *gin.Context) {
+	var request models.RevokeTokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "jti is required")
+		return
+	}
+
+	if err := h.AuthService.RevokeAccessToken(request); err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to revoke token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+// RevokeUserTokens godoc
+// @Summary Revoke a user's tokens
+// @Description Invalidate every token a user was issued before the given time, now by default (admin only)
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Param request body models.RevokeUserTokensRequest false "Cutoff time"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/revoke-tokens [post]
+func (h *AuthHandler) RevokeUserTokens(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.RevokeUserTokensRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "before must be an RFC 3339 timestamp")
+			return
+		}
+	}
+	request.UserID = id
+
+	if err := h.AuthService.RevokeUserTokens(request); err != nil {
+		sendUserError(c, err, "Failed to revoke tokens")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User tokens revoked"})
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying issued tokens, selected by the kid header. Empty when tokens are signed with HS256.
+// @Tags Auth
+// @Produce json
+// @Success 200 {object} utils.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.AuthService.JWKS())
+}
+
+// oidcStateCookie binds a started OIDC login to the browser, so a callback with someone
+// else's state and code (login CSRF) is rejected.
+const oidcStateCookie = "oidc_state"
+
+// OIDCLogin godoc
+// @Summary Start OIDC login
+// @Description Redirect to the company identity provider (authorization code flow with PKCE). Sets the oidc_state cookie the callback checks.
+// @Tags Auth
+// @Success 302
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 502 {object} models.ErrorResponse
+// @Router /auth/oidc/login [get]
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	authURL, state, err := h.OIDCService.StartLogin(c.Request.Context())
+	if err != nil {
+		if errors.Is(err, services.ErrOIDCDisabled) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "OIDC login disabled", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusBadGateway, "Identity provider unavailable", "Failed to start OIDC login")
+		}
+		return
+	}
+
+	// Lax, not Strict: the callback is a top-level redirect from the identity provider.
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, state, int(services.OIDCLoginTTL.Seconds()), "/auth/oidc", "", h.oidcCookieSecure(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback godoc
+// @Summary Complete OIDC login
+// @Description Redirect target of the identity provider; exchanges the code and returns the service's own tokens. The state must match the oidc_state cookie set by the login request.
+// @Tags Auth
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login request"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /auth/oidc/callback [get]
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if idpError := c.Query("error"); idpError != "" {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Login rejected by identity provider", idpError+": "+c.Query("error_description"))
+		return
+	}
+
+	state := c.Query("state")
+	code := c.Query("code")
+	if state == "" || code == "" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "code and state are required")
+		return
+	}
+
+	// The state cookie is single use, like the state itself.
+	browserState, _ := c.Cookie(oidcStateCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", h.oidcCookieSecure(c), true)
+
+	response, err := h.OIDCService.CompleteLogin(c.Request.Context(), state, browserState, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOIDCDisabled):
+			utils.SendErrorResponse(c, http.StatusNotFound, "OIDC login disabled", err.Error())
+		case errors.Is(err, services.ErrOIDCInvalidState):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid state", err.Error())
+		case errors.Is(err, services.ErrOIDCNoRole):
+			utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "OIDC login failed", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// oidcCookieSecure marks the state cookie Secure whenever the callback is served over HTTPS.
+func (h *AuthHandler) oidcCookieSecure(c *gin.Context) bool {
+	return c.Request.TLS != nil || strings.HasPrefix(h.OIDCService.Config.OIDCRedirectURL, "https://")
+}
+
+// GetAuditLog godoc
+// @Summary Auth audit log
+// @Description List login successes, failures and lockouts, newest first (admin only)
+// @Tags Auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Param username query string false "Filter by username"
+// @Param event query string false "Filter by event (login_success, login_failure, login_locked)"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.AuthAuditLogListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/audit-log [get]
+func (h *AuthHandler) GetAuditLog(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.AuthService.GetAuditLog(c.Query("username"), c.Query("event"), limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, entries)
+}
+
+// sendLoginLocked responds 429 with a Retry-After header in whole seconds.
+func sendLoginLocked(c *gin.Context, lockErr *services.LoginLockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(lockErr.RetryAfter.Seconds()))))
+	utils.SendErrorResponse(c, http.StatusTooManyRequests, "Too many login attempts", lockErr.Error())
+}
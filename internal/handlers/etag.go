@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// versionETag renders a resource version as a strong entity tag.
+func versionETag(version uint) string {
+	return `"` + strconv.FormatUint(uint64(version), 10) + `"`
+}
+
+// etagListMatches reports whether an If-Match / If-None-Match header value lists etag.
+// Weak comparison ignores the W/ prefix, which If-None-Match allows but If-Match does not.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch enforces the If-Match precondition against the current version. It returns
+// the version to make the write conditional on (zero without the header), and false after
+// responding 412 when the client's copy is stale.
+func checkIfMatch(c *gin.Context, currentVersion uint) (uint, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return 0, true // "*" only requires the movie to exist, which the caller checked
+	}
+	if !etagListMatches(header, versionETag(currentVersion), false) {
+		c.Header("ETag", versionETag(currentVersion))
+		utils.SendErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed",
+			"The movie has been changed since it was read; fetch it again and retry")
+		return 0, false
+	}
+	return currentVersion, true
+}
+
+// sendVersionConflict responds 412 when a conditional write lost a race after the
+// precondition was checked.
+func sendVersionConflict(c *gin.Context) {
+	utils.SendErrorResponse(c, http.StatusPreconditionFailed, "Precondition failed",
+		"The movie has been changed since it was read; fetch it again and retry")
+}
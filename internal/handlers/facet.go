@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxFacetLimit bounds the values listed per facet dimension.
+const maxFacetLimit = 100
+
+type FacetHandler struct {
+	service *services.FacetService
+}
+
+func NewFacetHandler(service *services.FacetService) *FacetHandler {
+	return &FacetHandler{service: service}
+}
+
+// GetMovieFacets counts movies per facet value
+// @Summary Get movie facets
+// @Description Count the movies matching the filters of GET /movies by decade, year, director, genre and rating, for a filter sidebar. Each dimension lists its most frequent values first. Counts are cached per filter for MOVIE_FACETS_CACHE_TTL.
+// @Tags movies
+// @Produce json
+// @Param title query string false "Filter by title"
+// @Param director query string false "Filter by director"
+// @Param year query int false "Filter by year"
+// @Param genre query []string false "Filter by genre name or slug; repeat or comma-separate for several" collectionFormat(multi)
+// @Param genre_match query string false "Match movies with any (default) or all of the genres" Enums(any, all)
+// @Param min_rating query number false "Only movies with at least this average rating (1-10)"
+// @Param filter query string false "Filter expression, e.g. year>=1990 AND (director~\"nolan\" OR title^=\"The\")"
+// @Param facet_limit query int false "Values listed per dimension, up to 100 (default 20)"
+// @Success 200 {object} models.MovieFacetsResponse
+// @Failure 400 {object} models.FilterErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/facets [get]
+func (h *FacetHandler) GetMovieFacets(c *gin.Context) {
+	filter, ok := parseMovieFilter(c)
+	if !ok {
+		return
+	}
+	limit := 20
+	if limitStr := c.Query("facet_limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 || limit > maxFacetLimit {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid facet_limit", "facet_limit must be between 1 and 100")
+			return
+		}
+	}
+
+	facets, err := h.service.GetMovieFacets(filter, limit)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to count movie facets")
+		return
+	}
+
+	c.JSON(http.StatusOK, facets)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type GenreHandler struct {
+	service *services.GenreService
+}
+
+func NewGenreHandler(service *services.GenreService) *GenreHandler {
+	return &GenreHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// CreateGenre creates a new genre
+// @Summary Create a genre
+// @Description Add a genre movies can be tagged with. Names are unique ignoring case and punctuation.
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param genre body models.GenreRequest true "Genre data"
+// @Success 201 {object} models.GenreResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres [post]
+func (h *GenreHandler) CreateGenre(c *gin.Context) {
+	request, ok := bindGenreRequest(c)
+	if !ok {
+		return
+	}
+
+	genre, err := h.service.CreateGenre(request)
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Genre already exists", "A genre with the same name already exists")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create genre")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, genre)
+}
+
+// GetAllGenres lists genres with movie counts
+// @Summary Get all genres
+// @Description List every genre with the number of movies that have it. The movie filters of GET /movies narrow the counts, for faceted browsing.
+// @Tags genres
+// @Produce json
+// @Param title query string false "Count only movies matching the title"
+// @Param director query string false "Count only movies matching the director"
+// @Param year query int false "Count only movies from the year"
+// @Param genre query []string false "Count only movies with these genres" collectionFormat(multi)
+// @Param genre_match query string false "Match movies with any (default) or all of the genres" Enums(any, all)
+// @Param min_rating query number false "Count only movies with at least this average rating"
+// @Param filter query string false "Count only movies matching the filter expression of GET /movies"
+// @Success 200 {object} models.GenreListResponse
+// @Failure 400 {object} models.FilterErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres [get]
+func (h *GenreHandler) GetAllGenres(c *gin.Context) {
+	filter, ok := parseMovieFilter(c)
+	if !ok {
+		return
+	}
+
+	genres, err := h.service.GetAllGenres(filter)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve genres")
+		return
+	}
+
+	c.JSON(http.StatusOK, genres)
+}
+
+// GetGenreByID retrieves a single genre by ID
+// @Summary Get a genre by ID
+// @Description Retrieve a genre with the number of movies that have it
+// @Tags genres
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 200 {object} models.GenreResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres/{id} [get]
+func (h *GenreHandler) GetGenreByID(c *gin.Context) {
+	id, ok := genreID(c)
+	if !ok {
+		return
+	}
+
+	genre, err := h.service.GetGenreByID(id)
+	if err != nil {
+		sendGenreError(c, err, "Failed to retrieve genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// UpdateGenre renames a genre
+// @Summary Rename a genre
+// @Description Rename a genre; movies keep it under the new name
+// @Tags genres
+// @Accept json
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Param genre body models.GenreRequest true "Genre data"
+// @Success 200 {object} models.GenreResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres/{id} [put]
+func (h *GenreHandler) UpdateGenre(c *gin.Context) {
+	id, ok := genreID(c)
+	if !ok {
+		return
+	}
+	request, ok := bindGenreRequest(c)
+	if !ok {
+		return
+	}
+
+	genre, err := h.service.UpdateGenre(id, request)
+	if err != nil {
+		sendGenreError(c, err, "Failed to update genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, genre)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// DeleteGenre deletes a genre
+// @Summary Delete a genre
+// @Description Delete a genre and remove it from every movie
+// @Tags genres
+// @Produce json
+// @Param id path int true "Genre ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /genres/{id} [delete]
+func (h *GenreHandler) DeleteGenre(c *gin.Context) {
+	id, ok := genreID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteGenre(id); err != nil {
+		sendGenreError(c, err, "Failed to delete genre")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Genre deleted"})
+}
+
+func genreID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Genre ID must be a positive integer")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bindGenreRequest(c *gin.Context) (*models.GenreRequest, bool) {
+	var request models.GenreRequest
+	if err := c.ShouldBindJSON(&request); err != nil || models.GenreSlug(request.Name) == "" {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Name is required, must contain a letter or digit and be <= 64 characters")
+		return nil, false
+	}
+	return &request, true
+}
+
+// sendGenreError maps errors of single-genre operations to responses.
+func sendGenreError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Genre not found", "No genre found with the given ID")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Genre already exists", "A genre with the same name already exists")
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", failure)
+	}
+}
@@ -0,0 +1,420 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ListHandler struct {
+	service *services.ListService
+}
+
+func NewListHandler(service *services.ListService) *ListHandler {
+	return &ListHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// GetLists lists movie lists
+// @Summary Get lists
+// @Description List your own lists, starting with your watchlist, or with user_id another user's public lists
+// @Tags lists
+// @Produce json
+// @Param user_id query int false "Owner of the public lists to show"
+// @Success 200 {object} models.ListsResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys have no lists"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists [get]
+func (h *ListHandler) GetLists(c *gin.Context) {
+	var lists models.ListsResponse
+	var err error
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, convErr := strconv.Atoi(userIDStr)
+		if convErr != nil || userID <= 0 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid user_id", "user_id must be a positive integer")
+			return
+		}
+		lists, err = h.service.GetPublicLists(uint(userID))
+	} else {
+		lists, err = h.service.GetMyLists(utils.ActorFromContext(c))
+	}
+	if err != nil {
+		sendListError(c, err, "Failed to retrieve lists")
+		return
+	}
+
+	c.JSON(http.StatusOK, lists)
+}
+
+// @Security ApiKeyAuth
+// CreateList creates a named list
+// @Summary Create a list
+// @Description Create a named list of movies. Names are unique per user and "Watchlist" is taken by the default list.
+// @Tags lists
+// @Accept json
+// @Produce json
+// @Param list body models.ListRequest true "List"
+// @Success 201 {object} models.ListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys have no lists"
+// @Failure 409 {object} models.ErrorResponse "You already have a list with this name"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists [post]
+func (h *ListHandler) CreateList(c *gin.Context) {
+	request, ok := bindListRequest(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.CreateList(request, utils.ActorFromContext(c))
+	if err != nil {
+		sendListError(c, err, "Failed to create list")
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// @Security ApiKeyAuth
+// GetList retrieves a list with its entries
+// @Summary Get a list
+// @Description Retrieve one of your lists, or another user's public list, with its movies in order. Use "watchlist" as the ID for your watchlist. Movies in the trash are hidden.
+// @Tags lists
+// @Produce json
+// @Param id path string true "List ID or watchlist"
+// @Success 200 {object} models.ListDetailResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id} [get]
+func (h *ListHandler) GetList(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.GetList(id, utils.ActorFromContext(c))
+	if err != nil {
+		sendListError(c, err, "Failed to retrieve list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Security ApiKeyAuth
+// UpdateList renames a list and sets its visibility
+// @Summary Update a list
+// @Description Rename a list and make it public or private. The watchlist keeps its name.
+// @Tags lists
+// @Accept json
+// @Produce json
+// @Param id path string true "List ID or watchlist"
+// @Param list body models.ListRequest true "List"
+// @Success 200 {object} models.ListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The list belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "You already have a list with this name"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id} [put]
+func (h *ListHandler) UpdateList(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+	request, ok := bindListRequest(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.service.UpdateList(id, request, utils.ActorFromContext(c))
+	if err != nil {
+		sendListError(c, err, "Failed to update list")
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Security ApiKeyAuth
+// DeleteList deletes a list
+// @Summary Delete a list
+// @Description Delete one of your named lists. The watchlist cannot be deleted.
+// @Tags lists
+// @Produce json
+// @Param id path int true "List ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The list belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id} [delete]
+func (h *ListHandler) DeleteList(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteList(id, utils.ActorFromContext(c)); err != nil {
+		sendListError(c, err, "Failed to delete list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "List deleted"})
+}
+
+// @Security ApiKeyAuth
+// AddListEntry puts a movie on a list
+// @Summary Add a movie to a list
+// @Description Add a movie at the given 1-based position, or at the end without one
+// @Tags lists
+// @Accept json
+// @Produce json
+// @Param id path string true "List ID or watchlist"
+// @Param entry body models.ListEntryRequest true "Movie to add"
+// @Success 201 {object} models.ListDetailResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The list belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "The movie is already on the list"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id}/entries [post]
+func (h *ListHandler) AddListEntry(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+	var request models.ListEntryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "A movie_id and a non-negative position are required")
+		return
+	}
+
+	list, err := h.service.AddEntry(id, &request, utils.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.SendErrorResponse(c, http.StatusConflict, "Already on the list", "The movie is already on the list")
+		} else {
+			sendListError(c, err, "Failed to add movie to list")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, list)
+}
+
+// @Security ApiKeyAuth
+// RemoveListEntry takes a movie off a list
+// @Summary Remove a movie from a list
+// @Description Remove a movie from a list; the movies after it move up
+// @Tags lists
+// @Produce json
+// @Param id path string true "List ID or watchlist"
+// @Param movie_id path int true "Movie ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The list belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id}/entries/{movie_id} [delete]
+func (h *ListHandler) RemoveListEntry(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+	movieID, err := strconv.Atoi(c.Param("movie_id"))
+	if err != nil || movieID <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	if err := h.service.RemoveEntry(id, uint(movieID), utils.ActorFromContext(c)); err != nil {
+		sendListError(c, err, "Failed to remove movie from list")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Movie removed from list"})
+}
+
+// @Security ApiKeyAuth
+// ReorderList reorders the movies of a list
+// @Summary Reorder a list
+// @Description Put the list's movies in the given order. Every movie shown on the list must be named exactly once.
+// @Tags lists
+// @Accept json
+// @Produce json
+// @Param id path string true "List ID or watchlist"
+// @Param order body models.ReorderListRequest true "Movie IDs in their new order"
+// @Success 200 {object} models.ListDetailResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The list belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /lists/{id}/order [put]
+func (h *ListHandler) ReorderList(c *gin.Context) {
+	id, ok := listID(c)
+	if !ok {
+		return
+	}
+	var request models.ReorderListRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "movie_ids is required")
+		return
+	}
+
+	list, err := h.service.ReorderList(id, &request, utils.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, repositories.ErrListOrderMismatch) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid order", "movie_ids must name every movie on the list exactly once")
+		} else {
+			sendListError(c, err, "Failed to reorder list")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// @Security ApiKeyAuth
+// GetWatched lists the movies you watched
+// @Summary Get watched movies
+// @Description List your watch log, most recently watched first. Movies in the trash are hidden.
+// @Tags lists
+// @Produce json
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.WatchedListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys have no watch log"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /watched [get]
+func (h *ListHandler) GetWatched(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	watched, err := h.service.GetWatched(limit, offset, utils.ActorFromContext(c))
+	if err != nil {
+		sendListError(c, err, "Failed to retrieve watched movies")
+		return
+	}
+
+	c.JSON(http.StatusOK, watched)
+}
+
+// @Security ApiKeyAuth
+// LogWatched records a watched movie
+// @Summary Log a watched movie
+// @Description Record that you watched a movie, by default now. Rewatches are logged separately.
+// @Tags lists
+// @Accept json
+// @Produce json
+// @Param entry body models.WatchedRequest true "Watched movie"
+// @Success 201 {object} models.WatchedResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys have no watch log"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /watched [post]
+func (h *ListHandler) LogWatched(c *gin.Context) {
+	var request models.WatchedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "A movie_id is required and watched_at must be an RFC 3339 time")
+		return
+	}
+
+	entry, err := h.service.LogWatched(&request, utils.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			sendListError(c, err, "Failed to log watched movie")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// @Security ApiKeyAuth
+// DeleteWatched removes an entry from the watch log
+// @Summary Delete a watched entry
+// @Description Remove an entry from your watch log
+// @Tags lists
+// @Produce json
+// @Param id path int true "Watched entry ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys have no watch log"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /watched/{id} [delete]
+func (h *ListHandler) DeleteWatched(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Watched entry ID must be a positive integer")
+		return
+	}
+
+	if err := h.service.DeleteWatched(uint(id), utils.ActorFromContext(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Not found", "No watched entry found with the given ID")
+		} else {
+			sendListError(c, err, "Failed to delete watched entry")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Watched entry deleted"})
+}
+
+// listID reads the list ID path parameter, where "watchlist" stands for the caller's
+// watchlist and becomes 0.
+func listID(c *gin.Context) (uint, bool) {
+	if c.Param("id") == "watchlist" {
+		return 0, true
+	}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "List ID must be a positive integer or watchlist")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bindListRequest(c *gin.Context) (*models.ListRequest, bool) {
+	var request models.ListRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Name is required and must be <= 100 characters")
+		return nil, false
+	}
+	return &request, true
+}
+
+// sendListError maps errors of list and watch log operations to responses.
+func sendListError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Not found", "No such list, or movie on the list")
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		utils.SendErrorResponse(c, http.StatusConflict, "List already exists", "You already have a list with this name")
+	case errors.Is(err, services.ErrDefaultList):
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Watchlist", "The watchlist cannot be renamed or deleted, and its name is reserved")
+	case errors.Is(err, services.ErrListOwnerRequired):
+		utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "Lists belong to users; API keys have none")
+	case errors.Is(err, services.ErrNotListOwner):
+		utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "You can only change your own lists")
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", failure)
+	}
+}
@@ -1,13 +1,24 @@
 package handlers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"itv-task/internal/models"
+	"itv-task/internal/repositories"
 	"itv-task/internal/services"
+	"itv-task/pkg/filterql"
 	"itv-task/pkg/utils"
+	"mime"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 type MovieHandler struct {
@@ -19,6 +30,7 @@ func NewMovieHandler(service *services.MovieService) *MovieHandler {
 }
 
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 // CreateMovie creates a new movie
 // @Summary Create a new movie
 // @Description Add a new movie to the database
@@ -26,8 +38,10 @@ func NewMovieHandler(service *services.MovieService) *MovieHandler {
 // @Accept json
 // @Produce json
 // @Param movie body models.CreateMovieRequest true "Movie data"
-// @Success 201 {object} models.CreateMovieRequest
+// @Param reject_duplicates query bool false "Refuse the movie with 409 if its title is close to existing ones, instead of only warning"
+// @Success 201 {object} models.CreateMovieResponse "Created; possible_duplicates lists movies with similar titles"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 409 {object} models.PossibleDuplicatesResponse "Movies with similar titles exist and reject_duplicates is set"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movies [post]
 func (h *MovieHandler) CreateMovie(c *gin.Context) {
@@ -54,13 +68,23 @@ func (h *MovieHandler) CreateMovie(c *gin.Context) {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie with the same title already exists")
 		return
 	}
+	duplicates, ok := h.possibleDuplicates(c, movie.Title)
+	if !ok {
+		return
+	}
 
-	if _, err := h.service.CreateMovie(&movie); err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create movie")
+	if _, err := h.service.CreateMovie(&movie, utils.ActorFromContext(c)); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie with the same title already exists")
+		} else if errors.Is(err, repositories.ErrUnknownGenre) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown genre", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create movie")
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, movie)
+	c.JSON(http.StatusCreated, models.CreateMovieResponse{CreateMovieRequest: movie, PossibleDuplicates: duplicates})
 }
 
 // GetAllMovies retrieves all movies
@@ -71,79 +95,241 @@ func (h *MovieHandler) CreateMovie(c *gin.Context) {
 // @Param title query string false "Filter by title"
 // @Param director query string false "Filter by director"
 // @Param year query int false "Filter by year"
+// @Param genre query []string false "Filter by genre name or slug; repeat or comma-separate for several" collectionFormat(multi)
+// @Param genre_match query string false "Match movies with any (default) or all of the genres" Enums(any, all)
+// @Param min_rating query number false "Only movies with at least this average rating (1-10)"
 // @Param limit query int false "Limit results"
 // @Param offset query int false "Offset results"
-// @Param sort_by query string false "Sort by field (title, year, created_at, director)"
+// @Param cursor query string false "Continue from the next_cursor or prev_cursor of a previous page, instead of offset"
+// @Param include_total query bool false "Count every match (default true); false skips the count"
+// @Param filter query string false "Filter expression, e.g. year>=1990 AND (director~\"nolan\" OR title^=\"The\")"
+// @Param sort query string false "Comma-separated sort fields, - for descending, e.g. -year,title"
+// @Param sort_by query string false "Sort by field (title, year, created_at, director, rating)"
 // @Param sort_order query string false "Sort order (asc, desc)"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,title,year; id is always included"
+// @Param expand query string false "Comma-separated relations to embed: genres, credits, credits.person, ratings"
 // @Success 200 {array} models.MovieListResponse
+// @Failure 400 {object} models.FilterErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movies [get]
 func (h *MovieHandler) GetAllMovies(c *gin.Context) {
-	title := c.Query("title")
-	director := c.Query("director")
+	filter, ok := parseMovieFilter(c)
+	if !ok {
+		return
+	}
+	view, ok := h.parseMovieView(c)
+	if !ok {
+		return
+	}
+	filter.Fields = view.Fields
+	if cursor := c.Query("cursor"); cursor != "" {
+		if c.Query("offset") != "" {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "cursor and offset cannot be combined")
+			return
+		}
+		position, err := h.service.DecodeMovieCursor(cursor)
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "The cursor is malformed or was not issued by this server")
+			return
+		}
+		sorted := c.Query("sort") != "" || c.Query("sort_by") != "" || c.Query("sort_order") != ""
+		if sorted && models.FormatSort(filter.Sort) != position.Sort {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "The cursor was issued for a different sort")
+			return
+		}
+		filter.Sort, filter.Cursor = nil, position
+		if position.Sort != "" {
+			if filter.Sort, err = parseSort(position.Sort); err != nil {
+				utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "The cursor does not fit its sort")
+				return
+			}
+		}
+	}
+	includeTotal, err := strconv.ParseBool(c.DefaultQuery("include_total", "true"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid include_total", "include_total must be true or false")
+		return
+	}
+	filter.SkipTotal = !includeTotal
+
+	movies, err := h.service.GetAllMovies(filter)
+	if err != nil {
+		if errors.Is(err, repositories.ErrInvalidCursor) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid cursor", "The cursor does not fit its sort")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movies")
+		}
+		return
+	}
+
+	if view.Full() {
+		c.JSON(http.StatusOK, movies)
+		return
+	}
+	documents, err := h.service.RenderMovies(movies.Movies, view)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movies")
+		return
+	}
+	c.JSON(http.StatusOK, models.MovieDocumentListResponse{
+		Movies:     documents,
+		Count:      movies.Count,
+		NextCursor: movies.NextCursor,
+		PrevCursor: movies.PrevCursor,
+	})
+}
+
+// parseMovieView reads the fields and expand query parameters. It responds with 400 and
+// returns false when one is invalid.
+func (h *MovieHandler) parseMovieView(c *gin.Context) (models.MovieView, bool) {
+	view, err := h.service.MovieView(c.Query("fields"), c.Query("expand"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid fields or expand", err.Error())
+		return view, false
+	}
+	return view, true
+}
+
+// parseMovieFilter reads the filter, sort and paging query parameters shared by the movie
+// listing endpoints. It responds with 400 and returns false when one is invalid.
+func parseMovieFilter(c *gin.Context) (models.MovieFilter, bool) {
+	filter := models.MovieFilter{
+		Title:    c.Query("title"),
+		Director: c.Query("director"),
+		Limit:    10,
+	}
+	sortBy := c.Query("sort_by")
+	sortOrder := c.Query("sort_order")
 	yearStr := c.Query("year")
+	minRatingStr := c.Query("min_rating")
 	limitStr := c.Query("limit")
 	offsetStr := c.Query("offset")
-	sortBy := c.Query("sort_by")
-	sortOrder := c.Query("sort_order")
 
-	var year, limit, offset int
 	var err error
-
 	if yearStr != "" {
-		year, err = strconv.Atoi(yearStr)
-		if err != nil || year < 1888 || year > 2025 {
+		filter.Year, err = strconv.Atoi(yearStr)
+		if err != nil || filter.Year < 1888 || filter.Year > 2025 {
 			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid year", "Year must be between 1888 and 2025")
-			return
+			return filter, false
+		}
+	}
+	if minRatingStr != "" {
+		filter.MinRating, err = strconv.ParseFloat(minRatingStr, 64)
+		if err != nil || filter.MinRating < 1 || filter.MinRating > 10 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid min_rating", "min_rating must be between 1 and 10")
+			return filter, false
 		}
 	}
 	if limitStr != "" {
-		limit, err = strconv.Atoi(limitStr)
-		if err != nil || limit <= 0 {
+		filter.Limit, err = strconv.Atoi(limitStr)
+		if err != nil || filter.Limit <= 0 {
 			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid limit", "Limit must be a positive number")
-			return
+			return filter, false
 		}
-	} else {
-		limit = 10
 	}
 	if offsetStr != "" {
-		offset, err = strconv.Atoi(offsetStr)
-		if err != nil || offset < 0 {
+		filter.Offset, err = strconv.Atoi(offsetStr)
+		if err != nil || filter.Offset < 0 {
 			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid offset", "Offset must be a non-negative number")
-			return
+			return filter, false
 		}
-	} else {
-		offset = 0
 	}
 	if sortBy != "" {
-		if sortBy != "title" && sortBy != "year" && sortBy != "created_at" && sortBy != "director" {
+		if sortBy != "title" && sortBy != "year" && sortBy != "created_at" && sortBy != "director" && sortBy != "rating" {
 			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid sort_by", "Invalid sort_by value")
-			return
+			return filter, false
 		}
 	}
 	if sortOrder != "" {
 		if sortOrder != "asc" && sortOrder != "desc" {
 			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid sort_order", "Invalid sort_order value")
-			return
+			return filter, false
+		}
+	}
+	if sort := c.Query("sort"); sort != "" {
+		if sortBy != "" || sortOrder != "" {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid sort", "sort cannot be combined with sort_by or sort_order")
+			return filter, false
 		}
+		if filter.Sort, err = parseSort(sort); err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid sort", err.Error())
+			return filter, false
+		}
+	} else if sortBy != "" {
+		// An explicit sort_by defaults to descending
+		filter.Sort = []models.SortField{{Field: sortBy, Desc: sortOrder != "asc"}}
+	} else if sortOrder == "desc" {
+		filter.Sort = []models.SortField{{Field: "id", Desc: true}}
 	}
 
-	movies, err := h.service.GetAllMovies(title, director, year, sortBy, sortOrder, limit, offset)
-	if err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movies")
-		return
+	if expression := c.Query("filter"); expression != "" {
+		filter.Expression, err = filterql.Parse(expression, repositories.MovieFilterFields)
+		var syntaxErr *filterql.Error
+		if errors.As(err, &syntaxErr) {
+			c.JSON(http.StatusBadRequest, models.FilterErrorResponse{
+				ErrorResponse: models.NewErrorResponse(http.StatusBadRequest, "Invalid filter", syntaxErr.Error()),
+				Position:      syntaxErr.Position,
+			})
+			return filter, false
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, value := range c.QueryArray("genre") {
+		for _, name := range strings.Split(value, ",") {
+			if slug := models.GenreSlug(name); slug != "" && !seen[slug] {
+				seen[slug] = true
+				filter.Genres = append(filter.Genres, slug)
+			}
+		}
+	}
+	switch filter.GenreMatch = c.DefaultQuery("genre_match", models.GenreMatchAny); filter.GenreMatch {
+	case models.GenreMatchAny, models.GenreMatchAll:
+	default:
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid genre_match", "genre_match must be any or all")
+		return filter, false
 	}
 
-	c.JSON(http.StatusOK, movies)
+	return filter, true
+}
+
+// movieSortFields are the fields the sort parameter accepts.
+var movieSortFields = []string{"id", "title", "year", "created_at", "director", "rating"}
+
+// parseSort parses a comma-separated list of sort fields, each ascending or, prefixed
+// with -, descending, such as "-year,title".
+func parseSort(value string) ([]models.SortField, error) {
+	var sort []models.SortField
+	seen := map[string]bool{}
+	for _, key := range strings.Split(value, ",") {
+		field := models.SortField{Field: strings.TrimSpace(key)}
+		if rest, ok := strings.CutPrefix(field.Field, "-"); ok {
+			field.Field, field.Desc = rest, true
+		}
+		if !slices.Contains(movieSortFields, field.Field) {
+			return nil, fmt.Errorf("unknown sort field %q; use %s", field.Field, strings.Join(movieSortFields, ", "))
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("sort field %q is repeated", field.Field)
+		}
+		seen[field.Field] = true
+		sort = append(sort, field)
+	}
+	return sort, nil
 }
 
 // GetMovieByID retrieves a single movie by ID
 // @Summary Get a movie by ID
-// @Description Retrieve a movie using its ID
+// @Description Retrieve a movie using its ID. Responses with expand embed data that changes without the movie's version, so they carry no ETag.
 // @Tags movies
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param fields query string false "Comma-separated fields to return, e.g. id,title,year; id is always included"
+// @Param expand query string false "Comma-separated relations to embed: genres, credits, credits.person, ratings"
+// @Param If-None-Match header string false "ETag of a cached copy; returns 304 if it is still current"
 // @Success 200 {object} models.MovieResponse
+// @Success 304 "Not modified"
+// @Header 200 {string} ETag "Version of the movie, for If-Match / If-None-Match"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
 // @Router /movies/{id} [get]
@@ -154,7 +340,12 @@ func (h *MovieHandler) GetMovieByID(c *gin.Context) {
 		return
 	}
 
-	movie, err := h.service.GetMovieByID(uint(id))
+	view, ok := h.parseMovieView(c)
+	if !ok {
+		return
+	}
+
+	movie, err := h.service.GetMovieFields(uint(id), view.Fields)
 	if err != nil {
 		if err.Error() == "record not found" {
 			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
@@ -164,10 +355,30 @@ func (h *MovieHandler) GetMovieByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, movie)
+	// Expanded relations change without the movie's version, which the ETag stands for
+	if view.Expand == (models.MovieExpansion{}) {
+		etag := versionETag(movie.Version)
+		c.Header("ETag", etag)
+		if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" && etagListMatches(ifNoneMatch, etag, true) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	if view.Full() {
+		c.JSON(http.StatusOK, movie)
+		return
+	}
+	documents, err := h.service.RenderMovies([]models.MovieResponse{*movie}, view)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+		return
+	}
+	c.JSON(http.StatusOK, documents[0])
 }
 
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 // UpdateMovie updates an existing movie
 // @Summary Update a movie
 // @Description Modify an existing movie
@@ -176,9 +387,12 @@ func (h *MovieHandler) GetMovieByID(c *gin.Context) {
 // @Produce json
 // @Param id path int true "Movie ID"
 // @Param movie body models.UpdateMovieRequest true "Updated movie data"
+// @Param If-Match header string false "ETag the update is conditional on"
 // @Success 200 {object} models.UpdateMovieRequest
+// @Header 200 {string} ETag "New version of the movie"
 // @Failure 400 {object} models.ErrorResponse
 // @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movies/{id} [put]
 func (h *MovieHandler) UpdateMovie(c *gin.Context) {
@@ -194,7 +408,7 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		return
 	}
 
-	_, err = h.service.GetMovieByID(uint(id))
+	current, err := h.service.GetMovieByID(uint(id))
 	if err != nil {
 		if err.Error() == "record not found" {
 			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
@@ -203,6 +417,11 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		}
 		return
 	}
+	version, ok := checkIfMatch(c, current.Version)
+	if !ok {
+		return
+	}
+	movie.Version = version
 
 	if len(movie.Title) == 0 || len(movie.Title) > 255 {
 		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid title", "Title is required and must be <= 255 characters")
@@ -224,22 +443,243 @@ func (h *MovieHandler) UpdateMovie(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.UpdateMovie(&movie); err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to update movie")
+	updated, err := h.service.UpdateMovie(&movie, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrVersionConflict):
+			sendVersionConflict(c)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie with the same title already exists")
+		case errors.Is(err, repositories.ErrUnknownGenre):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown genre", err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to update movie")
+		}
+		return
+	}
+	c.Header("ETag", versionETag(updated.Version))
+	c.JSON(http.StatusOK, movie)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// PatchMovie partially updates a movie
+// @Summary Partially update a movie
+// @Description Change only some fields of a movie. Send a JSON Merge Patch (RFC 7386) as application/merge-patch+json
+// @Description (or application/json), e.g. {"plot": "New plot"}, where null clears the plot or the genres; or a JSON Patch (RFC 6902)
+// @Description as application/json-patch+json, e.g. [{"op": "add", "path": "/genres/-", "value": "Thriller"}].
+// @Description Patchable fields are title, director, year, plot and genres. Returns the full updated movie.
+// @Tags movies
+// @Accept application/merge-patch+json
+// @Accept application/json-patch+json
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param patch body models.PatchMovieRequest true "Merge patch, or an array of JSON Patch operations"
+// @Param If-Match header string false "ETag the update is conditional on"
+// @Success 200 {object} models.MovieResponse
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "A JSON Patch test operation failed"
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
+// @Failure 415 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id} [patch]
+func (h *MovieHandler) PatchMovie(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	contentType, _, _ := mime.ParseMediaType(c.GetHeader("Content-Type"))
+	if contentType != utils.ContentTypeMergePatch && contentType != utils.ContentTypeJSONPatch && contentType != "application/json" {
+		utils.SendErrorResponse(c, http.StatusUnsupportedMediaType, "Unsupported media type",
+			"Use application/merge-patch+json or application/json-patch+json")
 		return
 	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Failed to read request body")
+		return
+	}
+
+	current, err := h.service.GetMovieByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+		}
+		return
+	}
+	version, ok := checkIfMatch(c, current.Version)
+	if !ok {
+		return
+	}
+
+	document, _ := json.Marshal(movieDocument{
+		Title:    &current.Title,
+		Director: &current.Director,
+		Year:     &current.Year,
+		Plot:     &current.Plot,
+		Genres:   &current.Genres,
+	})
+	var patched []byte
+	if contentType == utils.ContentTypeJSONPatch {
+		patched, err = utils.JSONPatch(document, body)
+	} else {
+		patched, err = utils.MergePatch(document, body)
+	}
+	if err != nil {
+		if errors.Is(err, utils.ErrPatchTestFailed) {
+			utils.SendErrorResponse(c, http.StatusConflict, "Patch test failed", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid patch", err.Error())
+		}
+		return
+	}
+
+	request, ok := diffMovieDocument(c, current, patched)
+	if !ok {
+		return
+	}
+	request.ID = uint(id)
+	request.Version = version
+
+	if request.Title != nil {
+		existingMovie, err := h.service.GetMovieByTitle(*request.Title)
+		if err == nil && existingMovie.ID != request.ID {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie with the same title already exists")
+			return
+		}
+	}
+
+	movie, err := h.service.PatchMovie(request, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrVersionConflict):
+			sendVersionConflict(c)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie with the same title already exists")
+		case errors.Is(err, repositories.ErrUnknownGenre):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown genre", err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to update movie")
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(movie.Version))
 	c.JSON(http.StatusOK, movie)
 }
 
+// movieDocument is the JSON document PATCH requests are applied to.
+type movieDocument struct {
+	Title    *string   `json:"title"`
+	Director *string   `json:"director"`
+	Year     *int      `json:"year"`
+	Plot     *string   `json:"plot"`
+	Genres   *[]string `json:"genres"`
+}
+
+// diffMovieDocument validates the patched document and returns the fields that differ
+// from the current movie. It responds with 400 and returns false when invalid.
+func diffMovieDocument(c *gin.Context, current *models.MovieResponse, patched []byte) (*models.PatchMovieRequest, bool) {
+	var doc movieDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&doc); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid patch", "Only title, director, year, plot and genres can be patched: "+err.Error())
+		return nil, false
+	}
+
+	if doc.Title == nil || len(*doc.Title) == 0 || len(*doc.Title) > 255 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid title", "Title is required and must be <= 255 characters")
+		return nil, false
+	}
+	if doc.Director == nil || len(*doc.Director) == 0 || len(*doc.Director) > 255 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid director", "Director is required and must be <= 255 characters")
+		return nil, false
+	}
+	if doc.Year == nil || *doc.Year < 1888 || *doc.Year > 2025 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid year", "Year must be between 1888 and 2025")
+		return nil, false
+	}
+	if doc.Plot == nil {
+		empty := "" // Removing the plot clears it
+		doc.Plot = &empty
+	}
+	if doc.Genres == nil {
+		doc.Genres = &[]string{} // So does removing the genres
+	}
+	if len(*doc.Genres) > 20 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid genres", "A movie can have at most 20 genres")
+		return nil, false
+	}
+	for _, genre := range *doc.Genres {
+		if models.GenreSlug(genre) == "" || len(genre) > 64 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid genres", "Genres must be non-empty names of at most 64 characters")
+			return nil, false
+		}
+	}
+
+	request := &models.PatchMovieRequest{}
+	if *doc.Title != current.Title {
+		request.Title = doc.Title
+	}
+	if *doc.Director != current.Director {
+		request.Director = doc.Director
+	}
+	if *doc.Year != current.Year {
+		request.Year = doc.Year
+	}
+	if *doc.Plot != current.Plot {
+		request.Plot = doc.Plot
+	}
+	if !sameGenres(*doc.Genres, current.Genres) {
+		request.Genres = doc.Genres
+	}
+	return request, true
+}
+
+// sameGenres reports whether two genre lists name the same genres, in any order.
+func sameGenres(a, b []string) bool {
+	slugs := make(map[string]bool, len(a))
+	for _, name := range a {
+		slugs[models.GenreSlug(name)] = true
+	}
+	other := make(map[string]bool, len(b))
+	for _, name := range b {
+		if !slugs[models.GenreSlug(name)] {
+			return false
+		}
+		other[models.GenreSlug(name)] = true
+	}
+	return len(slugs) == len(other)
+}
+
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 // DeleteMovie deletes a movie by ID
 // @Summary Delete a movie
-// @Description Remove a movie from the database
+// @Description Move a movie to the trash, or with hard=true (admins only) remove it permanently, from the trash or not
 // @Tags movies
 // @Produce json
 // @Param id path int true "Movie ID"
+// @Param hard query bool false "Permanently delete the movie"
+// @Param If-Match header string false "ETag the deletion is conditional on; ignored with hard=true"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "hard=true by a non-admin"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /movies/{id} [delete]
 func (h *MovieHandler) DeleteMovie(c *gin.Context) {
@@ -249,8 +689,43 @@ func (h *MovieHandler) DeleteMovie(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.DeleteMovie(uint(id)); err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to delete movie")
+	if hard := c.Query("hard"); hard != "" {
+		permanent, err := strconv.ParseBool(hard)
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid hard", "hard must be true or false")
+			return
+		}
+		if permanent {
+			h.hardDeleteMovie(c, uint(id))
+			return
+		}
+	}
+
+	var version uint
+	if c.GetHeader("If-Match") != "" {
+		current, err := h.service.GetMovieByID(uint(id))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+			} else {
+				utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+			}
+			return
+		}
+		var ok bool
+		if version, ok = checkIfMatch(c, current.Version); !ok {
+			return
+		}
+	}
+
+	if err := h.service.DeleteMovie(uint(id), version, utils.ActorFromContext(c)); err != nil {
+		if errors.Is(err, repositories.ErrVersionConflict) {
+			sendVersionConflict(c)
+		} else if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to delete movie")
+		}
 		return
 	}
 
@@ -263,9 +738,12 @@ func (h *MovieHandler) DeleteMovie(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Security ApiKeyHeader
 // @Param movies body models.BulkInsertMoviesRequest true "List of movies to insert"
-// @Success 201 {object} map[string]string "Movies created successfully"
+// @Param reject_duplicates query bool false "Refuse the movies with 409 if titles are close to existing ones, instead of only warning"
+// @Success 201 {object} models.BulkInsertMoviesResponse "Created; possible_duplicates lists movies with similar titles"
 // @Failure 400 {object} models.ErrorResponse "Invalid request data"
+// @Failure 409 {object} models.PossibleDuplicatesResponse "Movies with similar titles exist and reject_duplicates is set"
 // @Failure 500 {object} models.ErrorResponse "Internal server error"
 // @Router /movies/bulk-insert [post]
 func (h *MovieHandler) BulkInsertMovies(c *gin.Context) {
@@ -294,11 +772,25 @@ func (h *MovieHandler) BulkInsertMovies(c *gin.Context) {
 			return
 		}
 	}
+	titles := make([]string, len(req.Movies))
+	for i, movie := range req.Movies {
+		titles[i] = movie.Title
+	}
+	duplicates, ok := h.possibleDuplicates(c, titles...)
+	if !ok {
+		return
+	}
 
-	if err := h.service.BulkInsertMovies(&req); err != nil {
-		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create movie")
+	if err := h.service.BulkInsertMovies(&req, utils.ActorFromContext(c)); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "A movie title is duplicated or already taken")
+		} else if errors.Is(err, repositories.ErrUnknownGenre) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown genre", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create movie")
+		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Movies created"})
+	c.JSON(http.StatusCreated, models.BulkInsertMoviesResponse{Message: "Movies created", PossibleDuplicates: duplicates})
 }
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// GetMovieCredits lists the cast and crew of a movie
+// @Summary Get movie credits
+// @Description List the people credited on a movie with their roles, in billing order
+// @Tags movies
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Success 200 {object} models.MovieCreditsResponse
+// @Header 200 {string} ETag "Version of the movie, for If-Match"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/credits [get]
+func (h *MovieHandler) GetMovieCredits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	credits, err := h.service.GetMovieCredits(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie credits")
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(credits.Version))
+	c.JSON(http.StatusOK, credits)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// ReplaceMovieCredits replaces the cast and crew of a movie
+// @Summary Replace movie credits
+// @Description Replace every credit of a movie. Only actors have a character. The movie's director field is set to the comma-joined names of the director credits (kept when there are none, rejected when longer than 255 characters), and the change bumps the movie's version.
+// @Tags movies
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param credits body models.ReplaceCreditsRequest true "Credits of the movie"
+// @Param If-Match header string false "ETag the replacement is conditional on"
+// @Success 200 {object} models.MovieCreditsResponse
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/credits [put]
+func (h *MovieHandler) ReplaceMovieCredits(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	var request models.ReplaceCreditsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return
+	}
+	for _, credit := range request.Credits {
+		if credit.Character != "" && credit.Role != models.CreditActor {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Only actor credits can have a character")
+			return
+		}
+	}
+	request.ID = uint(id)
+
+	current, err := h.service.GetMovieByID(request.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+		}
+		return
+	}
+	version, ok := checkIfMatch(c, current.Version)
+	if !ok {
+		return
+	}
+	request.Version = version
+
+	credits, err := h.service.ReplaceMovieCredits(&request, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrVersionConflict):
+			sendVersionConflict(c)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		case errors.Is(err, repositories.ErrUnknownPerson):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown person", err.Error())
+		case errors.Is(err, repositories.ErrDirectorTooLong):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid director", err.Error())
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Duplicate credit", "A person can be credited only once per role")
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to replace movie credits")
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(credits.Version))
+	c.JSON(http.StatusOK, credits)
+}
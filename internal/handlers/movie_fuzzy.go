@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"itv-task/internal/models"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultFuzzyThreshold is the similarity fuzzy searches start matching at, the pg_trgm default.
+const defaultFuzzyThreshold = 0.3
+
+// FuzzySearchMovies finds movies by approximate title
+// @Summary Fuzzy title search
+// @Description Find movies whose titles resemble the given one, most similar first. Titles are compared by trigram similarity, from 0 to 1, which tolerates typos and reordered words.
+// @Tags movies
+// @Produce json
+// @Param title query string true "Title to look for, e.g. matirx"
+// @Param threshold query number false "Lowest similarity to match, above 0 and up to 1 (default 0.3)"
+// @Param limit query int false "Limit results"
+// @Success 200 {object} models.SimilarMoviesResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/search/fuzzy [get]
+func (h *MovieHandler) FuzzySearchMovies(c *gin.Context) {
+	title := strings.TrimSpace(c.Query("title"))
+	if title == "" || len(title) > 255 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid title", "title is required and must be <= 255 characters")
+		return
+	}
+	threshold, ok := similarityThreshold(c, defaultFuzzyThreshold)
+	if !ok {
+		return
+	}
+	limit, ok := parseLimit(c)
+	if !ok {
+		return
+	}
+
+	movies, err := h.service.SearchSimilarMovies(title, threshold, limit)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to search movies")
+		return
+	}
+
+	c.JSON(http.StatusOK, movies)
+}
+
+// @Security ApiKeyAuth
+// GetDuplicateClusters lists likely duplicate movies
+// @Summary List likely duplicates
+// @Description Group the movies outside the trash whose titles are similar, closest groups first, for cleanup.
+// @Tags movies
+// @Produce json
+// @Param threshold query number false "Lowest similarity to group by, above 0 and up to 1 (default MOVIE_DUPLICATE_THRESHOLD)"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.DuplicateClustersResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/duplicates [get]
+func (h *MovieHandler) GetDuplicateClusters(c *gin.Context) {
+	threshold, ok := similarityThreshold(c, 0)
+	if !ok {
+		return
+	}
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	clusters, err := h.service.GetDuplicateClusters(threshold, limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to find duplicate movies")
+		return
+	}
+
+	c.JSON(http.StatusOK, clusters)
+}
+
+// similarityThreshold reads the threshold query parameter, which must be above 0 and at
+// most 1, falling back to the default when it is absent.
+func similarityThreshold(c *gin.Context, fallback float64) (float64, bool) {
+	thresholdStr := c.Query("threshold")
+	if thresholdStr == "" {
+		return fallback, true
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil || threshold <= 0 || threshold > 1 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid threshold", "threshold must be above 0 and at most 1")
+		return 0, false
+	}
+	return threshold, true
+}
+
+// possibleDuplicates looks for movies already in the catalogue whose titles resemble the
+// titles. The matches are a warning for the response, unless the reject_duplicates query
+// parameter is set: then it responds with 409 and the matches instead. It returns false
+// when it responded.
+func (h *MovieHandler) possibleDuplicates(c *gin.Context, titles ...string) ([]models.PossibleDuplicate, bool) {
+	reject, err := strconv.ParseBool(c.DefaultQuery("reject_duplicates", "false"))
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid reject_duplicates", "reject_duplicates must be true or false")
+		return nil, false
+	}
+
+	duplicates, err := h.service.FindPossibleDuplicates(titles...)
+	if err != nil {
+		if !reject {
+			return nil, true // Only a warning; the service has logged the failure
+		}
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to check for duplicate movies")
+		return nil, false
+	}
+	if reject && len(duplicates) > 0 {
+		c.JSON(http.StatusConflict, models.PossibleDuplicatesResponse{
+			ErrorResponse: models.NewErrorResponse(http.StatusConflict, "Possible duplicate",
+				"Similar movies already exist; repeat the request without reject_duplicates to create it anyway"),
+			Duplicates: duplicates,
+		})
+		return nil, false
+	}
+	return duplicates, true
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// GetMovieHistory lists the revisions of a movie
+// @Summary Get movie history
+// @Description List who changed a movie, when, and how, newest revision first. Deleted movies keep their history.
+// @Tags movies
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.MovieRevisionListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/history [get]
+func (h *MovieHandler) GetMovieHistory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	history, err := h.service.GetMovieHistory(uint(id), limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie history")
+		return
+	}
+	c.JSON(http.StatusOK, history)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// GetMovieRevision returns one revision of a movie
+// @Summary Get a movie revision
+// @Description Retrieve one revision of a movie with its before and after snapshots
+// @Tags movies
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param rev path int true "Revision number"
+// @Success 200 {object} models.MovieRevisionResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/history/{rev} [get]
+func (h *MovieHandler) GetMovieRevision(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil || rev <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid revision", "Revision must be a positive integer")
+		return
+	}
+
+	revision, err := h.service.GetMovieRevision(uint(id), uint(rev))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Revision not found", "The movie has no such revision")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie revision")
+		}
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// RollbackMovie restores a movie to an earlier revision
+// @Summary Roll back a movie
+// @Description Restore the movie's fields as they were after the given revision. The rollback is recorded as a new revision.
+// @Tags movies
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param request body models.RollbackMovieRequest true "Revision to roll back to"
+// @Param If-Match header string false "ETag the rollback is conditional on"
+// @Success 200 {object} models.MovieResponse
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/rollback [post]
+func (h *MovieHandler) RollbackMovie(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	var request models.RollbackMovieRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "A positive revision number is required")
+		return
+	}
+	request.ID = uint(id)
+
+	current, err := h.service.GetMovieByID(request.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+		}
+		return
+	}
+	version, ok := checkIfMatch(c, current.Version)
+	if !ok {
+		return
+	}
+	request.Version = version
+
+	movie, err := h.service.RollbackMovie(&request, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRevisionNotRestorable):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid revision", "The revision records a deletion and has no movie to restore")
+		case errors.Is(err, repositories.ErrVersionConflict):
+			sendVersionConflict(c)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Not found", "No such movie or revision")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Movie already exists", "Another movie now has the revision's title")
+		case errors.Is(err, repositories.ErrUnknownGenre):
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Unknown genre", "A genre of the revision has since been deleted: "+err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to roll back movie")
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(movie.Version))
+	c.JSON(http.StatusOK, movie)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportFileSize limits uploads to POST /movies/import.
+const maxImportFileSize = 10 << 20
+
+// importFormats maps file extensions to import formats.
+var importFormats = map[string]string{
+	".csv":    models.ImportCSV,
+	".tsv":    models.ImportTSV,
+	".tab":    models.ImportTSV,
+	".ndjson": models.ImportNDJSON,
+	".jsonl":  models.ImportNDJSON,
+}
+
+// ImportMovies imports movies from a CSV, TSV or NDJSON file
+// @Summary Import movies from a file
+// @Description Import movies from an uploaded CSV or TSV file with a header row, or an NDJSON file with an object per line. Rows hold title, director, year, plot and genres (separated by | in CSV and TSV, a list or |-separated string in NDJSON); mapping names other columns for them. all_or_nothing writes nothing if any row is rejected, skip_invalid writes the valid rows, and upsert also updates the movies whose title exists. The report lists the row number and reasons of every rejected row, and a dry run also reports on every row without writing anything.
+// @Tags movies
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// @Param file formData file true "CSV, TSV or NDJSON file, up to 10 MiB and 10000 rows"
+// @Param format formData string false "File format; taken from the file extension by default" Enums(csv, tsv, ndjson)
+// @Param mapping formData string false "JSON object mapping movie fields to columns or keys, e.g. {\"title\":\"Film\",\"year\":\"Released\"}"
+// @Param mode formData string false "Import mode (default all_or_nothing)" Enums(all_or_nothing, skip_invalid, upsert)
+// @Param dry_run formData bool false "Check every row without writing anything"
+// @Success 200 {object} models.MovieImportReport "Dry run"
+// @Success 201 {object} models.MovieImportReport "Imported"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 413 {object} models.ErrorResponse
+// @Failure 422 {object} models.MovieImportReport "All-or-nothing import with rejected rows; nothing was written"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/import [post]
+func (h *MovieHandler) ImportMovies(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportFileSize+64<<10)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.SendErrorResponse(c, http.StatusRequestEntityTooLarge, "File too large", "Import files are limited to 10 MiB")
+		} else {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid file", "Upload the file as the multipart form field file")
+		}
+		return
+	}
+	if header.Size > maxImportFileSize {
+		utils.SendErrorResponse(c, http.StatusRequestEntityTooLarge, "File too large", "Import files are limited to 10 MiB")
+		return
+	}
+
+	options := models.MovieImportOptions{
+		Format: strings.ToLower(c.PostForm("format")),
+		Mode:   c.DefaultPostForm("mode", models.ImportAllOrNothing),
+	}
+	if options.Format == "" {
+		options.Format = importFormats[strings.ToLower(filepath.Ext(header.Filename))]
+	}
+	switch options.Format {
+	case models.ImportCSV, models.ImportTSV, models.ImportNDJSON:
+	default:
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid format", "format must be csv, tsv or ndjson, or the file named .csv, .tsv or .ndjson")
+		return
+	}
+	switch options.Mode {
+	case models.ImportAllOrNothing, models.ImportSkipInvalid, models.ImportUpsert:
+	default:
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid mode", "mode must be all_or_nothing, skip_invalid or upsert")
+		return
+	}
+	if options.DryRun, err = strconv.ParseBool(c.DefaultPostForm("dry_run", "false")); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid dry_run", "dry_run must be true or false")
+		return
+	}
+	if mapping := c.PostForm("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &options.Mapping); err != nil {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid mapping", "mapping must be a JSON object of field names to column names")
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid file", "The uploaded file could not be read")
+		return
+	}
+	defer file.Close()
+
+	report, err := h.service.ImportMovies(file, options, utils.ActorFromContext(c))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidImport) {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid import file", err.Error())
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to import movies")
+		}
+		return
+	}
+
+	switch {
+	case report.DryRun:
+		c.JSON(http.StatusOK, report)
+	case !report.Committed:
+		c.JSON(http.StatusUnprocessableEntity, report)
+	default:
+		c.JSON(http.StatusCreated, report)
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"itv-task/pkg/utils"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQueryLength bounds the length of search queries, in characters.
+const maxSearchQueryLength = 256
+
+// SearchMovies runs a full-text search over movies
+// @Summary Search movies
+// @Description Full-text search over the title, director and plot of movies, most relevant first. The query uses web search syntax: "quoted phrases", -excluded words and OR. Each result has its relevance rank and a plot snippet with the matching words in <mark> tags; the plot text itself is not HTML-escaped. The filters of GET /movies narrow the results, and sort or sort_by orders them instead of relevance.
+// @Tags movies
+// @Produce json
+// @Param q query string true "Search query, e.g. dream heist -nolan"
+// @Param title query string false "Filter by title"
+// @Param director query string false "Filter by director"
+// @Param year query int false "Filter by year"
+// @Param genre query []string false "Filter by genre name or slug; repeat or comma-separate for several" collectionFormat(multi)
+// @Param genre_match query string false "Match movies with any (default) or all of the genres" Enums(any, all)
+// @Param min_rating query number false "Only movies with at least this average rating (1-10)"
+// @Param filter query string false "Filter expression, e.g. year>=1990 AND (director~\"nolan\" OR title^=\"The\")"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Param sort query string false "Comma-separated sort fields instead of relevance, - for descending, e.g. -year,title"
+// @Param sort_by query string false "Sort by field instead of relevance (title, year, created_at, director, rating)"
+// @Param sort_order query string false "Sort order (asc, desc)"
+// @Success 200 {object} models.MovieSearchResponse
+// @Failure 400 {object} models.FilterErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/search [get]
+func (h *MovieHandler) SearchMovies(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid query", "q is required and must be <= 256 characters")
+		return
+	}
+	filter, ok := parseMovieFilter(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.service.SearchMovies(query, filter)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to search movies")
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
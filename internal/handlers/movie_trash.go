@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// GetTrash lists deleted movies
+// @Summary List deleted movies
+// @Description List the movies in the trash, most recently deleted first. They are purged after MOVIE_TRASH_RETENTION.
+// @Tags movies
+// @Produce json
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.MovieListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/trash [get]
+func (h *MovieHandler) GetTrash(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	movies, err := h.service.GetTrash(limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve deleted movies")
+		return
+	}
+	c.JSON(http.StatusOK, movies)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// RestoreMovie takes a movie out of the trash
+// @Summary Restore a deleted movie
+// @Description Take a movie out of the trash. Fails if another movie has taken its title since.
+// @Tags movies
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param If-Match header string false "ETag the restore is conditional on"
+// @Success 200 {object} models.MovieResponse
+// @Header 200 {string} ETag "New version of the movie"
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "No such movie in the trash"
+// @Failure 409 {object} models.ErrorResponse "The title is taken by another movie"
+// @Failure 412 {object} models.ErrorResponse "The movie changed since the If-Match ETag"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/restore [post]
+func (h *MovieHandler) RestoreMovie(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	deleted, err := h.service.GetDeletedMovieByID(uint(id))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No deleted movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve movie")
+		}
+		return
+	}
+	version, ok := checkIfMatch(c, deleted.Version)
+	if !ok {
+		return
+	}
+
+	movie, err := h.service.RestoreMovie(uint(id), version, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrVersionConflict):
+			sendVersionConflict(c)
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No deleted movie found with the given ID")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusConflict, "Title taken", "Another movie now has this title; rename or delete it first")
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to restore movie")
+		}
+		return
+	}
+
+	c.Header("ETag", versionETag(movie.Version))
+	c.JSON(http.StatusOK, movie)
+}
+
+// hardDeleteMovie serves DELETE /movies/:id?hard=true, which only admins may use.
+func (h *MovieHandler) hardDeleteMovie(c *gin.Context, id uint) {
+	role, ok := utils.CurrentRole(c)
+	if !ok || !models.RoleAtLeast(role, models.RoleAdmin) {
+		utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "Only admins can permanently delete movies")
+		return
+	}
+
+	if err := h.service.HardDeleteMovie(id, utils.ActorFromContext(c)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to delete movie")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Movie permanently deleted"})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type PersonHandler struct {
+	service *services.PersonService
+}
+
+func NewPersonHandler(service *services.PersonService) *PersonHandler {
+	return &PersonHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// CreatePerson adds a person to the catalogue
+// @Summary Create a person
+// @Description Add someone who can be credited in a movie's cast or crew
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param person body models.PersonRequest true "Person data"
+// @Success 201 {object} models.PersonResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people [post]
+func (h *PersonHandler) CreatePerson(c *gin.Context) {
+	request, ok := bindPersonRequest(c)
+	if !ok {
+		return
+	}
+
+	person, err := h.service.CreatePerson(request)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create person")
+		return
+	}
+
+	c.JSON(http.StatusCreated, person)
+}
+
+// GetAllPeople lists people
+// @Summary Get all people
+// @Description List people by name, optionally only those whose name contains the given text
+// @Tags people
+// @Produce json
+// @Param name query string false "Filter by name"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.PersonListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people [get]
+func (h *PersonHandler) GetAllPeople(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	people, err := h.service.GetAllPeople(c.Query("name"), limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve people")
+		return
+	}
+
+	c.JSON(http.StatusOK, people)
+}
+
+// GetPersonByID retrieves a single person by ID
+// @Summary Get a person by ID
+// @Description Retrieve a person using their ID
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {object} models.PersonResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [get]
+func (h *PersonHandler) GetPersonByID(c *gin.Context) {
+	id, ok := personID(c)
+	if !ok {
+		return
+	}
+
+	person, err := h.service.GetPersonByID(id)
+	if err != nil {
+		sendPersonError(c, err, "Failed to retrieve person")
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// GetFilmography lists the movies a person is credited on
+// @Summary Get a person's filmography
+// @Description List the person's credits on movies outside the trash, newest movie first
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {object} models.FilmographyResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id}/filmography [get]
+func (h *PersonHandler) GetFilmography(c *gin.Context) {
+	id, ok := personID(c)
+	if !ok {
+		return
+	}
+
+	filmography, err := h.service.GetFilmography(id)
+	if err != nil {
+		sendPersonError(c, err, "Failed to retrieve filmography")
+		return
+	}
+
+	c.JSON(http.StatusOK, filmography)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// UpdatePerson updates a person
+// @Summary Update a person
+// @Description Replace a person's details. Movies keep their director field as written.
+// @Tags people
+// @Accept json
+// @Produce json
+// @Param id path int true "Person ID"
+// @Param person body models.PersonRequest true "Person data"
+// @Success 200 {object} models.PersonResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [put]
+func (h *PersonHandler) UpdatePerson(c *gin.Context) {
+	id, ok := personID(c)
+	if !ok {
+		return
+	}
+	request, ok := bindPersonRequest(c)
+	if !ok {
+		return
+	}
+
+	person, err := h.service.UpdatePerson(id, request)
+	if err != nil {
+		sendPersonError(c, err, "Failed to update person")
+		return
+	}
+
+	c.JSON(http.StatusOK, person)
+}
+
+// @Security ApiKeyAuth
+// @Security ApiKeyHeader
+// DeletePerson deletes a person
+// @Summary Delete a person
+// @Description Delete a person who is not credited on any movie, including movies in the trash
+// @Tags people
+// @Produce json
+// @Param id path int true "Person ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "The person still has credits"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /people/{id} [delete]
+func (h *PersonHandler) DeletePerson(c *gin.Context) {
+	id, ok := personID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeletePerson(id); err != nil {
+		sendPersonError(c, err, "Failed to delete person")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Person deleted"})
+}
+
+func personID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Person ID must be a positive integer")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bindPersonRequest(c *gin.Context) (*models.PersonRequest, bool) {
+	var request models.PersonRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", err.Error())
+		return nil, false
+	}
+	return &request, true
+}
+
+// sendPersonError maps errors of single-person operations to responses.
+func sendPersonError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Person not found", "No person found with the given ID")
+	case errors.Is(err, repositories.ErrPersonHasCredits):
+		utils.SendErrorResponse(c, http.StatusConflict, "Person has credits", "Remove the person from every movie's credits first")
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", failure)
+	}
+}
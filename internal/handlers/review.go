@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+}
+
+func NewReviewHandler(service *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// ReviewMovie adds the caller's review of a movie
+// @Summary Review a movie
+// @Description Rate a movie from 1 to 10, optionally with a text. Each user reviews a movie once; edit the review to change it.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param review body models.ReviewRequest true "Review"
+// @Success 201 {object} models.ReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "API keys cannot write reviews"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse "The user already reviewed the movie"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/reviews [post]
+func (h *ReviewHandler) ReviewMovie(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+	request, ok := bindReviewRequest(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.service.CreateReview(uint(id), request, utils.ActorFromContext(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		case errors.Is(err, gorm.ErrDuplicatedKey):
+			utils.SendErrorResponse(c, http.StatusConflict, "Already reviewed", "You have already reviewed this movie; edit your review instead")
+		default:
+			sendReviewError(c, err, "Failed to create review")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetMovieReviews lists the reviews of a movie
+// @Summary Get movie reviews
+// @Description List the reviews of a movie, newest first. The movie's rating_average and rating_count summarise them.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Movie ID"
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.ReviewListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /movies/{id}/reviews [get]
+func (h *ReviewHandler) GetMovieReviews(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Movie ID must be a positive integer")
+		return
+	}
+
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	reviews, err := h.service.GetMovieReviews(uint(id), limit, offset)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.SendErrorResponse(c, http.StatusNotFound, "Movie not found", "No movie found with the given ID")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve reviews")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// GetReviewByID retrieves a single review by ID
+// @Summary Get a review by ID
+// @Description Retrieve a review using its ID
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} models.ReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reviews/{id} [get]
+func (h *ReviewHandler) GetReviewByID(c *gin.Context) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.service.GetReviewByID(id)
+	if err != nil {
+		sendReviewError(c, err, "Failed to retrieve review")
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// @Security ApiKeyAuth
+// UpdateReview edits the caller's review
+// @Summary Edit a review
+// @Description Change the rating and text of your own review
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Param id path int true "Review ID"
+// @Param review body models.ReviewRequest true "Review"
+// @Success 200 {object} models.ReviewResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The review belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reviews/{id} [put]
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+	request, ok := bindReviewRequest(c)
+	if !ok {
+		return
+	}
+
+	review, err := h.service.UpdateReview(id, request, utils.ActorFromContext(c))
+	if err != nil {
+		sendReviewError(c, err, "Failed to update review")
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// @Security ApiKeyAuth
+// DeleteReview deletes a review
+// @Summary Delete a review
+// @Description Delete your own review. Admins can delete any review.
+// @Tags reviews
+// @Produce json
+// @Param id path int true "Review ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "The review belongs to another user"
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /reviews/{id} [delete]
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	id, ok := reviewID(c)
+	if !ok {
+		return
+	}
+
+	role, _ := utils.CurrentRole(c)
+	if err := h.service.DeleteReview(id, utils.ActorFromContext(c), role == models.RoleAdmin); err != nil {
+		sendReviewError(c, err, "Failed to delete review")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Review deleted"})
+}
+
+func reviewID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "Review ID must be a positive integer")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func bindReviewRequest(c *gin.Context) (*models.ReviewRequest, bool) {
+	var request models.ReviewRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Rating must be between 1 and 10 and the text at most 10000 characters")
+		return nil, false
+	}
+	return &request, true
+}
+
+// sendReviewError maps errors of single-review operations to responses.
+func sendReviewError(c *gin.Context, err error, failure string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.SendErrorResponse(c, http.StatusNotFound, "Review not found", "No review found with the given ID")
+	case errors.Is(err, services.ErrReviewerRequired):
+		utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "Reviews are written by users; API keys cannot write them")
+	case errors.Is(err, services.ErrNotReviewAuthor):
+		utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "You can only change your own reviews")
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", failure)
+	}
+}
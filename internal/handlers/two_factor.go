@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VerifyTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchange the challenge token returned by /auth/login and a TOTP or recovery code for tokens
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorVerifyRequest true "Challenge token and code"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse "Locked out; see the Retry-After header"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var request models.TwoFactorVerifyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "challenge_token and code are required")
+		return
+	}
+	request.IP = c.ClientIP()
+	request.UserAgent = c.Request.UserAgent()
+
+	response, err := h.AuthService.VerifyTwoFactor(request)
+	if err != nil {
+		var lockErr *services.LoginLockedError
+		switch {
+		case errors.As(err, &lockErr):
+			sendLoginLocked(c, lockErr)
+		case errors.Is(err, services.ErrInvalidChallenge):
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid challenge", "Log in again to get a new challenge token")
+		case errors.Is(err, services.ErrInvalidTwoFactorCode):
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid code", err.Error())
+		default:
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to verify code")
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// EnrollTwoFactor godoc
+// @Summary Start two-factor enrollment
+// @Description Generate a TOTP secret for the authenticated user. Render `otpauth_uri` as a QR code,
+// @Description then confirm with a code from the authenticator app to enable two-factor authentication.
+// @Tags Auth
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.TwoFactorEnrollResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/2fa/enroll [post]
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+
+	response, err := h.TwoFactorService.Enroll(userID)
+	if err != nil {
+		sendTwoFactorError(c, err, "Failed to start enrollment")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Confirm two-factor enrollment
+// @Description Enable two-factor authentication with a code from the authenticator app.
+// @Description Returns one-time recovery codes, which are shown only this once.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.TwoFactorConfirmRequest true "TOTP code"
+// @Success 200 {object} models.TwoFactorConfirmResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var request models.TwoFactorConfirmRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "code is required")
+		return
+	}
+
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+	request.UserID = userID
+
+	response, err := h.TwoFactorService.Confirm(request)
+	if err != nil {
+		sendTwoFactorError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turn off two-factor authentication; requires the password and a TOTP or recovery code
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.TwoFactorDisableRequest true "Password and code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 429 {object} models.ErrorResponse "Locked out; see the Retry-After header"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var request models.TwoFactorDisableRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "password and code are required")
+		return
+	}
+
+	userID, ok := utils.CurrentUserID(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token does not identify a user")
+		return
+	}
+	request.UserID = userID
+	request.IP = c.ClientIP()
+
+	if err := h.TwoFactorService.Disable(request); err != nil {
+		sendTwoFactorError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+func sendTwoFactorError(c *gin.Context, err error, detail string) {
+	var lockErr *services.LoginLockedError
+	switch {
+	case errors.As(err, &lockErr):
+		sendLoginLocked(c, lockErr)
+	case errors.Is(err, services.ErrInvalidCredentials):
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid credentials", "Password is incorrect")
+	case errors.Is(err, services.ErrInvalidTwoFactorCode):
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Invalid code", err.Error())
+	case errors.Is(err, services.ErrTwoFactorAlreadyEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled),
+		errors.Is(err, services.ErrTwoFactorNotEnabled):
+		utils.SendErrorResponse(c, http.StatusConflict, "Conflict", err.Error())
+	default:
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", detail)
+	}
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/services"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type UserHandler struct {
+	service *services.UserService
+}
+
+func NewUserHandler(service *services.UserService) *UserHandler {
+	return &UserHandler{service: service}
+}
+
+// @Security ApiKeyAuth
+// CreateUser creates a new user account
+// @Summary Create a user
+// @Description Create a user account (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param user body models.CreateUserRequest true "User data"
+// @Success 201 {object} models.UserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 409 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users [post]
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var request models.CreateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Username must be 3-255 characters and password 8-72 characters")
+		return
+	}
+
+	user, err := h.service.CreateUser(request)
+	if err != nil {
+		if errors.Is(err, services.ErrUsernameTaken) {
+			utils.SendErrorResponse(c, http.StatusConflict, "Username taken", "A user with the same username already exists")
+		} else {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to create user")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, user)
+}
+
+// @Security ApiKeyAuth
+// GetAllUsers lists user accounts
+// @Summary Get all users
+// @Description Retrieve a paginated list of users (admin only)
+// @Tags users
+// @Produce json
+// @Param limit query int false "Limit results"
+// @Param offset query int false "Offset results"
+// @Success 200 {object} models.UserListResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users [get]
+func (h *UserHandler) GetAllUsers(c *gin.Context) {
+	limit, offset, ok := parsePagination(c)
+	if !ok {
+		return
+	}
+
+	users, err := h.service.GetAllUsers(limit, offset)
+	if err != nil {
+		utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to retrieve users")
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// @Security ApiKeyAuth
+// GetUserByID retrieves a user account
+// @Summary Get a user by ID
+// @Description Retrieve a user account using its ID (admin only)
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /users/{id} [get]
+func (h *UserHandler) GetUserByID(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.service.GetUserByID(id)
+	if err != nil {
+		sendUserError(c, err, "Failed to retrieve user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// @Security ApiKeyAuth
+// UpdateUser updates a user account
+// @Summary Update a user
+// @Description Reset a user's password and revoke their sessions (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param user body models.UpdateUserRequest true "Fields to update"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id} [put]
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Password must be 8-72 characters")
+		return
+	}
+	request.ID = id
+
+	user, err := h.service.UpdateUser(request)
+	if err != nil {
+		sendUserError(c, err, "Failed to update user")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// @Security ApiKeyAuth
+// AssignRole changes the role of a user
+// @Summary Assign a role
+// @Description Set the role (viewer, editor, admin) of a user and revoke their sessions (admin only)
+// @Tags users
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body models.UpdateUserRoleRequest true "New role"
+// @Success 200 {object} models.UserResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id}/role [put]
+func (h *UserHandler) AssignRole(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	var request models.UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "Role must be one of viewer, editor, admin")
+		return
+	}
+	request.ID = id
+
+	if currentID, _ := utils.CurrentUserID(c); currentID == id && request.Role != models.RoleAdmin {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "You cannot remove your own admin role")
+		return
+	}
+
+	user, err := h.service.AssignRole(request)
+	if err != nil {
+		sendUserError(c, err, "Failed to assign role")
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// @Security ApiKeyAuth
+// DeleteUser deletes a user account
+// @Summary Delete a user
+// @Description Remove a user account and revoke its sessions (admin only)
+// @Tags users
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /users/{id} [delete]
+func (h *UserHandler) DeleteUser(c *gin.Context) {
+	id, ok := parseUserID(c)
+	if !ok {
+		return
+	}
+
+	if currentID, _ := utils.CurrentUserID(c); currentID == id {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid request", "You cannot delete your own account")
+		return
+	}
+
+	if err := h.service.DeleteUser(id); err != nil {
+		sendUserError(c, err, "Failed to delete user")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User deleted"})
+}
+
+func parseUserID(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil || id <= 0 {
+		utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid ID", "User ID must be a positive integer")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func sendUserError(c *gin.Context, err error, detail string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.SendErrorResponse(c, http.StatusNotFound, "User not found", "No user found with the given ID")
+		return
+	}
+	utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", detail)
+}
+
+// parsePagination reads limit/offset query parameters, defaulting to the first 10 rows.
+func parsePagination(c *gin.Context) (int, int, bool) {
+	limit, ok := parseLimit(c)
+	if !ok {
+		return 0, 0, false
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		var err error
+		offset, err = strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid offset", "Offset must be a non-negative number")
+			return 0, 0, false
+		}
+	}
+
+	return limit, offset, true
+}
+
+// parseLimit reads the limit query parameter of listings without an offset, defaulting to 10.
+func parseLimit(c *gin.Context) (int, bool) {
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		var err error
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			utils.SendErrorResponse(c, http.StatusBadRequest, "Invalid limit", "Limit must be a positive number")
+			return 0, false
+		}
+	}
+	return limit, true
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey lets machine clients call the API without a user account. Only the SHA-256
+// hash of the key is stored; the key itself is shown once on creation.
+type APIKey struct {
+	ID          uint   `gorm:"primaryKey;autoIncrement"`
+	Name        string `gorm:"type:varchar(255);not null"`
+	Prefix      string `gorm:"type:varchar(16);not null"`
+	KeyHash     string `gorm:"type:varchar(64);not null;uniqueIndex:idx_api_keys_key_hash"`
+	Scopes      string `gorm:"type:text;not null"` // Comma separated
+	CreatedByID uint   `gorm:"not null"`
+	ExpiresAt   *time.Time
+	LastUsedAt  *time.Time
+	RevokedAt   *time.Time
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+// ScopeList returns the scopes granted to the key.
+func (k *APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(k.Scopes, ",")
+}
+
+// HasScope reports whether the key was granted the given scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, granted := range k.ScopeList() {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateAPIKeyRequest struct {
+	Name        string     `json:"name" binding:"required,max=255" example:"nightly-ingest"`
+	Scopes      []string   `json:"scopes" binding:"required,min=1,dive,oneof=movies:read movies:write movies:delete movies:bulk" example:"movies:read,movies:bulk"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" example:"2026-01-01T00:00:00Z"`
+	CreatedByID uint       `json:"-"`
+}
+
+type APIKeyResponse struct {
+	ID         uint       `json:"id" example:"1"`
+	Name       string     `json:"name" example:"nightly-ingest"`
+	Prefix     string     `json:"prefix" example:"itv_3f9a1c2b"`
+	Scopes     []string   `json:"scopes" example:"movies:read,movies:bulk"`
+	ExpiresAt  *time.Time `json:"expires_at" example:"2026-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at" example:"2025-03-22T15:04:05Z"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key" example:"itv_3f9a1c2b5d7e9f0a1b2c3d4e5f60718293a4b5c6"` // Only returned once
+}
+
+type APIKeyListResponse struct {
+	APIKeys []APIKeyResponse `json:"api_keys"`
+	Count   int              `json:"count" example:"3"`
+}
@@ -5,27 +5,88 @@ import "time"
 type LoginRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+
+	// Filled from the HTTP request for throttling and the audit log.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
 }
 
 type LoginResponse struct {
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+
+	// Set instead of the tokens when the user has two-factor authentication enabled;
+	// the challenge token is exchanged for the tokens at /auth/2fa/verify.
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
 }
 
 type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+
+	// Filled from the access token used to call the endpoint.
+	UserID               uint      `json:"-"`
+	AccessTokenJTI       string    `json:"-"`
+	AccessTokenExpiresAt time.Time `json:"-"`
+}
+
 type TokenClaims struct {
 	Username string `json:"username"`
 	Exp      int64  `json:"exp"`
 	Iat      int64  `json:"iat"`
 }
 
+// Reasons stored in AuthToken.RevokedReason.
+const (
+	TokenRevokedRotated       = "rotated"
+	TokenRevokedLogout        = "logout"
+	TokenRevokedLogoutAll     = "logout_all"
+	TokenRevokedReuseDetected = "reuse_detected"
+	TokenRevokedByAdmin       = "revoked_by_admin"
+)
+
+// AuthToken is an issued refresh token. Only the SHA-256 hash of the token is stored.
+// Every refresh rotates the token inside the same family, so presenting a rotated-out
+// token again reveals a leak and revokes the whole family.
 type AuthToken struct {
-	ID           uint      `gorm:"primaryKey;autoIncrement"`
-	Username     string    `gorm:"type:varchar(255);not null"`
-	RefreshToken string    `gorm:"type:text;not null"`
-	ExpiresAt    time.Time `gorm:"not null"`
-	CreatedAt    time.Time `gorm:"autoCreateTime"`
+	ID            uint       `gorm:"primaryKey;autoIncrement"`
+	UserID        uint       `gorm:"not null;index:idx_auth_tokens_user_id"`
+	Username      string     `gorm:"type:varchar(255);not null"`
+	TokenHash     string     `gorm:"type:varchar(64);not null;uniqueIndex:idx_auth_tokens_token_hash"`
+	FamilyID      string     `gorm:"type:varchar(64);not null;index:idx_auth_tokens_family_id"`
+	ExpiresAt     time.Time  `gorm:"not null"`
+	RevokedAt     *time.Time `gorm:"index:idx_auth_tokens_revoked_at"`
+	RevokedReason string     `gorm:"type:varchar(32)"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime"`
+}
+
+// RevokedToken is a denylisted access token, kept until the token would have expired anyway.
+type RevokedToken struct {
+	JTI       string    `gorm:"type:varchar(64);primaryKey"`
+	UserID    uint      `gorm:"not null;index:idx_revoked_tokens_user_id"`
+	ExpiresAt time.Time `gorm:"not null;index:idx_revoked_tokens_expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+// UserTokenCutoff invalidates every access token of a user issued before RevokedBefore.
+type UserTokenCutoff struct {
+	UserID        uint      `gorm:"primaryKey"`
+	RevokedBefore time.Time `gorm:"not null"`
+	ExpiresAt     time.Time `gorm:"not null;index:idx_user_token_cutoffs_expires_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+type RevokeTokenRequest struct {
+	JTI       string     `json:"jti" binding:"required,max=64" example:"9f86d081884c7d659a2feaa0c55ad015"`
+	UserID    uint       `json:"user_id" example:"1"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty" example:"2025-03-23T15:04:05Z"`
+}
+
+type RevokeUserTokensRequest struct {
+	UserID uint       `json:"-"`
+	Before *time.Time `json:"before,omitempty" example:"2025-03-22T15:04:05Z"`
 }
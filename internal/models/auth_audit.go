@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Events recorded in the auth audit log.
+const (
+	AuthEventLoginSuccess = "login_success"
+	AuthEventLoginFailure = "login_failure"
+	AuthEventLoginLocked  = "login_locked"
+
+	AuthEventTwoFactorChallenge = "2fa_challenge" // Password accepted, waiting for the TOTP code
+	AuthEventTwoFactorFailure   = "2fa_failure"
+	AuthEventRecoveryCodeUsed   = "recovery_code_used"
+	AuthEventTwoFactorEnabled   = "2fa_enabled"
+	AuthEventTwoFactorDisabled  = "2fa_disabled"
+)
+
+type AuthAuditLog struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Event     string    `gorm:"type:varchar(32);not null;index:idx_auth_audit_logs_event"`
+	Username  string    `gorm:"type:varchar(255);not null;index:idx_auth_audit_logs_username"`
+	UserID    *uint     `gorm:"index:idx_auth_audit_logs_user_id"`
+	IP        string    `gorm:"type:varchar(64)"`
+	UserAgent string    `gorm:"type:varchar(512)"`
+	Reason    string    `gorm:"type:varchar(255)"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index:idx_auth_audit_logs_created_at"`
+}
+
+type AuthAuditLogResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	Event     string    `json:"event" example:"login_failure"`
+	Username  string    `json:"username" example:"admin"`
+	UserID    *uint     `json:"user_id" example:"1"`
+	IP        string    `json:"ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" example:"curl/8.5.0"`
+	Reason    string    `json:"reason" example:"invalid credentials"`
+	CreatedAt time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type AuthAuditLogListResponse struct {
+	Entries []AuthAuditLogResponse `json:"entries"`
+	Count   int                    `json:"count" example:"100"`
+}
@@ -0,0 +1,55 @@
+package models
+
+// SimilarMovie is a movie whose title resembles another title, scored by trigram
+// similarity from 0 (nothing in common) to 1 (the same words).
+type SimilarMovie struct {
+	ID       uint    `json:"id" example:"1"`
+	Title    string  `json:"title" example:"Matrix, The"`
+	Director string  `json:"director" example:"Lana Wachowski"`
+	Year     int     `json:"year" example:"1999"`
+	Score    float64 `json:"score" example:"0.83"`
+}
+
+type SimilarMoviesResponse struct {
+	Results []SimilarMovie `json:"results"`
+}
+
+// PossibleDuplicate lists the movies whose titles are close to the title of a movie
+// being created.
+type PossibleDuplicate struct {
+	Title   string         `json:"title" example:"The Matrix"`
+	Matches []SimilarMovie `json:"matches"`
+}
+
+// PossibleDuplicatesResponse is the 409 response to creating movies that look like
+// movies already in the catalogue, when the request asked for them to be rejected.
+type PossibleDuplicatesResponse struct {
+	ErrorResponse
+	Duplicates []PossibleDuplicate `json:"duplicates"`
+}
+
+// CreateMovieResponse is the created movie, with a warning listing the movies its title
+// resembles, if any.
+type CreateMovieResponse struct {
+	CreateMovieRequest
+	PossibleDuplicates []PossibleDuplicate `json:"possible_duplicates,omitempty"`
+}
+
+// BulkInsertMoviesResponse confirms a bulk insert, with a warning listing the movies the
+// new titles resemble, if any.
+type BulkInsertMoviesResponse struct {
+	Message            string              `json:"message" example:"Movies created"`
+	PossibleDuplicates []PossibleDuplicate `json:"possible_duplicates,omitempty"`
+}
+
+// DuplicateCluster is a group of movies linked by similar titles. The score of each movie
+// is its highest similarity to another movie of the cluster.
+type DuplicateCluster struct {
+	Movies []SimilarMovie `json:"movies"`
+	Score  float64        `json:"score" example:"1"` // Highest similarity within the cluster
+}
+
+type DuplicateClustersResponse struct {
+	Clusters []DuplicateCluster `json:"clusters"`
+	Count    int                `json:"count" example:"4"`
+}
@@ -13,3 +13,10 @@ func NewErrorResponse(code int, message string, detail string) ErrorResponse {
 		Detail:  detail,
 	}
 }
+
+// FilterErrorResponse is the 400 response to an invalid filter expression, pointing at
+// the 1-based character position of the problem.
+type FilterErrorResponse struct {
+	ErrorResponse
+	Position int `json:"position" example:"14"`
+}
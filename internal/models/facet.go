@@ -0,0 +1,20 @@
+package models
+
+// FacetValue is one value of a facet dimension with the number of matching movies that
+// have it. Value is what the movie filters take; Label, when set, is for display.
+type FacetValue struct {
+	Value string `json:"value" example:"1990"`
+	Label string `json:"label,omitempty" example:"1990s"`
+	Count int    `json:"count" example:"12"`
+}
+
+// MovieFacetsResponse counts the movies matching a filter along every facet dimension.
+// Each dimension lists its values by count, highest first.
+type MovieFacetsResponse struct {
+	Count     int          `json:"count" example:"100"` // Movies matching the filter
+	Decades   []FacetValue `json:"decades"`
+	Years     []FacetValue `json:"years"`
+	Directors []FacetValue `json:"directors"`
+	Genres    []FacetValue `json:"genres"`  // Valued by slug, labelled by name; movies count once per genre
+	Ratings   []FacetValue `json:"ratings"` // Whole part of the average rating; unrated movies are left out
+}
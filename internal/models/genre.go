@@ -0,0 +1,67 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Values of MovieFilter.GenreMatch.
+const (
+	GenreMatchAny = "any" // Movies with at least one of the genres
+	GenreMatchAll = "all" // Movies with every one of the genres
+)
+
+// Genre is a category movies can be browsed by. Names are matched by their slug, so
+// "Sci-Fi", "sci fi" and "sci-fi" all refer to the same genre.
+type Genre struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Name      string    `gorm:"type:varchar(64);not null"`
+	Slug      string    `gorm:"type:varchar(64);not null;uniqueIndex:idx_genres_slug"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// MovieGenre links a movie to a genre. Links are removed together with either side.
+type MovieGenre struct {
+	MovieID uint   `gorm:"primaryKey"`
+	GenreID uint   `gorm:"primaryKey;index:idx_movie_genres_genre_id"`
+	Movie   *Movie `gorm:"constraint:OnDelete:CASCADE"`
+	Genre   *Genre `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// GenreSlug normalizes a genre name: lower case, with runs of anything other than letters
+// and digits collapsed into single dashes.
+func GenreSlug(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return slug.String()
+}
+
+type GenreRequest struct {
+	Name string `json:"name" binding:"required,max=64" example:"Science Fiction"`
+}
+
+type GenreResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	Name       string    `json:"name" example:"Science Fiction"`
+	Slug       string    `json:"slug" example:"science-fiction"`
+	MovieCount int       `json:"movie_count" example:"42"` // Movies with the genre, among those matching the request's filters
+	CreatedAt  time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type GenreListResponse struct {
+	Genres []GenreResponse `json:"genres"`
+	Count  int             `json:"count" example:"12"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// DefaultListName is the name of the watchlist every user has.
+const DefaultListName = "Watchlist"
+
+// MovieList is a user's ordered list of movies. Each user has one default list, the
+// watchlist, created on first use, and any number of named lists.
+type MovieList struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_movie_lists_user_name,priority:1;uniqueIndex:idx_movie_lists_user_default,where:is_default"`
+	Name      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_movie_lists_user_name,priority:2"`
+	IsDefault bool      `gorm:"not null;default:false"`
+	Public    bool      `gorm:"not null;default:false"` // Readable by every signed-in user
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// MovieListEntry places a movie on a list. Entries of movies in the trash are kept but
+// hidden, and come back when the movie is restored.
+type MovieListEntry struct {
+	ID       uint       `gorm:"primaryKey;autoIncrement"`
+	ListID   uint       `gorm:"not null;uniqueIndex:idx_movie_list_entries_list_movie,priority:1;index:idx_movie_list_entries_list_position,priority:1"`
+	MovieID  uint       `gorm:"not null;uniqueIndex:idx_movie_list_entries_list_movie,priority:2;index:idx_movie_list_entries_movie_id"`
+	Position int        `gorm:"not null;index:idx_movie_list_entries_list_position,priority:2"` // 1-based, counting hidden entries
+	AddedAt  time.Time  `gorm:"autoCreateTime"`
+	List     *MovieList `gorm:"foreignKey:ListID;constraint:OnDelete:CASCADE"`
+	Movie    *Movie     `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// WatchedEntry logs that a user watched a movie. Rewatches are separate entries.
+type WatchedEntry struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	UserID    uint      `gorm:"not null;index:idx_watched_entries_user_watched_at,priority:1"`
+	MovieID   uint      `gorm:"not null;index:idx_watched_entries_movie_id"`
+	WatchedAt time.Time `gorm:"not null;index:idx_watched_entries_user_watched_at,priority:2"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	Movie     *Movie    `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type ListRequest struct {
+	Name   string `json:"name" binding:"required,max=100" example:"Rainy Sunday"`
+	Public bool   `json:"public" example:"false"`
+}
+
+type ListEntryRequest struct {
+	MovieID  uint `json:"movie_id" binding:"required" example:"1"`
+	Position int  `json:"position" binding:"gte=0" example:"1"` // 0 appends
+}
+
+type ReorderListRequest struct {
+	MovieIDs []uint `json:"movie_ids" binding:"required,max=1000" example:"3,1,2"`
+}
+
+type WatchedRequest struct {
+	MovieID   uint       `json:"movie_id" binding:"required" example:"1"`
+	WatchedAt *time.Time `json:"watched_at" example:"2025-03-22T20:30:00Z"` // Defaults to now
+}
+
+type ListResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	UserID     uint      `json:"user_id" example:"7"`
+	Name       string    `json:"name" example:"Watchlist"`
+	Default    bool      `json:"default" example:"true"`
+	Public     bool      `json:"public" example:"false"`
+	EntryCount int       `json:"entry_count" example:"3"`
+	CreatedAt  time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type ListEntryResponse struct {
+	MovieID  uint      `json:"movie_id" example:"1"`
+	Title    string    `json:"title" example:"Inception"`
+	Year     int       `json:"year" example:"2010"`
+	Position int       `json:"position" example:"1"`
+	AddedAt  time.Time `json:"added_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type ListDetailResponse struct {
+	ListResponse
+	Entries []ListEntryResponse `json:"entries"`
+}
+
+type ListsResponse struct {
+	Lists []ListResponse `json:"lists"`
+	Count int            `json:"count" example:"2"`
+}
+
+type WatchedResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	MovieID   uint      `json:"movie_id" example:"1"`
+	Title     string    `json:"title" example:"Inception"`
+	Year      int       `json:"year" example:"2010"`
+	WatchedAt time.Time `json:"watched_at" example:"2025-03-22T20:30:00Z"`
+}
+
+type WatchedListResponse struct {
+	Entries []WatchedResponse `json:"entries"`
+	Count   int               `json:"count" example:"25"`
+}
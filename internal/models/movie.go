@@ -1,27 +1,34 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"itv-task/pkg/filterql"
+
 	"gorm.io/gorm"
 )
 
 type Movie struct {
-	ID        uint           `gorm:"primaryKey;autoIncrement"`
-	Title     string         `gorm:"type:varchar(255);not null;uniqueIndex:idx_movies_title"` // Unique index for title
-	Director  string         `gorm:"type:varchar(255);not null;index:idx_movies_director"`    // Index for director
-	Year      int            `gorm:"index:idx_movies_year"`                                   // Index for faster search by year
-	Plot      string         `gorm:"type:text"`
-	CreatedAt time.Time      `gorm:"autoCreateTime"`
-	UpdatedAt time.Time      `gorm:"autoUpdateTime"`
-	DeletedAt gorm.DeletedAt `gorm:"index:idx_movies_deleted_at"` // Index for soft deletes
+	ID            uint           `gorm:"primaryKey;autoIncrement"`
+	Title         string         `gorm:"type:varchar(255);not null;uniqueIndex:idx_movies_title_active,where:deleted_at IS NULL"` // Unique among movies not in the trash
+	Director      string         `gorm:"type:varchar(255);not null;index:idx_movies_director"`                                    // Index for director
+	Year          int            `gorm:"index:idx_movies_year"`                                                                   // Index for faster search by year
+	Plot          string         `gorm:"type:text"`
+	Version       uint           `gorm:"not null;default:1"`                                                   // Incremented on every change, exposed as the ETag
+	RatingAverage float64        `gorm:"type:numeric(4,2);not null;default:0;index:idx_movies_rating_average"` // Kept by the review repository; not versioned
+	RatingCount   int            `gorm:"not null;default:0"`
+	CreatedAt     time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt     gorm.DeletedAt `gorm:"index:idx_movies_deleted_at"` // Index for soft deletes
 }
 
 type CreateMovieRequest struct {
-	Title    string `json:"title" binding:"required,max=255" example:"Inception"`
-	Director string `json:"director" binding:"required,max=255" example:"Christopher Nolan"`
-	Year     int    `json:"year" binding:"required,gte=1888,lte=2025" example:"2010"`
-	Plot     string `json:"plot" example:"A skilled thief is given a chance to erase his criminal past by performing an impossible task."`
+	Title    string   `json:"title" binding:"required,max=255" example:"Inception"`
+	Director string   `json:"director" binding:"required,max=255" example:"Christopher Nolan"`
+	Year     int      `json:"year" binding:"required,gte=1888,lte=2025" example:"2010"`
+	Plot     string   `json:"plot" example:"A skilled thief is given a chance to erase his criminal past by performing an impossible task."`
+	Genres   []string `json:"genres" binding:"max=20,dive,required,max=64" example:"Science Fiction,Thriller"`
 }
 
 type BulkInsertMoviesRequest struct {
@@ -30,23 +37,111 @@ type BulkInsertMoviesRequest struct {
 
 type UpdateMovieRequest struct {
 	ID       uint   `json:"-"`
+	Version  uint   `json:"-"` // Expected current version from If-Match; 0 skips the check
 	Title    string `json:"title" binding:"max=255" example:"Inception"`
 	Director string `json:"director" binding:"max=255" example:"Christopher Nolan"`
 	Year     int    `json:"year" binding:"gte=1888,lte=2025" example:"2010"`
 	Plot     string `json:"plot" example:"A skilled thief is given a chance to erase his criminal past by performing an impossible task."`
+	// Genres replaces the movie's genres; leaving it out keeps them and an empty list clears them.
+	Genres []string `json:"genres" binding:"max=20,dive,required,max=64" example:"Science Fiction,Thriller"`
+}
+
+// PatchMovieRequest holds the fields a PATCH changes; nil fields are left untouched.
+// Clients send either a JSON Merge Patch or a JSON Patch, which the handler resolves
+// against the stored movie into this request.
+type PatchMovieRequest struct {
+	ID       uint      `json:"-"`
+	Version  uint      `json:"-"` // Expected current version from If-Match; 0 skips the check
+	Title    *string   `json:"title,omitempty" example:"Inception"`
+	Director *string   `json:"director,omitempty" example:"Christopher Nolan"`
+	Year     *int      `json:"year,omitempty" example:"2010"`
+	Plot     *string   `json:"plot,omitempty" example:"A thief who steals corporate secrets through dream-sharing technology."`
+	Genres   *[]string `json:"genres,omitempty" example:"Science Fiction,Thriller"`
+}
+
+// Empty reports whether the patch changes nothing.
+func (r *PatchMovieRequest) Empty() bool {
+	return r.Title == nil && r.Director == nil && r.Year == nil && r.Plot == nil && r.Genres == nil
+}
+
+// MovieFilter selects and orders a page of movies.
+type MovieFilter struct {
+	Title      string        // Case-insensitive substring
+	Director   string        // Case-insensitive substring
+	Year       int           // Exact year; 0 matches any
+	Genres     []string      // Genre slugs
+	GenreMatch string        // GenreMatchAny (default) or GenreMatchAll
+	MinRating  float64       // Lowest average rating; 0 matches any, including unrated movies
+	Expression filterql.Expr // Parsed filter= expression; nil matches any
+	Sort       []SortField   // Sort keys, ties broken by ascending ID; empty sorts by ID alone
+	Limit      int
+	Offset     int
+	Cursor     *MovieCursor // Keyset position to page from, instead of or after Offset
+	SkipTotal  bool         // Leave out the total number of matches
+	Fields     []string     // JSON names of the fields to load, as in MovieView; empty loads them all
+}
+
+// SortField is one key of a movie ordering, named as in sort_by.
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// FormatSort renders an ordering in the syntax of the sort parameter, such as "-year,title".
+func FormatSort(sort []SortField) string {
+	keys := make([]string, len(sort))
+	for i, field := range sort {
+		keys[i] = field.Field
+		if field.Desc {
+			keys[i] = "-" + field.Field
+		}
+	}
+	return strings.Join(keys, ",")
+}
+
+// MovieCursor is a position in a sorted movie listing: the sort, and the sort keys and ID
+// of the movie a page starts after or, with Backward, ends before. Clients get it signed
+// as an opaque cursor.
+type MovieCursor struct {
+	Sort     string   `json:"s,omitempty"` // As formatted by FormatSort
+	Keys     []string `json:"k,omitempty"` // Values of the sort columns, as formatted by the repository
+	ID       uint     `json:"i"`
+	Backward bool     `json:"b,omitempty"`
 }
 
 type MovieResponse struct {
-	ID        uint      `json:"id" example:"1"`
-	Title     string    `json:"title" example:"Inception"`
-	Director  string    `json:"director" example:"Christopher Nolan"`
-	Year      int       `json:"year" example:"2010"`
-	Plot      string    `json:"plot" example:"A skilled thief is given a chance to erase his criminal past by performing an impossible task."`
-	CreatedAt time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
-	UpdatedAt time.Time `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+	ID            uint       `json:"id" example:"1"`
+	Title         string     `json:"title" example:"Inception"`
+	Director      string     `json:"director" example:"Christopher Nolan"`
+	Year          int        `json:"year" example:"2010"`
+	Plot          string     `json:"plot" example:"A skilled thief is given a chance to erase his criminal past by performing an impossible task."`
+	Version       uint       `json:"version" example:"1"`
+	RatingAverage float64    `json:"rating_average" example:"8.5"` // Mean review rating; 0 without reviews
+	RatingCount   int        `json:"rating_count" example:"12"`
+	CreatedAt     time.Time  `json:"created_at" example:"2025-03-22T15:04:05Z"`
+	UpdatedAt     time.Time  `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+	DeletedAt     *time.Time `json:"deleted_at,omitempty" example:"2025-03-22T15:04:05Z"` // Set for movies in the trash
+	Genres        []string   `json:"genres" gorm:"-" example:"Science Fiction,Thriller"`
 }
 
 type MovieListResponse struct {
-	Movies []MovieResponse `json:"movies"`
-	Count  int             `json:"count" example:"100"`
+	Movies     []MovieResponse `json:"movies"`
+	Count      *int            `json:"count,omitempty" example:"100"` // Left out with include_total=false
+	NextCursor string          `json:"next_cursor,omitempty" example:"eyJpIjoxMH0.c2lnbmF0dXJl"`
+	PrevCursor string          `json:"prev_cursor,omitempty" example:"eyJpIjoxLCJiIjp0cnVlfQ.c2lnbmF0dXJl"`
+	Next       *MovieCursor    `json:"-"` // Set by the repository, signed into NextCursor
+	Prev       *MovieCursor    `json:"-"`
+}
+
+// MovieSearchResult is a movie matching a full-text search, with its relevance and a
+// snippet of its plot in which the matching words are wrapped in <mark> tags.
+type MovieSearchResult struct {
+	MovieResponse
+	Rank     float64 `json:"rank" example:"0.6079271"`
+	Headline string  `json:"headline" example:"A thief who steals corporate secrets through the use of <mark>dream</mark>-sharing technology"`
+}
+
+type MovieSearchResponse struct {
+	Results []MovieSearchResult `json:"results"`
+	Count   int                 `json:"count" example:"3"`
 }
@@ -0,0 +1,57 @@
+package models
+
+// Modes of a movie import.
+const (
+	ImportAllOrNothing = "all_or_nothing" // Write nothing if any row is rejected
+	ImportSkipInvalid  = "skip_invalid"   // Write the valid rows and report the others
+	ImportUpsert       = "upsert"         // Like skip_invalid, but update movies whose title exists
+)
+
+// Formats of import files.
+const (
+	ImportCSV    = "csv"
+	ImportTSV    = "tsv"
+	ImportNDJSON = "ndjson"
+)
+
+// Statuses of the rows of an import report.
+const (
+	ImportRowCreated  = "created"
+	ImportRowUpdated  = "updated"
+	ImportRowRejected = "rejected"
+)
+
+// MovieImportOptions configures an import. Mapping maps movie fields (title, director,
+// year, plot, genres) to the columns, or NDJSON keys, holding them; unmapped fields are
+// read from the column of the same name.
+type MovieImportOptions struct {
+	Format  string
+	Mapping map[string]string
+	Mode    string
+	DryRun  bool
+}
+
+// MovieImportRowResult is the outcome of one row of an import. Rows are numbered by the
+// line of the file they start on, so the header of a CSV file is row 1.
+type MovieImportRowResult struct {
+	Row     int      `json:"row" example:"2"`
+	Title   string   `json:"title,omitempty" example:"Inception"`
+	Status  string   `json:"status" example:"created"` // created, updated or rejected; in dry runs what would happen
+	MovieID uint     `json:"movie_id,omitempty" example:"42"`
+	Reasons []string `json:"reasons,omitempty" example:"year must be between 1888 and 2025"`
+}
+
+// MovieImportReport summarizes an import. Created and Updated count the rows written or,
+// in a dry run, that would be. Errors lists every rejected row; Rows lists every row, in
+// dry runs only.
+type MovieImportReport struct {
+	Mode      string                 `json:"mode" example:"skip_invalid"`
+	DryRun    bool                   `json:"dry_run" example:"false"`
+	Committed bool                   `json:"committed" example:"true"` // Whether anything was written
+	Total     int                    `json:"total" example:"120"`
+	Created   int                    `json:"created" example:"110"`
+	Updated   int                    `json:"updated" example:"0"`
+	Rejected  int                    `json:"rejected" example:"10"`
+	Errors    []MovieImportRowResult `json:"errors"`
+	Rows      []MovieImportRowResult `json:"rows,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in MovieRevision.Action.
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionDelete   = "delete"
+	RevisionRestore  = "restore"
+	RevisionRollback = "rollback"
+	RevisionPurge    = "purge" // Permanently removed; the history is kept
+)
+
+// Actor identifies who made a change: a user from the access token, or an API key.
+type Actor struct {
+	UserID    *uint
+	Username  string
+	APIKeyID  *uint
+	RequestID string
+}
+
+// MovieRevision is one entry of a movie's change history. Revision numbers follow the
+// movie's version, so revision N holds the movie as it was at version N.
+type MovieRevision struct {
+	ID             uint            `gorm:"primaryKey;autoIncrement"`
+	MovieID        uint            `gorm:"not null;uniqueIndex:idx_movie_revisions_movie_revision,priority:1"`
+	Revision       uint            `gorm:"not null;uniqueIndex:idx_movie_revisions_movie_revision,priority:2"`
+	Action         string          `gorm:"type:varchar(16);not null"`
+	ActorUserID    *uint           `gorm:"index:idx_movie_revisions_actor_user_id"`
+	ActorName      string          `gorm:"type:varchar(255);not null;default:''"`
+	APIKeyID       *uint           `gorm:"column:api_key_id"`
+	RequestID      string          `gorm:"type:varchar(64);not null;default:''"`
+	SourceRevision *uint           // Revision a rollback restored
+	Before         json.RawMessage `gorm:"type:jsonb"` // Snapshot before the change; null on create
+	After          json.RawMessage `gorm:"type:jsonb"` // Snapshot after the change; null on delete
+	CreatedAt      time.Time       `gorm:"autoCreateTime"`
+}
+
+type MovieRevisionResponse struct {
+	Revision       uint            `json:"revision" example:"3"`
+	Action         string          `json:"action" example:"update"`
+	ActorUserID    *uint           `json:"actor_user_id,omitempty" example:"1"`
+	ActorName      string          `json:"actor_name" example:"admin"`
+	APIKeyID       *uint           `json:"api_key_id,omitempty"`
+	RequestID      string          `json:"request_id" example:"6f1c2a9b0e4d4e1f8a7b3c2d1e0f9a8b"`
+	SourceRevision *uint           `json:"source_revision,omitempty" example:"1"`
+	Before         json.RawMessage `json:"before" swaggertype:"object"`
+	After          json.RawMessage `json:"after" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type MovieRevisionListResponse struct {
+	Revisions []MovieRevisionResponse `json:"revisions"`
+	Count     int                     `json:"count" example:"3"`
+}
+
+type RollbackMovieRequest struct {
+	ID       uint `json:"-"`
+	Version  uint `json:"-"` // Expected current version from If-Match; 0 skips the check
+	Revision uint `json:"revision" binding:"required,min=1" example:"2"`
+}
@@ -0,0 +1,82 @@
+package models
+
+// Relations movie responses can embed with ?expand=, as dotted paths.
+const (
+	ExpandGenres        = "genres"         // Genres as objects instead of names
+	ExpandCredits       = "credits"        // Cast and crew in billing order
+	ExpandCreditsPerson = "credits.person" // Each credit's person in full
+	ExpandRatings       = "ratings"        // Review count, average and distribution
+)
+
+// MovieExpansion selects the relations to embed in movie responses.
+type MovieExpansion struct {
+	Genres        bool
+	Credits       bool
+	CreditsPerson bool // Implies Credits
+	Ratings       bool
+}
+
+// MovieView is how a client asked for movies to be rendered: the fields to include and
+// the relations to embed.
+type MovieView struct {
+	Fields []string // JSON names of the fields, always including id; empty includes every field
+	Expand MovieExpansion
+}
+
+// Full reports whether the view renders movies as plain MovieResponses.
+func (v MovieView) Full() bool {
+	return len(v.Fields) == 0 && v.Expand == MovieExpansion{}
+}
+
+// Includes reports whether the view renders the field.
+func (v MovieView) Includes(field string) bool {
+	if len(v.Fields) == 0 {
+		return true
+	}
+	for _, f := range v.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// GenreRef is a genre embedded in a movie.
+type GenreRef struct {
+	ID   uint   `json:"id" example:"1"`
+	Name string `json:"name" example:"Science Fiction"`
+	Slug string `json:"slug" example:"science-fiction"`
+}
+
+// ExpandedCredit is a credit embedded in a movie, with its person when expanded.
+type ExpandedCredit struct {
+	CreditResponse
+	Person *PersonResponse `json:"person,omitempty"`
+}
+
+// RatingSummary aggregates a movie's reviews. Distribution counts the reviews per rating
+// from 1 to 10.
+type RatingSummary struct {
+	Average      float64     `json:"average" example:"8.5"`
+	Count        int         `json:"count" example:"12"`
+	Distribution map[int]int `json:"distribution"`
+}
+
+// MovieRelations holds the related data of a movie that a MovieExpansion asks for.
+type MovieRelations struct {
+	Genres  []GenreRef
+	Credits []ExpandedCredit
+	Ratings *RatingSummary
+}
+
+// MovieDocument is a movie rendered in a MovieView: the requested fields of its
+// MovieResponse, keyed by JSON name, and the requested relations.
+type MovieDocument map[string]interface{}
+
+// MovieDocumentListResponse is a page of movies rendered in a MovieView.
+type MovieDocumentListResponse struct {
+	Movies     []MovieDocument `json:"movies"`
+	Count      *int            `json:"count,omitempty" example:"100"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+}
@@ -0,0 +1,101 @@
+package models
+
+import "time"
+
+// Roles a person can be credited with on a movie.
+const (
+	CreditDirector        = "director"
+	CreditWriter          = "writer"
+	CreditActor           = "actor"
+	CreditProducer        = "producer"
+	CreditComposer        = "composer"
+	CreditCinematographer = "cinematographer"
+	CreditEditor          = "editor"
+)
+
+// Person is someone in a movie's cast or crew. Names are not unique: two people can
+// share one, which is why credits refer to people by ID.
+type Person struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(255);not null;index:idx_people_name"`
+	Bio       string `gorm:"type:text"`
+	BirthYear *int
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+// MovieCredit credits a person with a role on a movie. Credits go away with the movie,
+// while a person cannot be deleted as long as they have credits.
+type MovieCredit struct {
+	ID        uint    `gorm:"primaryKey;autoIncrement"`
+	MovieID   uint    `gorm:"not null;uniqueIndex:idx_movie_credits_movie_person_role,priority:1"`
+	PersonID  uint    `gorm:"not null;uniqueIndex:idx_movie_credits_movie_person_role,priority:2;index:idx_movie_credits_person_id"`
+	Role      string  `gorm:"type:varchar(32);not null;uniqueIndex:idx_movie_credits_movie_person_role,priority:3"`
+	Character string  `gorm:"type:varchar(255);not null;default:''"` // Actors only
+	Billing   int     `gorm:"not null;default:0"`                    // Order within the credits, lowest first
+	Movie     *Movie  `gorm:"constraint:OnDelete:CASCADE"`
+	Person    *Person `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+type PersonRequest struct {
+	Name      string `json:"name" binding:"required,max=255" example:"Christopher Nolan"`
+	Bio       string `json:"bio" example:"British-American filmmaker."`
+	BirthYear *int   `json:"birth_year" binding:"omitempty,gte=1800,lte=2100" example:"1970"`
+}
+
+type PersonResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	Name      string    `json:"name" example:"Christopher Nolan"`
+	Bio       string    `json:"bio" example:"British-American filmmaker."`
+	BirthYear *int      `json:"birth_year" example:"1970"`
+	CreatedAt time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type PersonListResponse struct {
+	People []PersonResponse `json:"people"`
+	Count  int              `json:"count" example:"100"`
+}
+
+type CreditRequest struct {
+	PersonID  uint   `json:"person_id" binding:"required" example:"1"`
+	Role      string `json:"role" binding:"required,oneof=director writer actor producer composer cinematographer editor" example:"actor"`
+	Character string `json:"character" binding:"max=255" example:"Cobb"`
+	Billing   int    `json:"billing" binding:"gte=0" example:"1"`
+}
+
+// ReplaceCreditsRequest replaces every credit of a movie.
+type ReplaceCreditsRequest struct {
+	ID      uint            `json:"-"`
+	Version uint            `json:"-"` // Expected current version from If-Match; 0 skips the check
+	Credits []CreditRequest `json:"credits" binding:"required,max=500,dive"`
+}
+
+type CreditResponse struct {
+	PersonID  uint   `json:"person_id" example:"1"`
+	Name      string `json:"name" example:"Leonardo DiCaprio"`
+	Role      string `json:"role" example:"actor"`
+	Character string `json:"character,omitempty" example:"Cobb"`
+	Billing   int    `json:"billing" example:"1"`
+}
+
+type MovieCreditsResponse struct {
+	MovieID uint             `json:"movie_id" example:"1"`
+	Version uint             `json:"-"` // Movie version after a replace, sent as the ETag
+	Credits []CreditResponse `json:"credits"`
+}
+
+// FilmographyEntry is one credit of a person, with the movie it is on.
+type FilmographyEntry struct {
+	MovieID   uint   `json:"movie_id" example:"1"`
+	Title     string `json:"title" example:"Inception"`
+	Year      int    `json:"year" example:"2010"`
+	Role      string `json:"role" example:"director"`
+	Character string `json:"character,omitempty"`
+	Billing   int    `json:"billing" example:"0"`
+}
+
+type FilmographyResponse struct {
+	Person  PersonResponse     `json:"person"`
+	Credits []FilmographyEntry `json:"credits"`
+}
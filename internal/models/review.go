@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// Review is a user's rating of a movie, with an optional text. A user reviews a movie at
+// most once. Reviews keep the author's username as it was when written, and outlive the
+// author's account like the movie history does.
+type Review struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	MovieID   uint      `gorm:"not null;uniqueIndex:idx_reviews_movie_user,priority:1"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_reviews_movie_user,priority:2;index:idx_reviews_user_id"`
+	Username  string    `gorm:"type:varchar(255);not null;default:''"`
+	Rating    int       `gorm:"not null;check:chk_reviews_rating,rating BETWEEN 1 AND 10"`
+	Body      string    `gorm:"type:text"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+	Movie     *Movie    `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,gte=1,lte=10" example:"9"`
+	Body   string `json:"body" binding:"max=10000" example:"Layered, loud and worth a second watch."`
+}
+
+type ReviewResponse struct {
+	ID        uint      `json:"id" example:"1"`
+	MovieID   uint      `json:"movie_id" example:"1"`
+	UserID    uint      `json:"user_id" example:"7"`
+	Username  string    `json:"username" example:"alice"`
+	Rating    int       `json:"rating" example:"9"`
+	Body      string    `json:"body" example:"Layered, loud and worth a second watch."`
+	CreatedAt time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type ReviewListResponse struct {
+	Reviews []ReviewResponse `json:"reviews"`
+	Count   int              `json:"count" example:"12"`
+}
@@ -0,0 +1,44 @@
+package models
+
+// Roles are ordered: every role includes the permissions of the roles below it.
+const (
+	RoleViewer = "viewer"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// Permissions gate individual actions. They are checked against the caller's role.
+const (
+	PermMoviesRead   = "movies:read"
+	PermMoviesWrite  = "movies:write"
+	PermMoviesDelete = "movies:delete"
+	PermMoviesBulk   = "movies:bulk"
+	PermUsersManage  = "users:manage"
+)
+
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+}
+
+// permissionRole is the minimum role required for each permission.
+var permissionRole = map[string]string{
+	PermMoviesRead:   RoleViewer,
+	PermMoviesWrite:  RoleEditor,
+	PermMoviesDelete: RoleAdmin,
+	PermMoviesBulk:   RoleAdmin,
+	PermUsersManage:  RoleAdmin,
+}
+
+// RoleAtLeast reports whether role is equal to or above the required role.
+func RoleAtLeast(role, required string) bool {
+	rank, ok := roleRank[role]
+	return ok && rank >= roleRank[required]
+}
+
+// RoleHasPermission reports whether role grants the given permission.
+func RoleHasPermission(role, permission string) bool {
+	required, ok := permissionRole[permission]
+	return ok && RoleAtLeast(role, required)
+}
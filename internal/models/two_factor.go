@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that replaces a TOTP code when the user has lost
+// their authenticator. Only the hash is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement"`
+	UserID    uint       `gorm:"not null;index:idx_recovery_codes_user_id"`
+	CodeHash  string     `gorm:"type:char(64);not null;index:idx_recovery_codes_hash"`
+	UsedAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time  `gorm:"autoCreateTime"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+	// OTPAuthURI is the payload to render as a QR code for authenticator apps.
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/movies_service:admin?algorithm=SHA1&digits=6&issuer=movies_service&period=30&secret=JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"`
+}
+
+type TwoFactorConfirmRequest struct {
+	UserID uint   `json:"-"`
+	Code   string `json:"code" binding:"required" example:"123456"`
+}
+
+type TwoFactorConfirmResponse struct {
+	// RecoveryCodes are shown once; each can be used a single time instead of a TOTP code.
+	RecoveryCodes []string `json:"recovery_codes" example:"3f9a-c2e1-77b0"`
+}
+
+type TwoFactorDisableRequest struct {
+	UserID   uint   `json:"-"`
+	Password string `json:"password" binding:"required" example:"s3cretpass"`
+	Code     string `json:"code" binding:"required" example:"123456"` // TOTP or recovery code
+	IP       string `json:"-"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required" example:"123456"` // TOTP or recovery code
+
+	// Filled from the HTTP request for throttling and the audit log.
+	IP        string `json:"-"`
+	UserAgent string `json:"-"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID           uint           `gorm:"primaryKey;autoIncrement"`
+	Username     string         `gorm:"type:varchar(255);not null;uniqueIndex:idx_users_username,where:deleted_at IS NULL"`
+	PasswordHash string         `gorm:"type:varchar(255);not null"`
+	Role         string         `gorm:"type:varchar(32);not null;default:viewer"`
+	ExternalID   *string        `gorm:"type:varchar(512);uniqueIndex:idx_users_external_id,where:deleted_at IS NULL"` // "<issuer>|<sub>" for OIDC users
+	CreatedAt    time.Time      `gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime"`
+	DeletedAt    gorm.DeletedAt `gorm:"index:idx_users_deleted_at"`
+
+	// Two-factor authentication. The secret is set on enrollment and only takes effect
+	// once the user confirms it with a valid code.
+	TOTPSecret      string `gorm:"column:totp_secret;type:varchar(64);not null;default:''"`
+	TOTPEnabled     bool   `gorm:"column:totp_enabled;not null;default:false"`
+	TOTPLastCounter int64  `gorm:"column:totp_last_counter;not null;default:0"` // Time step of the last accepted code, against replays
+}
+
+type RegisterRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=255" example:"john"`
+	Password string `json:"password" binding:"required,min=8,max=72" example:"s3cretpass"`
+}
+
+type ChangePasswordRequest struct {
+	UserID          uint   `json:"-"`
+	CurrentPassword string `json:"current_password" binding:"required" example:"s3cretpass"`
+	NewPassword     string `json:"new_password" binding:"required,min=8,max=72" example:"n3wpassword"`
+	IP              string `json:"-"`
+	UserAgent       string `json:"-"`
+}
+
+type CreateUserRequest struct {
+	Username string `json:"username" binding:"required,min=3,max=255" example:"john"`
+	Password string `json:"password" binding:"required,min=8,max=72" example:"s3cretpass"`
+	Role     string `json:"role" binding:"omitempty,oneof=viewer editor admin" example:"editor"`
+}
+
+type UpdateUserRequest struct {
+	ID       uint    `json:"-"`
+	Password *string `json:"password,omitempty" binding:"omitempty,min=8,max=72" example:"n3wpassword"`
+}
+
+type UpdateUserRoleRequest struct {
+	ID   uint   `json:"-"`
+	Role string `json:"role" binding:"required,oneof=viewer editor admin" example:"editor"`
+}
+
+type UserResponse struct {
+	ID       uint   `json:"id" example:"1"`
+	Username string `json:"username" example:"john"`
+	Role     string `json:"role" example:"editor"`
+	// TwoFactorEnabled reports whether logins require a TOTP code.
+	TwoFactorEnabled bool      `json:"two_factor_enabled" example:"false"`
+	CreatedAt        time.Time `json:"created_at" example:"2025-03-22T15:04:05Z"`
+	UpdatedAt        time.Time `json:"updated_at" example:"2025-03-22T15:04:05Z"`
+}
+
+type UserListResponse struct {
+	Users []UserResponse `json:"users"`
+	Count int            `json:"count" example:"10"`
+}
@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository stores API keys by hash. Revoked keys are kept, marked as revoked.
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	GetByHash(hash string) (*models.APIKey, error)
+	// GetAll lists every key by ID.
+	GetAll() ([]models.APIKey, error)
+	// Revoke marks an active key as revoked; it returns gorm.ErrRecordNotFound when there
+	// is no such key or it is already revoked.
+	Revoke(id uint) error
+	TouchLastUsed(id uint, at time.Time) error
+}
+
+// NewAPIKeyRepository keeps API keys next to the accounts, as configured by MOVIE_STORE.
+func NewAPIKeyRepository(cfg *config.Config, db *gorm.DB) APIKeyRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryAPIKeyRepository()
+	}
+	return NewPostgresAPIKeyRepository(db)
+}
+
+type PostgresAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresAPIKeyRepository(db *gorm.DB) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db}
+}
+
+func (r *PostgresAPIKeyRepository) Create(key *models.APIKey) error {
+	if err := r.db.Create(key).Error; err != nil {
+		log.Println("❌ Failed to create API key:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, "key_hash = ?", hash).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *PostgresAPIKeyRepository) GetAll() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("id ASC").Find(&keys).Error; err != nil {
+		log.Println("❌ Failed to retrieve API keys:", err)
+		return nil, err
+	}
+	return keys, nil
+}
+
+func (r *PostgresAPIKeyRepository) Revoke(id uint) error {
+	result := r.db.Model(&models.APIKey{}).Where("id = ? AND revoked_at IS NULL", id).Update("revoked_at", time.Now())
+	if result.Error != nil {
+		log.Println("❌ Failed to revoke API key:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PostgresAPIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	// UpdateColumn leaves updated_at alone; last use is not a modification of the key.
+	if err := r.db.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error; err != nil {
+		log.Println("❌ Failed to update API key last use:", err)
+		return err
+	}
+	return nil
+}
+
+type MemoryAPIKeyRepository struct {
+	mu     sync.RWMutex
+	keys   map[uint]*models.APIKey
+	nextID uint
+}
+
+func NewMemoryAPIKeyRepository() *MemoryAPIKeyRepository {
+	return &MemoryAPIKeyRepository{keys: make(map[uint]*models.APIKey), nextID: 1}
+}
+
+func (r *MemoryAPIKeyRepository) Create(key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.keys {
+		if existing.KeyHash == key.KeyHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now()
+	key.ID = r.nextID
+	key.CreatedAt = now
+	key.UpdatedAt = now
+	r.nextID++
+
+	stored := *key
+	r.keys[key.ID] = &stored
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) GetByHash(hash string) (*models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.KeyHash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAPIKeyRepository) GetAll() ([]models.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]models.APIKey, 0, len(r.keys))
+	for id := uint(1); id < r.nextID; id++ {
+		if key, ok := r.keys[id]; ok {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *MemoryAPIKeyRepository) Revoke(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.RevokedAt != nil {
+		return gorm.ErrRecordNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	key.UpdatedAt = now
+	return nil
+}
+
+func (r *MemoryAPIKeyRepository) TouchLastUsed(id uint, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if key, ok := r.keys[id]; ok {
+		key.LastUsedAt = &at
+	}
+	return nil
+}
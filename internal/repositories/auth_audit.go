@@ -0,0 +1,112 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuthAuditRepository stores the auth audit log.
+type AuthAuditRepository interface {
+	Create(entry *models.AuthAuditLog) error
+	// GetAll returns one page of the entries, newest first, optionally only those of a
+	// username or event, along with their total number.
+	GetAll(username, event string, limit, offset int) ([]models.AuthAuditLog, int, error)
+}
+
+// NewAuthAuditRepository keeps the audit log next to the accounts, as configured by MOVIE_STORE.
+func NewAuthAuditRepository(cfg *config.Config, db *gorm.DB) AuthAuditRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryAuthAuditRepository()
+	}
+	return NewPostgresAuthAuditRepository(db)
+}
+
+type PostgresAuthAuditRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresAuthAuditRepository(db *gorm.DB) *PostgresAuthAuditRepository {
+	return &PostgresAuthAuditRepository{db: db}
+}
+
+func (r *PostgresAuthAuditRepository) Create(entry *models.AuthAuditLog) error {
+	if err := r.db.Create(entry).Error; err != nil {
+		log.Println("❌ Failed to write auth audit log:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAuthAuditRepository) GetAll(username, event string, limit, offset int) ([]models.AuthAuditLog, int, error) {
+	var entries []models.AuthAuditLog
+	var totalCount int64
+
+	query := r.db.Model(&models.AuthAuditLog{})
+	if username != "" {
+		query = query.Where("username = ?", username)
+	}
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count auth audit log:", err)
+		return nil, 0, err
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("id DESC").Offset(offset).Find(&entries).Error; err != nil {
+		log.Println("❌ Failed to retrieve auth audit log:", err)
+		return nil, 0, err
+	}
+
+	return entries, int(totalCount), nil
+}
+
+type MemoryAuthAuditRepository struct {
+	mu      sync.RWMutex
+	entries []models.AuthAuditLog
+}
+
+func NewMemoryAuthAuditRepository() *MemoryAuthAuditRepository {
+	return &MemoryAuthAuditRepository{}
+}
+
+func (r *MemoryAuthAuditRepository) Create(entry *models.AuthAuditLog) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.CreatedAt = time.Now()
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *MemoryAuthAuditRepository) GetAll(username, event string, limit, offset int) ([]models.AuthAuditLog, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.AuthAuditLog{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if (username == "" || entry.Username == username) && (event == "" || entry.Event == event) {
+			entries = append(entries, entry)
+		}
+	}
+
+	total := len(entries)
+	if offset >= total {
+		return []models.AuthAuditLog{}, total, nil
+	}
+	entries = entries[offset:]
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"errors"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTokenAlreadyRevoked is returned by Rotate when another request revoked the token first.
+var ErrTokenAlreadyRevoked = errors.New("token already revoked")
+
+// AuthTokenRepository stores issued refresh tokens by hash.
+type AuthTokenRepository interface {
+	Create(token *models.AuthToken) error
+	GetByHash(hash string) (*models.AuthToken, error)
+	// Rotate revokes the old token and stores its replacement atomically, failing with
+	// ErrTokenAlreadyRevoked when the old token was revoked in the meantime.
+	Rotate(oldID uint, replacement *models.AuthToken) error
+	// RevokeFamily revokes every still-active token descending from the same login.
+	RevokeFamily(familyID, reason string) error
+	// RevokeForUserBefore revokes the user's active refresh tokens created before the given time.
+	RevokeForUserBefore(userID uint, before time.Time, reason string) error
+	// RevokeAllForUser revokes every active refresh token the user holds.
+	RevokeAllForUser(userID uint, reason string) error
+}
+
+// NewAuthTokenRepository keeps refresh tokens next to the accounts, as configured by MOVIE_STORE.
+func NewAuthTokenRepository(cfg *config.Config, db *gorm.DB) AuthTokenRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryAuthTokenRepository()
+	}
+	return NewPostgresAuthTokenRepository(db)
+}
+
+type PostgresAuthTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresAuthTokenRepository(db *gorm.DB) *PostgresAuthTokenRepository {
+	return &PostgresAuthTokenRepository{db: db}
+}
+
+func (r *PostgresAuthTokenRepository) Create(token *models.AuthToken) error {
+	if err := r.db.Create(token).Error; err != nil {
+		log.Println("❌ Failed to store refresh token:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAuthTokenRepository) GetByHash(hash string) (*models.AuthToken, error) {
+	var token models.AuthToken
+	if err := r.db.First(&token, "token_hash = ?", hash).Error; err != nil {
+		log.Println("❌ Refresh token not found:", err)
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Rotate uses a single transaction, whose conditional update makes concurrent refreshes
+// with the same token fail.
+func (r *PostgresAuthTokenRepository) Rotate(oldID uint, replacement *models.AuthToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AuthToken{}).
+			Where("id = ? AND revoked_at IS NULL", oldID).
+			Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": models.TokenRevokedRotated})
+		if result.Error != nil {
+			log.Println("❌ Failed to revoke rotated refresh token:", result.Error)
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTokenAlreadyRevoked
+		}
+
+		if err := tx.Create(replacement).Error; err != nil {
+			log.Println("❌ Failed to store rotated refresh token:", err)
+			return err
+		}
+		return nil
+	})
+}
+
+func (r *PostgresAuthTokenRepository) RevokeFamily(familyID, reason string) error {
+	err := r.db.Model(&models.AuthToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+	if err != nil {
+		log.Println("❌ Failed to revoke token family:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAuthTokenRepository) RevokeForUserBefore(userID uint, before time.Time, reason string) error {
+	err := r.db.Model(&models.AuthToken{}).
+		Where("user_id = ? AND created_at < ? AND revoked_at IS NULL", userID, before).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+	if err != nil {
+		log.Println("❌ Failed to revoke user tokens:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresAuthTokenRepository) RevokeAllForUser(userID uint, reason string) error {
+	err := r.db.Model(&models.AuthToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+	if err != nil {
+		log.Println("❌ Failed to revoke user tokens:", err)
+		return err
+	}
+	return nil
+}
+
+type MemoryAuthTokenRepository struct {
+	mu     sync.Mutex
+	tokens map[uint]*models.AuthToken
+	nextID uint
+}
+
+func NewMemoryAuthTokenRepository() *MemoryAuthTokenRepository {
+	return &MemoryAuthTokenRepository{tokens: make(map[uint]*models.AuthToken), nextID: 1}
+}
+
+func (r *MemoryAuthTokenRepository) Create(token *models.AuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.insert(token)
+}
+
+func (r *MemoryAuthTokenRepository) GetByHash(hash string) (*models.AuthToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == hash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryAuthTokenRepository) Rotate(oldID uint, replacement *models.AuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.tokens[oldID]
+	if !ok || old.RevokedAt != nil {
+		return ErrTokenAlreadyRevoked
+	}
+	if err := r.insert(replacement); err != nil {
+		return err
+	}
+	r.revoke(old, models.TokenRevokedRotated, time.Now())
+	return nil
+}
+
+func (r *MemoryAuthTokenRepository) RevokeFamily(familyID, reason string) error {
+	return r.revokeWhere(reason, func(token *models.AuthToken) bool { return token.FamilyID == familyID })
+}
+
+func (r *MemoryAuthTokenRepository) RevokeForUserBefore(userID uint, before time.Time, reason string) error {
+	return r.revokeWhere(reason, func(token *models.AuthToken) bool {
+		return token.UserID == userID && token.CreatedAt.Before(before)
+	})
+}
+
+func (r *MemoryAuthTokenRepository) RevokeAllForUser(userID uint, reason string) error {
+	return r.revokeWhere(reason, func(token *models.AuthToken) bool { return token.UserID == userID })
+}
+
+func (r *MemoryAuthTokenRepository) insert(token *models.AuthToken) error {
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	r.nextID++
+
+	stored := *token
+	r.tokens[token.ID] = &stored
+	return nil
+}
+
+func (r *MemoryAuthTokenRepository) revokeWhere(reason string, match func(token *models.AuthToken) bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.RevokedAt == nil && match(token) {
+			r.revoke(token, reason, now)
+		}
+	}
+	return nil
+}
+
+func (r *MemoryAuthTokenRepository) revoke(token *models.AuthToken, reason string, at time.Time) {
+	token.RevokedAt = &at
+	token.RevokedReason = reason
+}
@@ -0,0 +1,277 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUnknownGenre is returned when a movie names a genre that does not exist.
+var ErrUnknownGenre = errors.New("unknown genre")
+
+// GenreRepository stores genres. A name whose slug is already taken is reported as
+// gorm.ErrDuplicatedKey and a missing genre as gorm.ErrRecordNotFound. Deleting a genre
+// also removes it from every movie.
+type GenreRepository interface {
+	Create(genre *models.GenreRequest) (*models.Genre, error)
+	// GetAll lists every genre by name.
+	GetAll() ([]models.Genre, error)
+	GetByID(id uint) (*models.Genre, error)
+	Update(id uint, genre *models.GenreRequest) (*models.Genre, error)
+	Delete(id uint) error
+}
+
+// NewGenreRepository keeps genres next to the movies, as configured by MOVIE_STORE.
+func NewGenreRepository(cfg *config.Config, db *gorm.DB) GenreRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryGenreRepository()
+	}
+	return NewPostgresGenreRepository(db)
+}
+
+// unknownGenres wraps ErrUnknownGenre with the names that matched no genre.
+func unknownGenres(names []string) error {
+	return fmt.Errorf("%w: %s", ErrUnknownGenre, strings.Join(names, ", "))
+}
+
+type PostgresGenreRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresGenreRepository(db *gorm.DB) *PostgresGenreRepository {
+	return &PostgresGenreRepository{db: db}
+}
+
+func (r *PostgresGenreRepository) Create(request *models.GenreRequest) (*models.Genre, error) {
+	genre := models.Genre{Name: strings.TrimSpace(request.Name), Slug: models.GenreSlug(request.Name)}
+	if err := r.db.Create(&genre).Error; err != nil {
+		log.Println("❌ Failed to create genre:", err)
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *PostgresGenreRepository) GetAll() ([]models.Genre, error) {
+	var genres []models.Genre
+	if err := r.db.Order("name ASC").Find(&genres).Error; err != nil {
+		log.Println("❌ Failed to retrieve genres:", err)
+		return nil, err
+	}
+	return genres, nil
+}
+
+func (r *PostgresGenreRepository) GetByID(id uint) (*models.Genre, error) {
+	var genre models.Genre
+	if err := r.db.First(&genre, id).Error; err != nil {
+		log.Println("❌ Genre not found:", err)
+		return nil, err
+	}
+	return &genre, nil
+}
+
+func (r *PostgresGenreRepository) Update(id uint, request *models.GenreRequest) (*models.Genre, error) {
+	genre := models.Genre{ID: id}
+	result := r.db.Model(&genre).Clauses(clause.Returning{}).Updates(map[string]interface{}{
+		"name": strings.TrimSpace(request.Name),
+		"slug": models.GenreSlug(request.Name),
+	})
+	if result.Error != nil {
+		log.Println("❌ Failed to update genre:", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &genre, nil
+}
+
+// Delete relies on the foreign keys of movie_genres to unlink the genre from its movies.
+func (r *PostgresGenreRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Genre{}, id)
+	if result.Error != nil {
+		log.Println("❌ Failed to delete genre:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MemoryGenreRepository keeps genres in process memory for MemoryMovieRepository, which
+// resolves movie genres through it.
+type MemoryGenreRepository struct {
+	mu     sync.RWMutex
+	genres map[uint]*models.Genre
+	nextID uint
+}
+
+func NewMemoryGenreRepository() *MemoryGenreRepository {
+	return &MemoryGenreRepository{genres: make(map[uint]*models.Genre), nextID: 1}
+}
+
+func (r *MemoryGenreRepository) Create(request *models.GenreRequest) (*models.Genre, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slug := models.GenreSlug(request.Name)
+	if r.slugTaken(slug, 0) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	now := time.Now()
+	genre := &models.Genre{ID: r.nextID, Name: strings.TrimSpace(request.Name), Slug: slug, CreatedAt: now, UpdatedAt: now}
+	r.genres[genre.ID] = genre
+	r.nextID++
+
+	created := *genre
+	return &created, nil
+}
+
+func (r *MemoryGenreRepository) GetAll() ([]models.Genre, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	genres := make([]models.Genre, 0, len(r.genres))
+	for _, genre := range r.genres {
+		genres = append(genres, *genre)
+	}
+	sort.Slice(genres, func(i, j int) bool { return genres[i].Name < genres[j].Name })
+	return genres, nil
+}
+
+func (r *MemoryGenreRepository) GetByID(id uint) (*models.Genre, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	genre, ok := r.genres[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *genre
+	return &found, nil
+}
+
+func (r *MemoryGenreRepository) Update(id uint, request *models.GenreRequest) (*models.Genre, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	genre, ok := r.genres[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	slug := models.GenreSlug(request.Name)
+	if r.slugTaken(slug, id) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	genre.Name = strings.TrimSpace(request.Name)
+	genre.Slug = slug
+	genre.UpdatedAt = time.Now()
+
+	updated := *genre
+	return &updated, nil
+}
+
+// Delete only removes the genre; MemoryMovieRepository skips links to genres that no
+// longer exist, which has the same effect as the cascading foreign key.
+func (r *MemoryGenreRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.genres[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.genres, id)
+	return nil
+}
+
+// resolve maps genre names to IDs by slug, ignoring duplicates.
+func (r *MemoryGenreRepository) resolve(names []string) ([]uint, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	bySlug := make(map[string]uint, len(r.genres))
+	for id, genre := range r.genres {
+		bySlug[genre.Slug] = id
+	}
+
+	ids := make([]uint, 0, len(names))
+	seen := make(map[uint]bool, len(names))
+	var unknown []string
+	for _, name := range names {
+		id, ok := bySlug[models.GenreSlug(name)]
+		if !ok {
+			unknown = append(unknown, name)
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, unknownGenres(unknown)
+	}
+	return ids, nil
+}
+
+// refs returns the genres that still exist, sorted by name.
+func (r *MemoryGenreRepository) refs(ids []uint) []models.GenreRef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	refs := make([]models.GenreRef, 0, len(ids))
+	for _, id := range ids {
+		if genre, ok := r.genres[id]; ok {
+			refs = append(refs, models.GenreRef{ID: genre.ID, Name: genre.Name, Slug: genre.Slug})
+		}
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs
+}
+
+// names returns the sorted names of the genres that still exist.
+func (r *MemoryGenreRepository) names(ids []uint) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if genre, ok := r.genres[id]; ok {
+			names = append(names, genre.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// slugs returns the slugs of the genres that still exist, keyed by ID.
+func (r *MemoryGenreRepository) slugs(ids []uint) map[uint]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	slugs := make(map[uint]string, len(ids))
+	for _, id := range ids {
+		if genre, ok := r.genres[id]; ok {
+			slugs[id] = genre.Slug
+		}
+	}
+	return slugs
+}
+
+func (r *MemoryGenreRepository) slugTaken(slug string, exceptID uint) bool {
+	for id, genre := range r.genres {
+		if id != exceptID && genre.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
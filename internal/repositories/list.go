@@ -0,0 +1,590 @@
+package repositories
+
+import (
+	"errors"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrListOrderMismatch is returned when a reorder does not name every visible entry of
+// the list exactly once.
+var ErrListOrderMismatch = errors.New("movie IDs do not match the list's entries")
+
+// MovieListRepository stores users' movie lists. Entries of movies in the trash are kept
+// in place but left out of entry listings and counts. A missing list is reported as
+// gorm.ErrRecordNotFound, as is a movie missing from a list, and a movie already on the
+// list or a list name the user already has as gorm.ErrDuplicatedKey.
+type MovieListRepository interface {
+	Create(list *models.MovieList) (*models.ListResponse, error)
+	// GetByUser returns the user's lists, the watchlist first and the rest by name.
+	GetByUser(userID uint, publicOnly bool) ([]models.ListResponse, error)
+	GetByID(id uint) (*models.ListResponse, error)
+	// GetDefault returns the user's watchlist, creating it on first use.
+	GetDefault(userID uint) (*models.ListResponse, error)
+	Update(id uint, request *models.ListRequest) (*models.ListResponse, error)
+	Delete(id uint) error
+
+	// GetEntries returns the visible entries of the list in order.
+	GetEntries(listID uint) ([]models.ListEntryResponse, error)
+	// AddEntry puts the movie on the list at the position, shifting later entries down.
+	// Position 0, or one past the end, appends.
+	AddEntry(listID, movieID uint, position int) error
+	// RemoveEntry takes the movie off the list, closing the gap it leaves.
+	RemoveEntry(listID, movieID uint) error
+	// Reorder puts the visible entries in the order of movieIDs, followed by the hidden
+	// ones in their current order.
+	Reorder(listID uint, movieIDs []uint) error
+}
+
+// NewMovieListRepository keeps lists next to the movies, as configured by MOVIE_STORE.
+// The in-memory store hides trashed movies by asking the in-memory movie repository.
+func NewMovieListRepository(cfg *config.Config, db *gorm.DB, movies MovieRepository) (MovieListRepository, error) {
+	if cfg.MovieStore == "memory" {
+		memory, err := memoryMovies(movies)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemoryMovieListRepository(memory), nil
+	}
+	return NewPostgresMovieListRepository(db), nil
+}
+
+type PostgresMovieListRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMovieListRepository(db *gorm.DB) *PostgresMovieListRepository {
+	return &PostgresMovieListRepository{db: db}
+}
+
+// listRow is a list with the number of its visible entries.
+type listRow struct {
+	models.MovieList
+	EntryCount int
+}
+
+func (r *PostgresMovieListRepository) Create(list *models.MovieList) (*models.ListResponse, error) {
+	created := *list
+	if err := r.db.Create(&created).Error; err != nil {
+		log.Println("❌ Failed to create list:", err)
+		return nil, err
+	}
+	response := toListResponse(&created, 0)
+	return &response, nil
+}
+
+func (r *PostgresMovieListRepository) GetByUser(userID uint, publicOnly bool) ([]models.ListResponse, error) {
+	var rows []listRow
+	query := r.withCounts().Where("movie_lists.user_id = ?", userID)
+	if publicOnly {
+		query = query.Where("movie_lists.public")
+	}
+	if err := query.Order("movie_lists.is_default DESC, movie_lists.name ASC").Scan(&rows).Error; err != nil {
+		log.Println("❌ Failed to retrieve lists:", err)
+		return nil, err
+	}
+
+	lists := make([]models.ListResponse, 0, len(rows))
+	for i := range rows {
+		lists = append(lists, toListResponse(&rows[i].MovieList, rows[i].EntryCount))
+	}
+	return lists, nil
+}
+
+func (r *PostgresMovieListRepository) GetByID(id uint) (*models.ListResponse, error) {
+	var rows []listRow
+	if err := r.withCounts().Where("movie_lists.id = ?", id).Scan(&rows).Error; err != nil {
+		log.Println("❌ Failed to retrieve list:", err)
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	list := toListResponse(&rows[0].MovieList, rows[0].EntryCount)
+	return &list, nil
+}
+
+func (r *PostgresMovieListRepository) GetDefault(userID uint) (*models.ListResponse, error) {
+	var lists []models.MovieList
+	if err := r.db.Where("user_id = ? AND is_default", userID).Limit(1).Find(&lists).Error; err != nil {
+		log.Println("❌ Failed to retrieve watchlist:", err)
+		return nil, err
+	}
+	if len(lists) > 0 {
+		return r.GetByID(lists[0].ID)
+	}
+
+	list := models.MovieList{UserID: userID, Name: models.DefaultListName, IsDefault: true}
+	err := r.db.Create(&list).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return r.GetDefault(userID) // Created by a concurrent request
+	}
+	if err != nil {
+		log.Println("❌ Failed to create watchlist:", err)
+		return nil, err
+	}
+	response := toListResponse(&list, 0)
+	return &response, nil
+}
+
+func (r *PostgresMovieListRepository) Update(id uint, request *models.ListRequest) (*models.ListResponse, error) {
+	result := r.db.Model(&models.MovieList{ID: id}).Updates(map[string]interface{}{
+		"name":   request.Name,
+		"public": request.Public,
+	})
+	if result.Error != nil {
+		log.Println("❌ Failed to update list:", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.GetByID(id)
+}
+
+func (r *PostgresMovieListRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.MovieList{}, id)
+	if result.Error != nil {
+		log.Println("❌ Failed to delete list:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PostgresMovieListRepository) GetEntries(listID uint) ([]models.ListEntryResponse, error) {
+	entries := []models.ListEntryResponse{}
+	err := r.db.Table("movie_list_entries").
+		Select("movie_list_entries.movie_id, movies.title, movies.year, movie_list_entries.position, movie_list_entries.added_at").
+		Joins("JOIN movies ON movies.id = movie_list_entries.movie_id AND movies.deleted_at IS NULL").
+		Where("movie_list_entries.list_id = ?", listID).
+		Order("movie_list_entries.position ASC").
+		Scan(&entries).Error
+	if err != nil {
+		log.Println("❌ Failed to retrieve list entries:", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresMovieListRepository) AddEntry(listID, movieID uint, position int) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockList(tx, listID); err != nil {
+			return err
+		}
+
+		var last int
+		if err := tx.Model(&models.MovieListEntry{}).Where("list_id = ?", listID).
+			Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		if position == 0 || position > last {
+			position = last + 1
+		} else {
+			err := tx.Model(&models.MovieListEntry{}).Where("list_id = ? AND position >= ?", listID, position).
+				Update("position", gorm.Expr("position + 1")).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		entry := models.MovieListEntry{ListID: listID, MovieID: movieID, Position: position}
+		if err := tx.Omit(clause.Associations).Create(&entry).Error; err != nil {
+			return err
+		}
+		return touchList(tx, listID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to add list entry:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresMovieListRepository) RemoveEntry(listID, movieID uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockList(tx, listID); err != nil {
+			return err
+		}
+
+		var entry models.MovieListEntry
+		if err := tx.Where("list_id = ? AND movie_id = ?", listID, movieID).First(&entry).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&entry).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.MovieListEntry{}).Where("list_id = ? AND position > ?", listID, entry.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+		if err != nil {
+			return err
+		}
+		return touchList(tx, listID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to remove list entry:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresMovieListRepository) Reorder(listID uint, movieIDs []uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockList(tx, listID); err != nil {
+			return err
+		}
+
+		var entries []struct {
+			ID       uint
+			MovieID  uint
+			Position int
+			Hidden   bool
+		}
+		err := tx.Table("movie_list_entries").
+			Select("movie_list_entries.id, movie_list_entries.movie_id, movie_list_entries.position, movies.deleted_at IS NOT NULL AS hidden").
+			Joins("JOIN movies ON movies.id = movie_list_entries.movie_id").
+			Where("movie_list_entries.list_id = ?", listID).
+			Order("movie_list_entries.position ASC").
+			Scan(&entries).Error
+		if err != nil {
+			return err
+		}
+
+		current := make([]listedMovie, 0, len(entries))
+		for _, entry := range entries {
+			current = append(current, listedMovie{movieID: entry.MovieID, hidden: entry.Hidden})
+		}
+		positions, err := reorderedPositions(current, movieIDs)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if position := positions[entry.MovieID]; position != entry.Position {
+				if err := tx.Model(&models.MovieListEntry{}).Where("id = ?", entry.ID).Update("position", position).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return touchList(tx, listID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to reorder list:", err)
+		return err
+	}
+	return nil
+}
+
+// withCounts selects lists along with the number of their entries outside the trash.
+func (r *PostgresMovieListRepository) withCounts() *gorm.DB {
+	return r.db.Table("movie_lists").Select(`movie_lists.*, (SELECT COUNT(*) FROM movie_list_entries
+		JOIN movies ON movies.id = movie_list_entries.movie_id AND movies.deleted_at IS NULL
+		WHERE movie_list_entries.list_id = movie_lists.id) AS entry_count`)
+}
+
+// lockList takes the list's row lock, so concurrent entry changes on the list queue up
+// and positions stay dense.
+func lockList(tx *gorm.DB, listID uint) error {
+	var list models.MovieList
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&list, listID).Error
+}
+
+func touchList(tx *gorm.DB, listID uint) error {
+	return tx.Model(&models.MovieList{ID: listID}).Update("updated_at", time.Now()).Error
+}
+
+// listedMovie is an entry as seen by reorderedPositions.
+type listedMovie struct {
+	movieID uint
+	hidden  bool
+}
+
+// reorderedPositions assigns positions to the entries, given in their current order:
+// the visible ones in the order of movieIDs, which must name each of them exactly once,
+// then the hidden ones.
+func reorderedPositions(entries []listedMovie, movieIDs []uint) (map[uint]int, error) {
+	visible := make(map[uint]bool, len(entries))
+	for _, entry := range entries {
+		if !entry.hidden {
+			visible[entry.movieID] = true
+		}
+	}
+	if len(movieIDs) != len(visible) {
+		return nil, ErrListOrderMismatch
+	}
+
+	positions := make(map[uint]int, len(entries))
+	for _, movieID := range movieIDs {
+		if !visible[movieID] || positions[movieID] != 0 {
+			return nil, ErrListOrderMismatch
+		}
+		positions[movieID] = len(positions) + 1
+	}
+	for _, entry := range entries {
+		if entry.hidden {
+			positions[entry.movieID] = len(positions) + 1
+		}
+	}
+	return positions, nil
+}
+
+func toListResponse(list *models.MovieList, entryCount int) models.ListResponse {
+	return models.ListResponse{
+		ID:         list.ID,
+		UserID:     list.UserID,
+		Name:       list.Name,
+		Default:    list.IsDefault,
+		Public:     list.Public,
+		EntryCount: entryCount,
+		CreatedAt:  list.CreatedAt,
+		UpdatedAt:  list.UpdatedAt,
+	}
+}
+
+// MemoryMovieListRepository keeps lists in process memory, each list's entries in order.
+type MemoryMovieListRepository struct {
+	mu      sync.RWMutex
+	lists   map[uint]*models.MovieList
+	entries map[uint][]models.MovieListEntry // By list ID, in position order
+	movies  *MemoryMovieRepository
+	nextID  uint
+}
+
+func NewMemoryMovieListRepository(movies *MemoryMovieRepository) *MemoryMovieListRepository {
+	return &MemoryMovieListRepository{
+		lists:   make(map[uint]*models.MovieList),
+		entries: make(map[uint][]models.MovieListEntry),
+		movies:  movies,
+		nextID:  1,
+	}
+}
+
+func (r *MemoryMovieListRepository) Create(list *models.MovieList) (*models.ListResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.nameTaken(list.UserID, list.Name, 0) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	return r.response(r.insert(list)), nil
+}
+
+func (r *MemoryMovieListRepository) GetByUser(userID uint, publicOnly bool) ([]models.ListResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := make([]*models.MovieList, 0)
+	for _, list := range r.lists {
+		if list.UserID == userID && (list.Public || !publicOnly) {
+			lists = append(lists, list)
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool {
+		if lists[i].IsDefault != lists[j].IsDefault {
+			return lists[i].IsDefault
+		}
+		return lists[i].Name < lists[j].Name
+	})
+
+	responses := make([]models.ListResponse, 0, len(lists))
+	for _, list := range lists {
+		responses = append(responses, *r.response(list))
+	}
+	return responses, nil
+}
+
+func (r *MemoryMovieListRepository) GetByID(id uint) (*models.ListResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, ok := r.lists[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.response(list), nil
+}
+
+func (r *MemoryMovieListRepository) GetDefault(userID uint) (*models.ListResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, list := range r.lists {
+		if list.UserID == userID && list.IsDefault {
+			return r.response(list), nil
+		}
+	}
+	if r.nameTaken(userID, models.DefaultListName, 0) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	return r.response(r.insert(&models.MovieList{UserID: userID, Name: models.DefaultListName, IsDefault: true})), nil
+}
+
+func (r *MemoryMovieListRepository) Update(id uint, request *models.ListRequest) (*models.ListResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if r.nameTaken(list.UserID, request.Name, id) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	list.Name = request.Name
+	list.Public = request.Public
+	list.UpdatedAt = time.Now()
+	return r.response(list), nil
+}
+
+func (r *MemoryMovieListRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.lists[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.lists, id)
+	delete(r.entries, id)
+	return nil
+}
+
+func (r *MemoryMovieListRepository) GetEntries(listID uint) ([]models.ListEntryResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	stored := r.entries[listID]
+	movies := r.movies.activeMovies(listedMovieIDs(stored))
+	entries := make([]models.ListEntryResponse, 0, len(stored))
+	for i, entry := range stored {
+		movie, ok := movies[entry.MovieID]
+		if !ok {
+			continue
+		}
+		entries = append(entries, models.ListEntryResponse{
+			MovieID:  entry.MovieID,
+			Title:    movie.Title,
+			Year:     movie.Year,
+			Position: i + 1,
+			AddedAt:  entry.AddedAt,
+		})
+	}
+	return entries, nil
+}
+
+func (r *MemoryMovieListRepository) AddEntry(listID, movieID uint, position int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[listID]
+	if !ok || !r.movies.exists(movieID) {
+		return gorm.ErrRecordNotFound
+	}
+	entries := r.entries[listID]
+	for _, entry := range entries {
+		if entry.MovieID == movieID {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	if position == 0 || position > len(entries) {
+		position = len(entries) + 1
+	}
+	entry := models.MovieListEntry{ListID: listID, MovieID: movieID, AddedAt: now}
+	entries = append(entries[:position-1], append([]models.MovieListEntry{entry}, entries[position-1:]...)...)
+	r.entries[listID] = entries
+	list.UpdatedAt = now
+	return nil
+}
+
+func (r *MemoryMovieListRepository) RemoveEntry(listID, movieID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[listID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	entries := r.entries[listID]
+	for i, entry := range entries {
+		if entry.MovieID == movieID {
+			r.entries[listID] = append(entries[:i:i], entries[i+1:]...)
+			list.UpdatedAt = time.Now()
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+func (r *MemoryMovieListRepository) Reorder(listID uint, movieIDs []uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.lists[listID]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	entries := r.entries[listID]
+	movies := r.movies.activeMovies(listedMovieIDs(entries))
+	current := make([]listedMovie, 0, len(entries))
+	for _, entry := range entries {
+		_, visible := movies[entry.MovieID]
+		current = append(current, listedMovie{movieID: entry.MovieID, hidden: !visible})
+	}
+	positions, err := reorderedPositions(current, movieIDs)
+	if err != nil {
+		return err
+	}
+
+	reordered := make([]models.MovieListEntry, len(entries))
+	for _, entry := range entries {
+		reordered[positions[entry.MovieID]-1] = entry
+	}
+	r.entries[listID] = reordered
+	list.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *MemoryMovieListRepository) insert(list *models.MovieList) *models.MovieList {
+	now := time.Now()
+	stored := *list
+	stored.ID = r.nextID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	r.lists[stored.ID] = &stored
+	r.nextID++
+	return &stored
+}
+
+// response converts a stored list, counting its entries outside the trash.
+func (r *MemoryMovieListRepository) response(list *models.MovieList) *models.ListResponse {
+	visible := r.movies.activeMovies(listedMovieIDs(r.entries[list.ID]))
+	response := toListResponse(list, len(visible))
+	return &response
+}
+
+// nameTaken mirrors the unique index on the user's list names.
+func (r *MemoryMovieListRepository) nameTaken(userID uint, name string, exceptID uint) bool {
+	for id, list := range r.lists {
+		if id != exceptID && list.UserID == userID && list.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func listedMovieIDs(entries []models.MovieListEntry) []uint {
+	ids := make([]uint, 0, len(entries))
+	for _, entry := range entries {
+		ids = append(ids, entry.MovieID)
+	}
+	return ids
+}
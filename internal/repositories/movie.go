@@ -1,166 +1,529 @@
 package repositories
 
 import (
+	"errors"
+	"fmt"
+	"itv-task/config"
 	"itv-task/internal/models"
-	"log"
+	"itv-task/pkg/filterql"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"gorm.io/gorm"
 )
 
-type MovieRepository struct {
-	db *gorm.DB
-}
+// ErrVersionConflict is returned when a movie changed since the version the caller expected.
+var ErrVersionConflict = errors.New("movie version conflict")
+
+// ErrInvalidCursor is returned when a cursor's sort keys do not fit its sort columns.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrDirectorTooLong is returned when the names of a movie's director credits do not fit
+// its director field.
+var ErrDirectorTooLong = errors.New("director names too long")
+
+// MovieRepository stores the movie catalogue. Lookups and listings skip soft-deleted
+// movies, which sit in the trash until restored or purged. A missing movie is reported
+// as gorm.ErrRecordNotFound and a title already taken by a movie outside the trash as
+// gorm.ErrDuplicatedKey.
+//
+// Every change increments the movie's version and returns the movie as written. Update,
+// Patch and Delete take the version the caller expects and apply atomically only if it
+// is still current, returning ErrVersionConflict otherwise; a zero version applies
+// unconditionally.
+//
+// Every change also takes a RecordRevision and writes the revision it builds together
+// with the change: if the revision cannot be written, the change is rolled back.
+type MovieRepository interface {
+	Create(movie *models.CreateMovieRequest, record RecordRevision) (*models.MovieResponse, error)
+	GetByID(id uint) (*models.MovieResponse, error)
+	GetByTitle(title string) (*models.MovieResponse, error)
+	// GetByIDFields is GetByID loading only the fields, by JSON name, along with the ID
+	// and version; no fields loads them all.
+	GetByIDFields(id uint, fields []string) (*models.MovieResponse, error)
+	// GetAll returns one page of the movies matching the filter along with the total
+	// number of matches, unless the filter skips it. Pages start after the filter's
+	// cursor, if any, and come with the positions of the pages before and after them.
+	GetAll(filter models.MovieFilter) (models.MovieListResponse, error)
+	// Search returns one page of the movies matching both the websearch-style query and
+	// the filter, most relevant first unless the filter sorts them, along with the total
+	// number of matches.
+	Search(query string, filter models.MovieFilter) (models.MovieSearchResponse, error)
+	// FindSimilar lists up to limit movies whose titles have at least the given trigram
+	// similarity to the title, most similar first.
+	FindSimilar(title string, threshold float64, limit int) ([]models.SimilarMovie, error)
+	// DuplicateClusters groups the movies whose titles have at least the given similarity
+	// to another one and returns one page of the groups, closest first, along with their
+	// total number.
+	DuplicateClusters(threshold float64, limit, offset int) ([]models.DuplicateCluster, int, error)
+	// Facets counts the movies matching the filter, ignoring its paging, by decade, year,
+	// director, genre and rating. Values are in no particular order.
+	Facets(filter models.MovieFilter) (models.MovieFacetsResponse, error)
+	// GenreCounts counts the movies matching the filter by genre ID, ignoring its paging.
+	GenreCounts(filter models.MovieFilter) (map[uint]int, error)
+	// Update replaces the movie's fields, and its genres unless the request has none.
+	// Genres are given by name and unknown ones fail with ErrUnknownGenre.
+	Update(movie *models.UpdateMovieRequest, record RecordRevision) (*models.MovieResponse, error)
+	// Patch updates only the non-nil fields of the request.
+	Patch(movie *models.PatchMovieRequest, record RecordRevision) (*models.MovieResponse, error)
+	// Delete soft deletes the movie and returns it as it was deleted.
+	Delete(id, version uint, record RecordRevision) (*models.MovieResponse, error)
+	// BulkInsertMovies inserts every movie or, if any of them fails, none.
+	BulkInsertMovies(movies *models.BulkInsertMoviesRequest, record RecordRevision) ([]models.MovieResponse, error)
+	// ImportMovies creates the movies, whose titles must be distinct, one by one and
+	// reports the outcome of each. A taken title fails with gorm.ErrDuplicatedKey unless
+	// upsert is set, which updates the movie with the title instead, keeping its genres
+	// when the import has none for it. Failed movies leave the others alone; with atomic,
+	// a failure writes nothing at all, and neither does dryRun.
+	ImportMovies(movies []models.CreateMovieRequest, upsert, atomic, dryRun bool, record RecordRevision) ([]ImportOutcome, error)
+
+	// GetDeleted lists the trash, most recently deleted first.
+	GetDeleted(limit, offset int) (models.MovieListResponse, error)
+	GetDeletedByID(id uint) (*models.MovieResponse, error)
+	// Restore takes the movie out of the trash, under the same version rules as Update.
+	Restore(id, version uint, record RecordRevision) (*models.MovieResponse, error)
+	// HardDelete permanently removes the movie, in the trash or not, and returns it as it was.
+	HardDelete(id uint, record RecordRevision) (*models.MovieResponse, error)
+	// PurgeDeleted permanently removes the movies deleted before the given time.
+	PurgeDeleted(before time.Time, record RecordRevision) ([]models.MovieResponse, error)
 
-func NewMovieRepository(db *gorm.DB) *MovieRepository {
-	return &MovieRepository{db: db}
+	// GetRelations loads the relations the expansion asks for of each of the movies,
+	// keyed by movie ID. Expanded lists are empty rather than nil.
+	GetRelations(ids []uint, expand models.MovieExpansion) (map[uint]*models.MovieRelations, error)
+	// GetCredits lists the movie's cast and crew in billing order.
+	GetCredits(movieID uint) ([]models.CreditResponse, error)
+	// ReplaceCredits replaces every credit of the movie, under the same version rules as
+	// Update. The director field is rewritten to the names of the director credits, or kept
+	// when there are none, and fails with ErrDirectorTooLong when the names do not fit.
+	// Credits of unknown people fail with ErrUnknownPerson.
+	ReplaceCredits(request *models.ReplaceCreditsRequest, record RecordRevision) (*models.MovieResponse, error)
+	// GetFilmography lists the person's credits on movies outside the trash, newest first.
+	GetFilmography(personID uint) ([]models.FilmographyEntry, error)
+	// CountCredits counts the person's credits, including those on movies in the trash.
+	CountCredits(personID uint) (int, error)
 }
 
-func (r *MovieRepository) Create(movie *models.CreateMovieRequest) (uint, error) {
-	gormModel := models.Movie{
-		Title:    movie.Title,
-		Director: movie.Director,
-		Year:     movie.Year,
-		Plot:     movie.Plot,
+// ErrMixedStores is returned when an in-memory repository is wired to one of the
+// repositories it depends on that does not keep its data in memory too.
+var ErrMixedStores = errors.New("in-memory repositories need in-memory dependencies")
+
+// NewMovieRepository picks the repository implementation configured by MOVIE_STORE. The
+// in-memory store looks genres and people up in the in-memory genre and person repositories
+// and writes revisions to the in-memory revision repository.
+func NewMovieRepository(cfg *config.Config, db *gorm.DB, genres GenreRepository, people PersonRepository, revisions MovieRevisionRepository) (MovieRepository, error) {
+	if cfg.MovieStore == "memory" {
+		memoryGenres, ok := genres.(*MemoryGenreRepository)
+		if !ok {
+			return nil, fmt.Errorf("%w: genres are kept in %T", ErrMixedStores, genres)
+		}
+		memoryPeople, ok := people.(*MemoryPersonRepository)
+		if !ok {
+			return nil, fmt.Errorf("%w: people are kept in %T", ErrMixedStores, people)
+		}
+		memoryRevisions, ok := revisions.(*MemoryMovieRevisionRepository)
+		if !ok {
+			return nil, fmt.Errorf("%w: revisions are kept in %T", ErrMixedStores, revisions)
+		}
+		return NewMemoryMovieRepository(memoryGenres, memoryPeople, memoryRevisions), nil
 	}
-	if err := r.db.Table("movies").Create(&gormModel).Error; err != nil {
-		log.Println("❌ Failed to create movie:", err)
-		return 0, err
+	return NewPostgresMovieRepository(db), nil
+}
+
+// memoryMovies returns the in-memory movie repository that in-memory repositories of data
+// about movies work with.
+func memoryMovies(movies MovieRepository) (*MemoryMovieRepository, error) {
+	memory, ok := movies.(*MemoryMovieRepository)
+	if !ok {
+		return nil, fmt.Errorf("%w: movies are kept in %T", ErrMixedStores, movies)
 	}
-	return gormModel.ID, nil
+	return memory, nil
+}
+
+// ImportOutcome is what importing a movie did or, when nothing was written, would have
+// done. Movie and Before, the movie as it was before an update, are only set for movies
+// that were written.
+type ImportOutcome struct {
+	Movie   *models.MovieResponse
+	Before  *models.MovieResponse
+	Updated bool
+	Err     error
+}
+
+// movieSortColumns maps the accepted sort fields to columns.
+var movieSortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"year":       "year",
+	"created_at": "created_at",
+	"director":   "director",
+	"rating":     "rating_average",
+}
+
+// MovieFilterFields are the fields filter expressions over movies can use, with the
+// columns they compile to.
+var MovieFilterFields = map[string]filterql.Field{
+	"title":        {Kind: filterql.Text, Column: "movies.title"},
+	"director":     {Kind: filterql.Text, Column: "movies.director"},
+	"plot":         {Kind: filterql.Text, Column: "movies.plot"},
+	"year":         {Kind: filterql.Integer, Column: "movies.year"},
+	"rating":       {Kind: filterql.Number, Column: "movies.rating_average"},
+	"rating_count": {Kind: filterql.Integer, Column: "movies.rating_count"},
+	"created_at":   {Kind: filterql.Time, Column: "movies.created_at"},
+	"updated_at":   {Kind: filterql.Time, Column: "movies.updated_at"},
+}
+
+// movieFieldColumns maps the fields of MovieResponse that are columns to them.
+var movieFieldColumns = map[string]string{
+	"id":             "id",
+	"title":          "title",
+	"director":       "director",
+	"year":           "year",
+	"plot":           "plot",
+	"version":        "version",
+	"rating_average": "rating_average",
+	"rating_count":   "rating_count",
+	"created_at":     "created_at",
+	"updated_at":     "updated_at",
 }
 
-func (r *MovieRepository) GetByID(id uint) (*models.MovieResponse, error) {
-	var movie models.MovieResponse
-	if err := r.db.Table("movies").First(&movie, "id = ? AND deleted_at IS NULL ", id).Error; err != nil {
-		log.Println("❌ Movie not found:", err)
-		return nil, err
+// selectColumns returns the columns to load for the fields, followed by the required
+// columns the caller needs regardless, or nil to load every column.
+func selectColumns(fields []string, required ...string) []string {
+	if len(fields) == 0 {
+		return nil
 	}
-	return &movie, nil
+	var columns []string
+	for _, field := range fields {
+		if column, ok := movieFieldColumns[field]; ok && !slices.Contains(columns, "movies."+column) {
+			columns = append(columns, "movies."+column)
+		}
+	}
+	for _, column := range required {
+		if !slices.Contains(columns, "movies."+column) {
+			columns = append(columns, "movies."+column)
+		}
+	}
+	return columns
+}
+
+// loadsGenres reports whether the fields include the movies' genres.
+func loadsGenres(fields []string) bool {
+	return len(fields) == 0 || slices.Contains(fields, "genres")
 }
 
-func (r *MovieRepository) GetByTitle(title string) (*models.MovieResponse, error) {
-	var movie models.MovieResponse
-	if err := r.db.Table("movies").First(&movie, "title = ? AND deleted_at IS NULL ", title).Error; err != nil {
-		log.Println("❌ Movie not found:", err)
-		return nil, err
+// ratingSummary aggregates the counts of reviews per rating, rounding the average like
+// numeric(4,2).
+func ratingSummary(distribution map[int]int) *models.RatingSummary {
+	summary := &models.RatingSummary{Distribution: make(map[int]int, 10)}
+	sum := 0
+	for rating := 1; rating <= 10; rating++ {
+		summary.Distribution[rating] = distribution[rating]
+		summary.Count += distribution[rating]
+		sum += rating * distribution[rating]
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(sum)/float64(summary.Count)*100) / 100
 	}
-	return &movie, nil
+	return summary
 }
-func (r *MovieRepository) GetAll(title, director string, year int, sortBy, sortOrder string, limit, offset int) (models.MovieListResponse, error) {
-	var movies []models.MovieResponse
-	var totalCount int64
-	query := r.db.Model(&models.Movie{})
 
-	// Apply filters
-	if title != "" {
-		query = query.Where("title ILIKE ?", "%"+title+"%")
+// emptyRelations returns the relations of the movies with every expanded list empty.
+func emptyRelations(ids []uint, expand models.MovieExpansion) map[uint]*models.MovieRelations {
+	relations := make(map[uint]*models.MovieRelations, len(ids))
+	for _, id := range ids {
+		relation := &models.MovieRelations{}
+		if expand.Genres {
+			relation.Genres = []models.GenreRef{}
+		}
+		if expand.Credits || expand.CreditsPerson {
+			relation.Credits = []models.ExpandedCredit{}
+		}
+		if expand.Ratings {
+			relation.Ratings = ratingSummary(nil)
+		}
+		relations[id] = relation
 	}
-	if director != "" {
-		query = query.Where("director ILIKE ?", "%"+director+"%")
+	return relations
+}
+
+// personResponse renders a person embedded in a credit.
+func personResponse(person *models.Person) *models.PersonResponse {
+	return &models.PersonResponse{
+		ID:        person.ID,
+		Name:      person.Name,
+		Bio:       person.Bio,
+		BirthYear: person.BirthYear,
+		CreatedAt: person.CreatedAt,
+		UpdatedAt: person.UpdatedAt,
 	}
-	if year > 0 {
-		query = query.Where("year = ?", year)
+}
+
+// orderColumn is a column of a movie ordering and its direction.
+type orderColumn struct {
+	column string
+	desc   bool
+}
+
+// movieOrder resolves the sort fields into columns. Ties, and movies without a sort,
+// are ordered by ascending ID.
+func movieOrder(sort []models.SortField) []orderColumn {
+	order := make([]orderColumn, 0, len(sort))
+	for _, field := range sort {
+		if column, ok := movieSortColumns[field.Field]; ok {
+			order = append(order, orderColumn{column: column, desc: field.Desc})
+		}
 	}
+	return order
+}
 
-	// Get total count before applying limit & offset
-	if err := query.Count(&totalCount).Error; err != nil {
-		log.Println("❌ Failed to count movies:", err)
-		return models.MovieListResponse{}, err
+// orderClauses returns the ORDER BY terms for the order, reversed for reading backwards,
+// ending with the ID unless the order already includes it.
+func orderClauses(order []orderColumn, backward bool) []string {
+	clauses := make([]string, 0, len(order)+1)
+	byID := false
+	for _, o := range order {
+		direction := " ASC"
+		if o.desc != backward {
+			direction = " DESC"
+		}
+		clauses = append(clauses, o.column+direction)
+		byID = byID || o.column == "id"
 	}
+	if !byID {
+		if backward {
+			clauses = append(clauses, "id DESC")
+		} else {
+			clauses = append(clauses, "id ASC")
+		}
+	}
+	return clauses
+}
 
-	switch sortOrder {
-	case "asc":
-		sortOrder = " AS "
-	case "desc":
-		sortOrder = " DESC "
-	default:
-		sortOrder = " DESC "
+// keysetCondition returns the condition selecting the movies after the position in the
+// order, or with backward the movies before it, and its arguments. A movie comes after
+// the position when it is past it on the first column it differs on, ascending ID last.
+func keysetCondition(order []orderColumn, backward bool, keys []interface{}, id uint) (string, []interface{}) {
+	var alternatives []string
+	var args []interface{}
+	for i := 0; i <= len(order); i++ {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, "movies."+order[j].column+" = ?")
+			args = append(args, keys[j])
+		}
+		if i < len(order) {
+			op := ">"
+			if order[i].desc != backward {
+				op = "<"
+			}
+			terms = append(terms, "movies."+order[i].column+" "+op+" ?")
+			args = append(args, keys[i])
+		} else {
+			op := ">"
+			if backward {
+				op = "<"
+			}
+			terms = append(terms, "movies.id "+op+" ?")
+			args = append(args, id)
+		}
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
 	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
 
-	// Apply sorting
-	switch sortBy {
+// sortKey formats the movie's value of the sort column for a cursor.
+func sortKey(movie *models.MovieResponse, column string) string {
+	switch column {
 	case "title":
-		query = query.Order("title " + sortBy)
+		return movie.Title
+	case "director":
+		return movie.Director
 	case "year":
-		query = query.Order("year " + sortOrder)
+		return strconv.Itoa(movie.Year)
+	case "rating_average":
+		return strconv.FormatFloat(movie.RatingAverage, 'f', -1, 64)
 	case "created_at":
-		query = query.Order("created_at " + sortOrder)
-	case "director":
-		query = query.Order("director " + sortBy)
+		return movie.CreatedAt.Format(time.RFC3339Nano)
 	default:
-		query = query.Order("id " + sortBy)
+		return strconv.FormatUint(uint64(movie.ID), 10)
 	}
+}
 
-	if limit > 0 {
-		query = query.Limit(limit)
+// cursorMovie returns a movie with the cursor's ID and sort keys, for comparing others with.
+func cursorMovie(cursor *models.MovieCursor, order []orderColumn) (*models.Movie, error) {
+	if len(cursor.Keys) != len(order) {
+		return nil, ErrInvalidCursor
 	}
-	query = query.Offset(offset)
-
-	if err := query.Find(&movies).Error; err != nil {
-		log.Println("❌ Failed to retrieve movies:", err)
-		return models.MovieListResponse{}, err
+	movie := &models.Movie{ID: cursor.ID}
+	for i, o := range order {
+		var err error
+		key := cursor.Keys[i]
+		switch o.column {
+		case "title":
+			movie.Title = key
+		case "director":
+			movie.Director = key
+		case "year":
+			movie.Year, err = strconv.Atoi(key)
+		case "rating_average":
+			movie.RatingAverage, err = strconv.ParseFloat(key, 64)
+		case "created_at":
+			movie.CreatedAt, err = time.Parse(time.RFC3339Nano, key)
+		case "id":
+			if key != strconv.FormatUint(uint64(cursor.ID), 10) {
+				err = ErrInvalidCursor
+			}
+		}
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
 	}
+	return movie, nil
+}
 
-	return models.MovieListResponse{
-		Movies: movies,
-		Count:  int(totalCount),
-	}, nil
+// sortValues returns the movie's values of the order's columns.
+func sortValues(movie *models.Movie, order []orderColumn) []interface{} {
+	values := make([]interface{}, len(order))
+	for i, o := range order {
+		switch o.column {
+		case "title":
+			values[i] = movie.Title
+		case "director":
+			values[i] = movie.Director
+		case "year":
+			values[i] = movie.Year
+		case "rating_average":
+			values[i] = movie.RatingAverage
+		case "created_at":
+			values[i] = movie.CreatedAt
+		default:
+			values[i] = movie.ID
+		}
+	}
+	return values
 }
 
-func (r *MovieRepository) Update(movie *models.UpdateMovieRequest) error {
-	gormModel := models.Movie{
-		Title:     movie.Title,
-		Director:  movie.Director,
-		Year:      movie.Year,
-		Plot:      movie.Plot,
-		UpdatedAt: time.Now(),
+// pageCursors returns the positions of the pages after and before a page of movies in
+// the order. more reports whether movies follow the page in the direction it was read,
+// which for a backward page is towards the start.
+func pageCursors(filter models.MovieFilter, movies []models.MovieResponse, more bool, order []orderColumn) (next, prev *models.MovieCursor) {
+	if len(movies) == 0 {
+		return nil, nil
+	}
+	position := func(movie *models.MovieResponse, backward bool) *models.MovieCursor {
+		keys := make([]string, len(order))
+		for i, o := range order {
+			keys[i] = sortKey(movie, o.column)
+		}
+		return &models.MovieCursor{Sort: models.FormatSort(filter.Sort), Keys: keys, ID: movie.ID, Backward: backward}
 	}
-	if err := r.db.Table("movies").Save(gormModel).Error; err != nil {
-		log.Println("❌ Failed to update movie:", err)
-		return err
+
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	if more || backward {
+		next = position(&movies[len(movies)-1], false)
 	}
-	return nil
+	if (backward && more) || (!backward && (filter.Cursor != nil || filter.Offset > 0)) {
+		prev = position(&movies[0], true)
+	}
+	return next, prev
 }
 
-func (r *MovieRepository) Delete(id uint) error {
-	if err := r.db.Table("movies").Where("id = ?", id).Delete(&models.Movie{}).Error; err != nil {
-		log.Println("❌ Failed to soft delete movie:", err)
-		return err
+func toMovieResponse(movie *models.Movie) *models.MovieResponse {
+	return &models.MovieResponse{
+		ID:            movie.ID,
+		Title:         movie.Title,
+		Director:      movie.Director,
+		Year:          movie.Year,
+		Plot:          movie.Plot,
+		Version:       movie.Version,
+		RatingAverage: movie.RatingAverage,
+		RatingCount:   movie.RatingCount,
+		CreatedAt:     movie.CreatedAt,
+		UpdatedAt:     movie.UpdatedAt,
+		DeletedAt:     deletedAt(movie.DeletedAt),
+		Genres:        []string{},
 	}
-	return nil
 }
 
-func (s *MovieRepository) BulkInsertMovies(movies *models.BulkInsertMoviesRequest) error {
-	tx := s.db.Table("movies").Begin() // Start transaction
-	if tx.Error != nil {
-		log.Println("❌ Failed to start transaction:", tx.Error)
-		return tx.Error
+func emptyFacets() models.MovieFacetsResponse {
+	return models.MovieFacetsResponse{
+		Decades:   []models.FacetValue{},
+		Years:     []models.FacetValue{},
+		Directors: []models.FacetValue{},
+		Genres:    []models.FacetValue{},
+		Ratings:   []models.FacetValue{},
 	}
-	defer func() {
-		tx.Rollback()
-	}()
+}
 
-	var gormModel = models.Movie{}
-	for _, movie := range movies.Movies {
-		gormModel = models.Movie{
-			Title:    movie.Title,
-			Director: movie.Director,
-			Year:     movie.Year,
-			Plot:     movie.Plot,
-		}
-		if err := tx.Create(&gormModel).Error; err != nil {
-			tx.Rollback() // Rollback on failure
-			log.Println("❌ Failed to bulk insert movies:", err)
-			return err
+// decadeFacet values a decade by its first year and labels it like "1990s".
+func decadeFacet(decade, count int) models.FacetValue {
+	return models.FacetValue{Value: strconv.Itoa(decade), Label: strconv.Itoa(decade) + "s", Count: count}
+}
+
+func deletedAt(value gorm.DeletedAt) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	deleted := value.Time
+	return &deleted
+}
+
+// sortedCredits orders credits by billing, keeping the request order among equals.
+func sortedCredits(credits []models.CreditRequest) []models.CreditRequest {
+	sorted := append([]models.CreditRequest(nil), credits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Billing < sorted[j].Billing })
+	return sorted
+}
+
+// directorSeparator joins the names of several directors in the director field.
+const directorSeparator = ", "
+
+// maxDirectorLength is the size of the director column, in characters.
+const maxDirectorLength = 255
+
+// directorOf joins the names of the director credits, which must be sorted, into the
+// movie's director field. It returns "" when no credit is a director.
+func directorOf(credits []models.CreditRequest, names map[uint]string) (string, error) {
+	var directors []string
+	for _, credit := range credits {
+		if credit.Role == models.CreditDirector {
+			directors = append(directors, names[credit.PersonID])
 		}
 	}
+	director := strings.Join(directors, directorSeparator)
+	if utf8.RuneCountInString(director) > maxDirectorLength {
+		return "", fmt.Errorf("%w: %d characters, at most %d fit", ErrDirectorTooLong, utf8.RuneCountInString(director), maxDirectorLength)
+	}
+	return director, nil
+}
 
-	// Commit transaction
-	if err := tx.Commit().Error; err != nil {
-		log.Println("❌ Failed to commit transaction:", err)
-		return err
+// directorNames splits a director field back into the names of its directors, each once,
+// so that a field written back as directorOf joined it credits every director again.
+func directorNames(director string) []string {
+	var names []string
+	for _, name := range strings.Split(director, directorSeparator) {
+		if name = strings.TrimSpace(name); name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
 	}
+	return names
+}
 
-	return nil
+// missingPeople returns the IDs that have no name, each once and in ascending order.
+func missingPeople(ids []uint, names map[uint]string) []uint {
+	seen := make(map[uint]bool)
+	var missing []uint
+	for _, id := range ids {
+		if _, ok := names[id]; !ok && !seen[id] {
+			seen[id] = true
+			missing = append(missing, id)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	return missing
 }
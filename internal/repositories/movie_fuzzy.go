@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"itv-task/internal/models"
+	"sort"
+)
+
+// similarPair is two movies with similar titles.
+type similarPair struct {
+	first, second models.SimilarMovie
+	score         float64
+}
+
+// clusterPairs joins the pairs into clusters of movies connected by similar titles and
+// returns one page of them, closest first, along with the number of clusters.
+func clusterPairs(pairs []similarPair, limit, offset int) ([]models.DuplicateCluster, int) {
+	parent := make(map[uint]uint)
+	var root func(id uint) uint
+	root = func(id uint) uint {
+		if parent[id] == id {
+			return id
+		}
+		parent[id] = root(parent[id])
+		return parent[id]
+	}
+
+	movies := make(map[uint]models.SimilarMovie)
+	for _, pair := range pairs {
+		for _, movie := range []models.SimilarMovie{pair.first, pair.second} {
+			known, ok := movies[movie.ID]
+			if !ok {
+				parent[movie.ID] = movie.ID
+			}
+			if !ok || pair.score > known.Score {
+				movie.Score = pair.score
+				movies[movie.ID] = movie
+			}
+		}
+		if a, b := root(pair.first.ID), root(pair.second.ID); a != b {
+			parent[max(a, b)] = min(a, b)
+		}
+	}
+
+	byRoot := make(map[uint]*models.DuplicateCluster)
+	for id, movie := range movies {
+		cluster, ok := byRoot[root(id)]
+		if !ok {
+			cluster = &models.DuplicateCluster{}
+			byRoot[root(id)] = cluster
+		}
+		cluster.Movies = append(cluster.Movies, movie)
+		cluster.Score = max(cluster.Score, movie.Score)
+	}
+
+	clusters := make([]models.DuplicateCluster, 0, len(byRoot))
+	for _, cluster := range byRoot {
+		sort.Slice(cluster.Movies, func(i, j int) bool {
+			a, b := cluster.Movies[i], cluster.Movies[j]
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return a.ID < b.ID
+		})
+		clusters = append(clusters, *cluster)
+	}
+	// Movies are in one cluster only, so the lowest IDs tell clusters of equal score apart
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Score != clusters[j].Score {
+			return clusters[i].Score > clusters[j].Score
+		}
+		return lowestID(clusters[i]) < lowestID(clusters[j])
+	})
+
+	total := len(clusters)
+	if offset >= total {
+		return []models.DuplicateCluster{}, total
+	}
+	clusters = clusters[offset:]
+	if limit > 0 && limit < len(clusters) {
+		clusters = clusters[:limit]
+	}
+	return clusters, total
+}
+
+func lowestID(cluster models.DuplicateCluster) uint {
+	lowest := cluster.Movies[0].ID
+	for _, movie := range cluster.Movies {
+		lowest = min(lowest, movie.ID)
+	}
+	return lowest
+}
+
+// trigrams returns the trigrams of the text the way pg_trgm extracts them: each word of
+// letters and digits, lowercased and padded with two spaces in front and one behind, is
+// cut into every run of three characters.
+func trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, word := range searchWords(text) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// trigramSimilarity is pg_trgm's similarity: the trigrams two texts share over the
+// trigrams of either.
+func trigramSimilarity(a, b map[string]bool) float64 {
+	shared := 0
+	for trigram := range a {
+		if b[trigram] {
+			shared++
+		}
+	}
+	union := len(a) + len(b) - shared
+	if union == 0 {
+		return 0
+	}
+	return float64(shared) / float64(union)
+}
@@ -0,0 +1,1070 @@
+package repositories
+
+import (
+	"cmp"
+	"itv-task/internal/models"
+	"itv-task/pkg/filterql"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MemoryMovieRepository keeps movies in process memory. It follows the Postgres
+// repository's semantics, including the title index that only covers movies outside the
+// trash, so services and handlers can be exercised without a database.
+type MemoryMovieRepository struct {
+	mu          sync.RWMutex
+	movies      map[uint]*models.Movie
+	movieGenres map[uint][]uint               // Genre IDs by movie ID
+	credits     map[uint][]models.MovieCredit // By movie ID, in billing order
+	ratings     map[uint]map[int]int          // Review counts per rating by movie ID, kept by the review repository
+	genres      *MemoryGenreRepository
+	people      *MemoryPersonRepository
+	revisions   *MemoryMovieRevisionRepository
+	nextID      uint
+}
+
+func NewMemoryMovieRepository(genres *MemoryGenreRepository, people *MemoryPersonRepository, revisions *MemoryMovieRevisionRepository) *MemoryMovieRepository {
+	return &MemoryMovieRepository{
+		movies:      make(map[uint]*models.Movie),
+		movieGenres: make(map[uint][]uint),
+		credits:     make(map[uint][]models.MovieCredit),
+		ratings:     make(map[uint]map[int]int),
+		genres:      genres,
+		people:      people,
+		revisions:   revisions,
+		nextID:      1,
+	}
+}
+
+func (r *MemoryMovieRepository) Create(movie *models.CreateMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.titleTaken(movie.Title, 0) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	genreIDs, err := r.genres.resolve(movie.Genres)
+	if err != nil {
+		return nil, err
+	}
+
+	created := r.response(r.insert(movie, genreIDs))
+	if err := r.commit([]movieState{{id: created.ID}}, record(nil, created)); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *MemoryMovieRepository) GetByID(id uint) (*models.MovieResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	movie, ok := r.active(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.response(movie), nil
+}
+
+// GetByIDFields loads every field; handlers leave out those not asked for.
+func (r *MemoryMovieRepository) GetByIDFields(id uint, fields []string) (*models.MovieResponse, error) {
+	return r.GetByID(id)
+}
+
+func (r *MemoryMovieRepository) GetByTitle(title string) (*models.MovieResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, movie := range r.movies {
+		if movie.Title == title && !movie.DeletedAt.Valid {
+			return r.response(movie), nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *MemoryMovieRepository) GetAll(filter models.MovieFilter) (models.MovieListResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	matches := r.filtered(filter)
+	columns := movieOrder(filter.Sort)
+	order := movieComparator(columns)
+	slices.SortFunc(matches, order)
+
+	response := models.MovieListResponse{Movies: []models.MovieResponse{}}
+	if !filter.SkipTotal {
+		count := len(matches)
+		response.Count = &count
+	}
+
+	// Backward pages are read in reverse, from the cursor towards the start, and flipped below
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	if filter.Cursor != nil {
+		position, err := cursorMovie(filter.Cursor, columns)
+		if err != nil {
+			return models.MovieListResponse{}, err
+		}
+		var page []*models.Movie
+		for _, movie := range matches {
+			if c := order(movie, position); (c > 0 && !backward) || (c < 0 && backward) {
+				page = append(page, movie)
+			}
+		}
+		if backward {
+			slices.Reverse(page)
+		}
+		matches = page
+	}
+
+	if filter.Offset >= len(matches) {
+		return response, nil
+	}
+	matches = matches[filter.Offset:]
+	more := filter.Limit > 0 && len(matches) > filter.Limit
+	if more {
+		matches = matches[:filter.Limit]
+	}
+	if backward {
+		slices.Reverse(matches)
+	}
+	for _, movie := range matches {
+		response.Movies = append(response.Movies, *r.response(movie))
+	}
+	response.Next, response.Prev = pageCursors(filter, response.Movies, more, columns)
+	return response, nil
+}
+
+func (r *MemoryMovieRepository) Search(query string, filter models.MovieFilter) (models.MovieSearchResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	parsed := parseSearchQuery(query)
+	ranks := make(map[uint]float64)
+	var matches []*models.Movie
+	for _, movie := range r.filtered(filter) {
+		if rank, ok := newSearchDocument(movie.Title, movie.Director, movie.Plot).rank(parsed); ok {
+			ranks[movie.ID] = rank
+			matches = append(matches, movie)
+		}
+	}
+
+	order := movieComparator(movieOrder(filter.Sort))
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if len(filter.Sort) == 0 && ranks[a.ID] != ranks[b.ID] {
+			return ranks[a.ID] > ranks[b.ID]
+		}
+		return order(a, b) < 0
+	})
+
+	response := models.MovieSearchResponse{Results: []models.MovieSearchResult{}, Count: len(matches)}
+	if filter.Offset >= len(matches) {
+		return response, nil
+	}
+	matches = matches[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(matches) {
+		matches = matches[:filter.Limit]
+	}
+	for _, movie := range matches {
+		response.Results = append(response.Results, models.MovieSearchResult{
+			MovieResponse: *r.response(movie),
+			Rank:          ranks[movie.ID],
+			Headline:      searchHeadline(movie.Plot, parsed),
+		})
+	}
+	return response, nil
+}
+
+func (r *MemoryMovieRepository) FindSimilar(title string, threshold float64, limit int) ([]models.SimilarMovie, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	searched := trigrams(title)
+	movies := []models.SimilarMovie{}
+	for _, movie := range r.movies {
+		if movie.DeletedAt.Valid {
+			continue
+		}
+		if score := trigramSimilarity(searched, trigrams(movie.Title)); score > 0 && score >= threshold {
+			movies = append(movies, similarMovie(movie, score))
+		}
+	}
+	sort.Slice(movies, func(i, j int) bool {
+		if movies[i].Score != movies[j].Score {
+			return movies[i].Score > movies[j].Score
+		}
+		return movies[i].ID < movies[j].ID
+	})
+	if limit > 0 && limit < len(movies) {
+		movies = movies[:limit]
+	}
+	return movies, nil
+}
+
+func (r *MemoryMovieRepository) DuplicateClusters(threshold float64, limit, offset int) ([]models.DuplicateCluster, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var active []*models.Movie
+	titles := make(map[uint]map[string]bool)
+	for _, movie := range r.movies {
+		if !movie.DeletedAt.Valid {
+			active = append(active, movie)
+			titles[movie.ID] = trigrams(movie.Title)
+		}
+	}
+
+	var pairs []similarPair
+	for i, a := range active {
+		for _, b := range active[i+1:] {
+			if score := trigramSimilarity(titles[a.ID], titles[b.ID]); score > 0 && score >= threshold {
+				pairs = append(pairs, similarPair{first: similarMovie(a, 0), second: similarMovie(b, 0), score: score})
+			}
+		}
+	}
+	clusters, total := clusterPairs(pairs, limit, offset)
+	return clusters, total, nil
+}
+
+func similarMovie(movie *models.Movie, score float64) models.SimilarMovie {
+	return models.SimilarMovie{ID: movie.ID, Title: movie.Title, Director: movie.Director, Year: movie.Year, Score: score}
+}
+
+func (r *MemoryMovieRepository) Facets(filter models.MovieFilter) (models.MovieFacetsResponse, error) {
+	genres, err := r.genres.GetAll()
+	if err != nil {
+		return models.MovieFacetsResponse{}, err
+	}
+	genreByID := make(map[uint]models.Genre, len(genres))
+	for _, genre := range genres {
+		genreByID[genre.ID] = genre
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	decades, years, ratings := make(map[int]int), make(map[int]int), make(map[int]int)
+	directors, genreCounts := make(map[string]int), make(map[uint]int)
+	matches := r.filtered(filter)
+	for _, movie := range matches {
+		decades[movie.Year/10*10]++
+		years[movie.Year]++
+		directors[movie.Director]++
+		for _, genreID := range r.movieGenres[movie.ID] {
+			if _, ok := genreByID[genreID]; ok {
+				genreCounts[genreID]++
+			}
+		}
+		if movie.RatingCount > 0 {
+			ratings[int(math.Floor(movie.RatingAverage))]++
+		}
+	}
+
+	facets := emptyFacets()
+	facets.Count = len(matches)
+	for decade, count := range decades {
+		facets.Decades = append(facets.Decades, decadeFacet(decade, count))
+	}
+	for year, count := range years {
+		facets.Years = append(facets.Years, models.FacetValue{Value: strconv.Itoa(year), Count: count})
+	}
+	for director, count := range directors {
+		facets.Directors = append(facets.Directors, models.FacetValue{Value: director, Count: count})
+	}
+	for genreID, count := range genreCounts {
+		genre := genreByID[genreID]
+		facets.Genres = append(facets.Genres, models.FacetValue{Value: genre.Slug, Label: genre.Name, Count: count})
+	}
+	for rating, count := range ratings {
+		facets.Ratings = append(facets.Ratings, models.FacetValue{Value: strconv.Itoa(rating), Count: count})
+	}
+	return facets, nil
+}
+
+func (r *MemoryMovieRepository) GenreCounts(filter models.MovieFilter) (map[uint]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	counts := make(map[uint]int)
+	for _, movie := range r.filtered(filter) {
+		for genreID := range r.genres.slugs(r.movieGenres[movie.ID]) {
+			counts[genreID]++
+		}
+	}
+	return counts, nil
+}
+
+// filtered returns the movies outside the trash that match the filter, in no particular order.
+func (r *MemoryMovieRepository) filtered(filter models.MovieFilter) []*models.Movie {
+	title, director := strings.ToLower(filter.Title), strings.ToLower(filter.Director)
+	matches := make([]*models.Movie, 0, len(r.movies))
+	for _, movie := range r.movies {
+		if movie.DeletedAt.Valid {
+			continue
+		}
+		if title != "" && !strings.Contains(strings.ToLower(movie.Title), title) {
+			continue
+		}
+		if director != "" && !strings.Contains(strings.ToLower(movie.Director), director) {
+			continue
+		}
+		if filter.Year > 0 && movie.Year != filter.Year {
+			continue
+		}
+		if filter.MinRating > 0 && movie.RatingAverage < filter.MinRating {
+			continue
+		}
+		if len(filter.Genres) > 0 && !r.hasGenres(movie.ID, filter.Genres, filter.GenreMatch == models.GenreMatchAll) {
+			continue
+		}
+		if filter.Expression != nil && !filterql.Eval(filter.Expression, func(field string) interface{} { return filterValue(movie, field) }) {
+			continue
+		}
+		matches = append(matches, movie)
+	}
+	return matches
+}
+
+// hasGenres reports whether the movie has any, or with all set every, genre of the slugs.
+func (r *MemoryMovieRepository) hasGenres(id uint, slugs []string, all bool) bool {
+	has := make(map[string]bool)
+	for _, slug := range r.genres.slugs(r.movieGenres[id]) {
+		has[slug] = true
+	}
+	for _, slug := range slugs {
+		if has[slug] && !all {
+			return true
+		}
+		if !has[slug] && all {
+			return false
+		}
+	}
+	return all
+}
+
+func (r *MemoryMovieRepository) Update(movie *models.UpdateMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.activeVersion(movie.ID, movie.Version)
+	if err != nil {
+		return nil, err
+	}
+	if r.titleTaken(movie.Title, movie.ID) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	var genreIDs []uint
+	if movie.Genres != nil {
+		if genreIDs, err = r.genres.resolve(movie.Genres); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, before := r.save(movie.ID), r.response(stored)
+	if movie.Genres != nil {
+		r.movieGenres[movie.ID] = genreIDs
+	}
+	stored.Title = movie.Title
+	stored.Director = movie.Director
+	stored.Year = movie.Year
+	stored.Plot = movie.Plot
+	stored.Version++
+	stored.UpdatedAt = time.Now()
+	r.setDirector(stored.ID, stored.Director)
+
+	updated := r.response(stored)
+	if err := r.commit(saved, record(before, updated)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *MemoryMovieRepository) Patch(movie *models.PatchMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.activeVersion(movie.ID, movie.Version)
+	if err != nil {
+		return nil, err
+	}
+	if movie.Title != nil && r.titleTaken(*movie.Title, movie.ID) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+	var genreIDs []uint
+	if movie.Genres != nil {
+		if genreIDs, err = r.genres.resolve(*movie.Genres); err != nil {
+			return nil, err
+		}
+	}
+
+	saved, before := r.save(movie.ID), r.response(stored)
+	if movie.Genres != nil {
+		r.movieGenres[movie.ID] = genreIDs
+	}
+	if movie.Title != nil {
+		stored.Title = *movie.Title
+	}
+	if movie.Director != nil {
+		stored.Director = *movie.Director
+		r.setDirector(stored.ID, stored.Director)
+	}
+	if movie.Year != nil {
+		stored.Year = *movie.Year
+	}
+	if movie.Plot != nil {
+		stored.Plot = *movie.Plot
+	}
+	stored.Version++
+	stored.UpdatedAt = time.Now()
+
+	patched := r.response(stored)
+	if err := r.commit(saved, record(before, patched)); err != nil {
+		return nil, err
+	}
+	return patched, nil
+}
+
+func (r *MemoryMovieRepository) Delete(id, version uint, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	movie, err := r.activeVersion(id, version)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, before := r.save(id), r.response(movie)
+	now := time.Now()
+	movie.DeletedAt = gorm.DeletedAt{Time: now, Valid: true}
+	movie.Version++
+	movie.UpdatedAt = now
+
+	deleted := r.response(movie)
+	if err := r.commit(saved, record(before, deleted)); err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+func (r *MemoryMovieRepository) BulkInsertMovies(movies *models.BulkInsertMoviesRequest, record RecordRevision) ([]models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Validate the whole batch first so a conflict leaves nothing behind, like the rolled back transaction.
+	seen := make(map[string]bool, len(movies.Movies))
+	genreIDs := make([][]uint, len(movies.Movies))
+	for i, movie := range movies.Movies {
+		if seen[movie.Title] || r.titleTaken(movie.Title, 0) {
+			return nil, gorm.ErrDuplicatedKey
+		}
+		seen[movie.Title] = true
+
+		var err error
+		if genreIDs[i], err = r.genres.resolve(movie.Genres); err != nil {
+			return nil, err
+		}
+	}
+
+	created := make([]models.MovieResponse, 0, len(movies.Movies))
+	saved := make([]movieState, 0, len(movies.Movies))
+	revisions := make([]*models.MovieRevision, 0, len(movies.Movies))
+	for i := range movies.Movies {
+		movie := r.response(r.insert(&movies.Movies[i], genreIDs[i]))
+		created = append(created, *movie)
+		saved = append(saved, movieState{id: movie.ID})
+		revisions = append(revisions, record(nil, movie))
+	}
+	if err := r.commit(saved, revisions...); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// ImportMovies checks every movie before writing any, which makes atomic imports and dry
+// runs simple.
+func (r *MemoryMovieRepository) ImportMovies(movies []models.CreateMovieRequest, upsert, atomic, dryRun bool, record RecordRevision) ([]ImportOutcome, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcomes := make([]ImportOutcome, len(movies))
+	genreIDs := make([][]uint, len(movies))
+	existing := make([]*models.Movie, len(movies))
+	failed := false
+	for i, movie := range movies {
+		var err error
+		if genreIDs[i], err = r.genres.resolve(movie.Genres); err != nil {
+			outcomes[i].Err, failed = err, true
+			continue
+		}
+		existing[i] = r.activeByTitle(movie.Title)
+		if existing[i] != nil && !upsert {
+			outcomes[i].Err, failed = gorm.ErrDuplicatedKey, true
+			continue
+		}
+		outcomes[i].Updated = existing[i] != nil
+	}
+	if dryRun || (atomic && failed) {
+		return outcomes, nil
+	}
+
+	for i := range movies {
+		movie := &movies[i]
+		if outcomes[i].Err != nil {
+			continue
+		}
+
+		var saved movieState
+		if stored := existing[i]; stored == nil {
+			outcomes[i].Movie = r.response(r.insert(movie, genreIDs[i]))
+			saved = movieState{id: outcomes[i].Movie.ID}
+		} else {
+			saved, outcomes[i].Before = r.save(stored.ID)[0], r.response(stored)
+			if movie.Genres != nil {
+				r.movieGenres[stored.ID] = genreIDs[i]
+			}
+			stored.Director = movie.Director
+			stored.Year = movie.Year
+			stored.Plot = movie.Plot
+			stored.Version++
+			stored.UpdatedAt = time.Now()
+			r.setDirector(stored.ID, stored.Director)
+			outcomes[i].Movie = r.response(stored)
+		}
+
+		// A movie whose revision cannot be written fails alone, like under its savepoint.
+		if err := r.commit([]movieState{saved}, record(outcomes[i].Before, outcomes[i].Movie)); err != nil {
+			outcomes[i].Movie, outcomes[i].Before, outcomes[i].Err = nil, nil, err
+		}
+	}
+	return outcomes, nil
+}
+
+// activeByTitle returns the movie outside the trash with the title, if any.
+func (r *MemoryMovieRepository) activeByTitle(title string) *models.Movie {
+	for _, movie := range r.movies {
+		if movie.Title == title && !movie.DeletedAt.Valid {
+			return movie
+		}
+	}
+	return nil
+}
+
+func (r *MemoryMovieRepository) GetDeleted(limit, offset int) (models.MovieListResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	deleted := make([]*models.Movie, 0)
+	for _, movie := range r.movies {
+		if movie.DeletedAt.Valid {
+			deleted = append(deleted, movie)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool {
+		a, b := deleted[i], deleted[j]
+		if !a.DeletedAt.Time.Equal(b.DeletedAt.Time) {
+			return a.DeletedAt.Time.After(b.DeletedAt.Time)
+		}
+		return a.ID < b.ID
+	})
+
+	count := len(deleted)
+	response := models.MovieListResponse{Movies: []models.MovieResponse{}, Count: &count}
+	if offset >= len(deleted) {
+		return response, nil
+	}
+	deleted = deleted[offset:]
+	if limit > 0 && limit < len(deleted) {
+		deleted = deleted[:limit]
+	}
+	for _, movie := range deleted {
+		response.Movies = append(response.Movies, *r.response(movie))
+	}
+	return response, nil
+}
+
+func (r *MemoryMovieRepository) GetDeletedByID(id uint) (*models.MovieResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	movie, ok := r.movies[id]
+	if !ok || !movie.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return r.response(movie), nil
+}
+
+func (r *MemoryMovieRepository) Restore(id, version uint, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	movie, ok := r.movies[id]
+	if !ok || !movie.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if version > 0 && movie.Version != version {
+		return nil, ErrVersionConflict
+	}
+	if r.titleTaken(movie.Title, id) {
+		return nil, gorm.ErrDuplicatedKey
+	}
+
+	saved, before := r.save(id), r.response(movie)
+	movie.DeletedAt = gorm.DeletedAt{}
+	movie.Version++
+	movie.UpdatedAt = time.Now()
+
+	restored := r.response(movie)
+	if err := r.commit(saved, record(before, restored)); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+func (r *MemoryMovieRepository) HardDelete(id uint, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	movie, ok := r.movies[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	saved, removed := r.save(id), r.response(movie)
+	r.remove(id)
+	if err := r.commit(saved, record(removed, nil)); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (r *MemoryMovieRepository) PurgeDeleted(before time.Time, record RecordRevision) ([]models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := make([]models.MovieResponse, 0)
+	saved := make([]movieState, 0)
+	revisions := make([]*models.MovieRevision, 0)
+	for id, movie := range r.movies {
+		if movie.DeletedAt.Valid && movie.DeletedAt.Time.Before(before) {
+			removed := r.response(movie)
+			purged = append(purged, *removed)
+			saved = append(saved, r.save(id)...)
+			revisions = append(revisions, record(removed, nil))
+			r.remove(id)
+		}
+	}
+	if err := r.commit(saved, revisions...); err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+func (r *MemoryMovieRepository) insert(movie *models.CreateMovieRequest, genreIDs []uint) *models.Movie {
+	now := time.Now()
+	stored := &models.Movie{
+		ID:        r.nextID,
+		Title:     movie.Title,
+		Director:  movie.Director,
+		Year:      movie.Year,
+		Plot:      movie.Plot,
+		Version:   1,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	r.movies[stored.ID] = stored
+	r.movieGenres[stored.ID] = genreIDs
+	r.nextID++
+	r.setDirector(stored.ID, stored.Director)
+	return stored
+}
+
+// movieState is what a write may change of one movie, saved so that the write can be
+// undone when its revisions cannot be recorded.
+type movieState struct {
+	id      uint
+	movie   *models.Movie // Nil when the movie did not exist
+	genres  []uint
+	credits []models.MovieCredit
+	ratings map[int]int
+}
+
+// save copies the state of the movies. Writes replace the genre, credit and rating
+// entries rather than modify them, so those need no copy.
+func (r *MemoryMovieRepository) save(ids ...uint) []movieState {
+	states := make([]movieState, 0, len(ids))
+	for _, id := range ids {
+		state := movieState{id: id, genres: r.movieGenres[id], credits: r.credits[id], ratings: r.ratings[id]}
+		if movie, ok := r.movies[id]; ok {
+			saved := *movie
+			state.movie = &saved
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+// commit records the revisions of a write already applied or, like a rolled back
+// transaction, puts the saved movies back when they cannot be recorded.
+func (r *MemoryMovieRepository) commit(saved []movieState, revisions ...*models.MovieRevision) error {
+	err := r.revisions.add(revisions...)
+	if err == nil {
+		return nil
+	}
+	for _, state := range saved {
+		if state.movie == nil {
+			r.remove(state.id)
+			continue
+		}
+		r.movies[state.id] = state.movie
+		r.movieGenres[state.id] = state.genres
+		r.credits[state.id] = state.credits
+		if state.ratings != nil {
+			r.ratings[state.id] = state.ratings
+		}
+	}
+	return err
+}
+
+// remove drops the movie and everything stored with it.
+func (r *MemoryMovieRepository) remove(id uint) {
+	delete(r.movies, id)
+	delete(r.movieGenres, id)
+	delete(r.credits, id)
+	delete(r.ratings, id)
+}
+
+func (r *MemoryMovieRepository) GetRelations(ids []uint, expand models.MovieExpansion) (map[uint]*models.MovieRelations, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	relations := emptyRelations(ids, expand)
+	for id, relation := range relations {
+		if expand.Genres {
+			relation.Genres = append(relation.Genres, r.genres.refs(r.movieGenres[id])...)
+		}
+		if expand.Credits || expand.CreditsPerson {
+			var people map[uint]*models.PersonResponse
+			if expand.CreditsPerson {
+				people = r.people.responses(creditPeople(r.credits[id]))
+			}
+			for _, credit := range r.creditResponses(id) {
+				relation.Credits = append(relation.Credits, models.ExpandedCredit{CreditResponse: credit, Person: people[credit.PersonID]})
+			}
+		}
+		if expand.Ratings {
+			relation.Ratings = ratingSummary(r.ratings[id])
+		}
+	}
+	return relations, nil
+}
+
+func (r *MemoryMovieRepository) GetCredits(movieID uint) ([]models.CreditResponse, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.creditResponses(movieID), nil
+}
+
+// creditResponses lists the movie's credits in billing order.
+func (r *MemoryMovieRepository) creditResponses(movieID uint) []models.CreditResponse {
+	stored := r.credits[movieID]
+	names := r.people.names(creditPeople(stored))
+	credits := make([]models.CreditResponse, 0, len(stored))
+	for _, credit := range stored {
+		credits = append(credits, models.CreditResponse{
+			PersonID:  credit.PersonID,
+			Name:      names[credit.PersonID],
+			Role:      credit.Role,
+			Character: credit.Character,
+			Billing:   credit.Billing,
+		})
+	}
+	sort.SliceStable(credits, func(i, j int) bool {
+		if credits[i].Billing != credits[j].Billing {
+			return credits[i].Billing < credits[j].Billing
+		}
+		return credits[i].Role < credits[j].Role
+	})
+	return credits
+}
+
+func (r *MemoryMovieRepository) ReplaceCredits(request *models.ReplaceCreditsRequest, record RecordRevision) (*models.MovieResponse, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, err := r.activeVersion(request.ID, request.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	credits := sortedCredits(request.Credits)
+	ids := make([]uint, 0, len(credits))
+	for _, credit := range credits {
+		ids = append(ids, credit.PersonID)
+	}
+	names := r.people.names(ids)
+	if missing := missingPeople(ids, names); len(missing) > 0 {
+		return nil, unknownPeople(missing)
+	}
+	director, err := directorOf(credits, names)
+	if err != nil {
+		return nil, err
+	}
+
+	// Mirror the unique index on (movie_id, person_id, role).
+	type creditKey struct {
+		personID uint
+		role     string
+	}
+	seen := make(map[creditKey]bool, len(credits))
+	rows := make([]models.MovieCredit, 0, len(credits))
+	for _, credit := range credits {
+		key := creditKey{credit.PersonID, credit.Role}
+		if seen[key] {
+			return nil, gorm.ErrDuplicatedKey
+		}
+		seen[key] = true
+		rows = append(rows, models.MovieCredit{
+			MovieID:   request.ID,
+			PersonID:  credit.PersonID,
+			Role:      credit.Role,
+			Character: credit.Character,
+			Billing:   credit.Billing,
+		})
+	}
+
+	saved, before := r.save(request.ID), r.response(stored)
+	r.credits[request.ID] = rows
+	if director != "" {
+		stored.Director = director
+	}
+	stored.Version++
+	stored.UpdatedAt = time.Now()
+
+	updated := r.response(stored)
+	if err := r.commit(saved, record(before, updated)); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *MemoryMovieRepository) GetFilmography(personID uint) ([]models.FilmographyEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entries := []models.FilmographyEntry{}
+	for movieID, credits := range r.credits {
+		movie, ok := r.active(movieID)
+		if !ok {
+			continue
+		}
+		for _, credit := range credits {
+			if credit.PersonID == personID {
+				entries = append(entries, models.FilmographyEntry{
+					MovieID:   movie.ID,
+					Title:     movie.Title,
+					Year:      movie.Year,
+					Role:      credit.Role,
+					Character: credit.Character,
+					Billing:   credit.Billing,
+				})
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Year != b.Year {
+			return a.Year > b.Year
+		}
+		if a.Title != b.Title {
+			return a.Title < b.Title
+		}
+		return a.Role < b.Role
+	})
+	return entries, nil
+}
+
+func (r *MemoryMovieRepository) CountCredits(personID uint) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, credits := range r.credits {
+		for _, credit := range credits {
+			if credit.PersonID == personID {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
+
+// exists reports whether the movie exists, in the trash or not.
+func (r *MemoryMovieRepository) exists(id uint) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, ok := r.movies[id]
+	return ok
+}
+
+// activeMovies returns copies of the movies among ids that are outside the trash.
+func (r *MemoryMovieRepository) activeMovies(ids []uint) map[uint]models.Movie {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	movies := make(map[uint]models.Movie, len(ids))
+	for _, id := range ids {
+		if movie, ok := r.active(id); ok {
+			movies[id] = *movie
+		}
+	}
+	return movies
+}
+
+// setRating stores the review counts per rating of a movie and their aggregates, like the
+// Postgres review repository does in the movies table.
+func (r *MemoryMovieRepository) setRating(id uint, distribution map[int]int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if movie, ok := r.movies[id]; ok {
+		summary := ratingSummary(distribution)
+		movie.RatingAverage = summary.Average
+		movie.RatingCount = summary.Count
+		r.ratings[id] = distribution
+	}
+}
+
+// setDirector keeps the movie's director credits in line with its director field, like
+// the Postgres repository's setDirector.
+func (r *MemoryMovieRepository) setDirector(movieID uint, name string) {
+	credits := r.credits[movieID]
+	names := r.people.names(creditPeople(credits))
+	var current []string
+	kept := make([]models.MovieCredit, 0, len(credits)+1)
+	for _, credit := range credits {
+		if credit.Role == models.CreditDirector {
+			current = append(current, names[credit.PersonID])
+		} else {
+			kept = append(kept, credit)
+		}
+	}
+	if strings.Join(current, directorSeparator) == name {
+		return
+	}
+
+	var directors []models.MovieCredit
+	for _, director := range directorNames(name) {
+		directors = append(directors, models.MovieCredit{MovieID: movieID, PersonID: r.people.findOrCreate(director), Role: models.CreditDirector})
+	}
+	r.credits[movieID] = append(directors, kept...)
+}
+
+func creditPeople(credits []models.MovieCredit) []uint {
+	ids := make([]uint, 0, len(credits))
+	for _, credit := range credits {
+		ids = append(ids, credit.PersonID)
+	}
+	return ids
+}
+
+// response converts a stored movie, resolving its genre names.
+func (r *MemoryMovieRepository) response(movie *models.Movie) *models.MovieResponse {
+	response := toMovieResponse(movie)
+	response.Genres = r.genres.names(r.movieGenres[movie.ID])
+	return response
+}
+
+func (r *MemoryMovieRepository) active(id uint) (*models.Movie, bool) {
+	movie, ok := r.movies[id]
+	if !ok || movie.DeletedAt.Valid {
+		return nil, false
+	}
+	return movie, true
+}
+
+// activeVersion returns the movie if it exists and, unless version is zero, is at that version.
+func (r *MemoryMovieRepository) activeVersion(id, version uint) (*models.Movie, error) {
+	movie, ok := r.active(id)
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	if version > 0 && movie.Version != version {
+		return nil, ErrVersionConflict
+	}
+	return movie, nil
+}
+
+// titleTaken mirrors the partial unique index on movies.title, which ignores the trash.
+func (r *MemoryMovieRepository) titleTaken(title string, exceptID uint) bool {
+	for id, movie := range r.movies {
+		if id != exceptID && movie.Title == title && !movie.DeletedAt.Valid {
+			return true
+		}
+	}
+	return false
+}
+
+// movieComparator orders movies by the columns, then by ascending ID.
+func movieComparator(order []orderColumn) func(a, b *models.Movie) int {
+	return func(a, b *models.Movie) int {
+		for _, o := range order {
+			if c := compareMovies(a, b, o.column); c != 0 {
+				if o.desc {
+					return -c
+				}
+				return c
+			}
+		}
+		return cmp.Compare(a.ID, b.ID)
+	}
+}
+
+// filterValue returns the movie's value of a field of MovieFilterFields.
+func filterValue(movie *models.Movie, field string) interface{} {
+	switch field {
+	case "title":
+		return movie.Title
+	case "director":
+		return movie.Director
+	case "plot":
+		return movie.Plot
+	case "year":
+		return movie.Year
+	case "rating":
+		return movie.RatingAverage
+	case "rating_count":
+		return movie.RatingCount
+	case "created_at":
+		return movie.CreatedAt
+	case "updated_at":
+		return movie.UpdatedAt
+	default:
+		return nil
+	}
+}
+
+func compareMovies(a, b *models.Movie, column string) int {
+	switch column {
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	case "director":
+		return strings.Compare(a.Director, b.Director)
+	case "year":
+		return a.Year - b.Year
+	case "rating_average":
+		return cmp.Compare(a.RatingAverage, b.RatingAverage)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	default:
+		return int(a.ID) - int(b.ID)
+	}
+}
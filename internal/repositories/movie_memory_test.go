@@ -0,0 +1,250 @@
+package repositories
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"itv-task/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// recordVersion numbers revisions like the movie service does.
+func recordVersion(before, after *models.MovieResponse) *models.MovieRevision {
+	if after == nil {
+		return &models.MovieRevision{MovieID: before.ID, Revision: before.Version + 1}
+	}
+	return &models.MovieRevision{MovieID: after.ID, Revision: after.Version}
+}
+
+func newTestMovieRepository(t *testing.T) (*MemoryMovieRepository, *MemoryMovieRevisionRepository) {
+	t.Helper()
+	revisions := NewMemoryMovieRevisionRepository()
+	return NewMemoryMovieRepository(NewMemoryGenreRepository(), NewMemoryPersonRepository(), revisions), revisions
+}
+
+// takeRevision makes the revision a later write would record already exist, so that write fails.
+func takeRevision(t *testing.T, revisions *MemoryMovieRevisionRepository, movieID, revision uint) {
+	t.Helper()
+	if err := revisions.Create(&models.MovieRevision{MovieID: movieID, Revision: revision}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMemoryMovieRepositoryRecordsRevisions(t *testing.T) {
+	repo, revisions := newTestMovieRepository(t)
+
+	created, err := repo.Create(&models.CreateMovieRequest{Title: "Heat", Director: "Michael Mann", Year: 1995}, recordVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Update(&models.UpdateMovieRequest{ID: created.ID, Title: "Heat", Director: "Michael Mann", Year: 1996}, recordVersion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Delete(created.ID, 0, recordVersion); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.HardDelete(created.ID, recordVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	history, total, err := revisions.GetByMovie(created.ID, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 4 || history[0].Revision != 4 || history[3].Revision != 1 {
+		t.Fatalf("expected revisions 4 to 1, got %+v", history)
+	}
+}
+
+func TestMemoryMovieRepositoryRollsBackWithoutRevision(t *testing.T) {
+	repo, revisions := newTestMovieRepository(t)
+
+	movie, err := repo.Create(&models.CreateMovieRequest{Title: "Heat", Director: "Michael Mann", Year: 1995}, recordVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	takeRevision(t, revisions, movie.ID, 2)
+
+	expectUnchanged := func(t *testing.T) {
+		t.Helper()
+		current, err := repo.GetByID(movie.ID)
+		if err != nil {
+			t.Fatalf("movie is gone after a failed write: %v", err)
+		}
+		if current.Version != 1 || current.Year != 1995 || current.Director != "Michael Mann" {
+			t.Fatalf("failed write changed the movie: %+v", current)
+		}
+		credits, _ := repo.GetCredits(movie.ID)
+		if len(credits) != 1 || credits[0].Name != "Michael Mann" {
+			t.Fatalf("failed write changed the credits: %+v", credits)
+		}
+	}
+
+	t.Run("update", func(t *testing.T) {
+		_, err := repo.Update(&models.UpdateMovieRequest{ID: movie.ID, Title: "Heat", Director: "Someone Else", Year: 1996}, recordVersion)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("expected the revision conflict, got %v", err)
+		}
+		expectUnchanged(t)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		if _, err := repo.Delete(movie.ID, 1, recordVersion); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("expected the revision conflict, got %v", err)
+		}
+		expectUnchanged(t)
+	})
+
+	t.Run("hard delete", func(t *testing.T) {
+		if _, err := repo.HardDelete(movie.ID, recordVersion); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("expected the revision conflict, got %v", err)
+		}
+		expectUnchanged(t)
+	})
+
+	t.Run("create", func(t *testing.T) {
+		takeRevision(t, revisions, movie.ID+1, 1)
+		if _, err := repo.Create(&models.CreateMovieRequest{Title: "Thief", Year: 1981}, recordVersion); !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("expected the revision conflict, got %v", err)
+		}
+		if _, err := repo.GetByTitle("Thief"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Fatalf("failed create left the movie behind: %v", err)
+		}
+	})
+
+	t.Run("bulk insert", func(t *testing.T) {
+		takeRevision(t, revisions, movie.ID+3, 1)
+		_, err := repo.BulkInsertMovies(&models.BulkInsertMoviesRequest{Movies: []models.CreateMovieRequest{
+			{Title: "Collateral", Year: 2004},
+			{Title: "Ali", Year: 2001},
+		}}, recordVersion)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Fatalf("expected the revision conflict, got %v", err)
+		}
+		for _, title := range []string{"Collateral", "Ali"} {
+			if _, err := repo.GetByTitle(title); !errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Fatalf("failed bulk insert left %q behind: %v", title, err)
+			}
+		}
+	})
+}
+
+func TestMemoryMovieRepositoryPurgeRollsBackWithoutRevision(t *testing.T) {
+	repo, revisions := newTestMovieRepository(t)
+
+	var ids []uint
+	for _, title := range []string{"Heat", "Thief"} {
+		movie, err := repo.Create(&models.CreateMovieRequest{Title: title}, recordVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.Delete(movie.ID, 0, recordVersion); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, movie.ID)
+	}
+	takeRevision(t, revisions, ids[1], 3)
+
+	if _, err := repo.PurgeDeleted(time.Now().Add(time.Minute), recordVersion); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Fatalf("expected the revision conflict, got %v", err)
+	}
+	trash, err := repo.GetDeleted(0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *trash.Count != 2 {
+		t.Fatalf("failed purge removed movies from the trash: %+v", trash.Movies)
+	}
+	if history, _, _ := revisions.GetByMovie(ids[0], 0, 0); len(history) != 2 {
+		t.Fatalf("failed purge recorded a revision: %+v", history)
+	}
+}
+
+func TestMemoryMovieRepositoryKeepsDirectorInStepWithCredits(t *testing.T) {
+	people := NewMemoryPersonRepository()
+	repo := NewMemoryMovieRepository(NewMemoryGenreRepository(), people, NewMemoryMovieRevisionRepository())
+
+	movie, err := repo.Create(&models.CreateMovieRequest{Title: "Heat", Director: "Michael Mann", Year: 1995}, recordVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	for _, name := range []string{"Lana Wachowski", "Lilly Wachowski", "Keanu Reeves"} {
+		person, err := people.Create(&models.PersonRequest{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, person.ID)
+	}
+	expectDirectors := func(t *testing.T, director string, names ...string) {
+		t.Helper()
+		current, err := repo.GetByID(movie.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Director != director {
+			t.Fatalf("expected director %q, got %q", director, current.Director)
+		}
+		credits, _ := repo.GetCredits(movie.ID)
+		var directors []string
+		for _, credit := range credits {
+			if credit.Role == models.CreditDirector {
+				directors = append(directors, credit.Name)
+			}
+		}
+		if strings.Join(directors, "|") != strings.Join(names, "|") {
+			t.Fatalf("expected director credits %q, got %q", names, directors)
+		}
+	}
+
+	_, err = repo.ReplaceCredits(&models.ReplaceCreditsRequest{ID: movie.ID, Credits: []models.CreditRequest{
+		{PersonID: ids[0], Role: models.CreditDirector},
+		{PersonID: ids[1], Role: models.CreditDirector, Billing: 1},
+		{PersonID: ids[2], Role: models.CreditActor, Billing: 2},
+	}}, recordVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectDirectors(t, "Lana Wachowski, Lilly Wachowski", "Lana Wachowski", "Lilly Wachowski")
+
+	t.Run("joined names written back", func(t *testing.T) {
+		director := "Lilly Wachowski, Lana Wachowski"
+		if _, err := repo.Patch(&models.PatchMovieRequest{ID: movie.ID, Director: &director}, recordVersion); err != nil {
+			t.Fatal(err)
+		}
+		expectDirectors(t, director, "Lilly Wachowski", "Lana Wachowski")
+		if found, _, _ := people.GetAll(",", 0, 0); len(found) > 0 {
+			t.Fatalf("the joined names became a person: %+v", found)
+		}
+	})
+
+	t.Run("credits without a director", func(t *testing.T) {
+		_, err := repo.ReplaceCredits(&models.ReplaceCreditsRequest{ID: movie.ID, Credits: []models.CreditRequest{
+			{PersonID: ids[2], Role: models.CreditActor},
+		}}, recordVersion)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectDirectors(t, "Lilly Wachowski, Lana Wachowski")
+	})
+
+	t.Run("names too long", func(t *testing.T) {
+		var credits []models.CreditRequest
+		for i := 0; i < 20; i++ {
+			person, err := people.Create(&models.PersonRequest{Name: strings.Repeat("x", 20) + strconv.Itoa(i)})
+			if err != nil {
+				t.Fatal(err)
+			}
+			credits = append(credits, models.CreditRequest{PersonID: person.ID, Role: models.CreditDirector, Billing: i})
+		}
+		_, err := repo.ReplaceCredits(&models.ReplaceCreditsRequest{ID: movie.ID, Credits: credits}, recordVersion)
+		if !errors.Is(err, ErrDirectorTooLong) {
+			t.Fatalf("expected ErrDirectorTooLong, got %v", err)
+		}
+		expectDirectors(t, "Lilly Wachowski, Lana Wachowski")
+	})
+}
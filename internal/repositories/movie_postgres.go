@@ -0,0 +1,1134 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"itv-task/internal/models"
+	"itv-task/pkg/filterql"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PostgresMovieRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMovieRepository(db *gorm.DB) *PostgresMovieRepository {
+	return &PostgresMovieRepository{db: db}
+}
+
+func (r *PostgresMovieRepository) Create(movie *models.CreateMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	var created *models.MovieResponse
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if created, err = r.insert(tx, movie); err != nil {
+			return err
+		}
+		return writeRevision(tx, record, nil, created)
+	})
+	if err != nil {
+		log.Println("❌ Failed to create movie:", err)
+		return nil, err
+	}
+	return created, nil
+}
+
+// insert creates the movie along with its genre links.
+func (r *PostgresMovieRepository) insert(tx *gorm.DB, movie *models.CreateMovieRequest) (*models.MovieResponse, error) {
+	genreIDs, err := resolveGenres(tx, movie.Genres)
+	if err != nil {
+		return nil, err
+	}
+
+	gormModel := models.Movie{
+		Title:    movie.Title,
+		Director: movie.Director,
+		Year:     movie.Year,
+		Plot:     movie.Plot,
+	}
+	if err := tx.Table("movies").Create(&gormModel).Error; err != nil {
+		return nil, err
+	}
+	if err := replaceGenres(tx, gormModel.ID, genreIDs); err != nil {
+		return nil, err
+	}
+	if err := setDirector(tx, gormModel.ID, gormModel.Director); err != nil {
+		return nil, err
+	}
+
+	created := toMovieResponse(&gormModel)
+	return created, loadGenres(tx, created)
+}
+
+func (r *PostgresMovieRepository) GetByID(id uint) (*models.MovieResponse, error) {
+	var movie models.MovieResponse
+	if err := r.db.Table("movies").First(&movie, "id = ? AND deleted_at IS NULL ", id).Error; err != nil {
+		log.Println("❌ Movie not found:", err)
+		return nil, err
+	}
+	return &movie, loadGenres(r.db, &movie)
+}
+
+func (r *PostgresMovieRepository) GetByIDFields(id uint, fields []string) (*models.MovieResponse, error) {
+	var movie models.MovieResponse
+	query := r.db.Table("movies")
+	if columns := selectColumns(fields, "id", "version"); columns != nil {
+		query = query.Select(columns)
+	}
+	if err := query.First(&movie, "id = ? AND deleted_at IS NULL ", id).Error; err != nil {
+		log.Println("❌ Movie not found:", err)
+		return nil, err
+	}
+	if !loadsGenres(fields) {
+		return &movie, nil
+	}
+	return &movie, loadGenres(r.db, &movie)
+}
+
+func (r *PostgresMovieRepository) GetByTitle(title string) (*models.MovieResponse, error) {
+	var movie models.MovieResponse
+	if err := r.db.Table("movies").First(&movie, "title = ? AND deleted_at IS NULL ", title).Error; err != nil {
+		log.Println("❌ Movie not found:", err)
+		return nil, err
+	}
+	return &movie, loadGenres(r.db, &movie)
+}
+
+func (r *PostgresMovieRepository) GetAll(filter models.MovieFilter) (models.MovieListResponse, error) {
+	var movies []models.MovieResponse
+	var response models.MovieListResponse
+	query := r.filtered(filter)
+
+	// Get total count before applying the cursor, limit & offset
+	if !filter.SkipTotal {
+		var totalCount int64
+		if err := query.Count(&totalCount).Error; err != nil {
+			log.Println("❌ Failed to count movies:", err)
+			return models.MovieListResponse{}, err
+		}
+		count := int(totalCount)
+		response.Count = &count
+	}
+
+	order := movieOrder(filter.Sort)
+	backward := filter.Cursor != nil && filter.Cursor.Backward
+	if filter.Cursor != nil {
+		position, err := cursorMovie(filter.Cursor, order)
+		if err != nil {
+			return models.MovieListResponse{}, err
+		}
+		condition, args := keysetCondition(order, backward, sortValues(position, order), position.ID)
+		query = query.Where(condition, args...)
+	}
+
+	// Backward pages are read in reverse, from the cursor towards the start, and flipped below
+	for _, clause := range orderClauses(order, backward) {
+		query = query.Order(clause)
+	}
+	// Sort columns are loaded regardless of the fields, for the cursors
+	required := []string{"id"}
+	for _, o := range order {
+		required = append(required, o.column)
+	}
+	if columns := selectColumns(filter.Fields, required...); columns != nil {
+		query = query.Select(columns)
+	}
+
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit + 1) // One more tells whether another page follows
+	}
+	query = query.Offset(filter.Offset)
+
+	if err := query.Find(&movies).Error; err != nil {
+		log.Println("❌ Failed to retrieve movies:", err)
+		return models.MovieListResponse{}, err
+	}
+	more := filter.Limit > 0 && len(movies) > filter.Limit
+	if more {
+		movies = movies[:filter.Limit]
+	}
+	if backward {
+		slices.Reverse(movies)
+	}
+	if loadsGenres(filter.Fields) {
+		if err := loadGenres(r.db, moviePointers(movies)...); err != nil {
+			return models.MovieListResponse{}, err
+		}
+	}
+
+	response.Movies = movies
+	response.Next, response.Prev = pageCursors(filter, movies, more, order)
+	return response, nil
+}
+
+// filtered returns a query over the movies outside the trash that match the filter.
+func (r *PostgresMovieRepository) filtered(filter models.MovieFilter) *gorm.DB {
+	query := r.db.Model(&models.Movie{})
+
+	// Apply filters
+	if filter.Title != "" {
+		query = query.Where("title ILIKE ?", "%"+filter.Title+"%")
+	}
+	if filter.Director != "" {
+		query = query.Where("director ILIKE ?", "%"+filter.Director+"%")
+	}
+	if filter.Year > 0 {
+		query = query.Where("year = ?", filter.Year)
+	}
+	if filter.MinRating > 0 {
+		query = query.Where("rating_average >= ?", filter.MinRating)
+	}
+	if filter.Expression != nil {
+		query = query.Where(filterql.SQL(filter.Expression))
+	}
+	if len(filter.Genres) > 0 {
+		withGenres := r.db.Table("movie_genres").
+			Select("movie_genres.movie_id").
+			Joins("JOIN genres ON genres.id = movie_genres.genre_id").
+			Where("genres.slug IN ?", filter.Genres)
+		if filter.GenreMatch == models.GenreMatchAll {
+			withGenres = withGenres.Group("movie_genres.movie_id").
+				Having("COUNT(DISTINCT genres.id) = ?", len(filter.Genres))
+		}
+		query = query.Where("movies.id IN (?)", withGenres)
+	}
+	return query
+}
+
+// searchHeadlineOptions configures the plot snippets of search results.
+const searchHeadlineOptions = "StartSel=<mark>, StopSel=</mark>, MinWords=15, MaxWords=35, MaxFragments=2, FragmentDelimiter=\" ... \""
+
+func (r *PostgresMovieRepository) Search(query string, filter models.MovieFilter) (models.MovieSearchResponse, error) {
+	results := []models.MovieSearchResult{}
+	var totalCount int64
+	tsquery := gorm.Expr("websearch_to_tsquery('english', ?)", query)
+	matches := r.filtered(filter).Where("search_vector @@ ?", tsquery)
+
+	if err := matches.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count search results:", err)
+		return models.MovieSearchResponse{}, err
+	}
+
+	matches = matches.Select("movies.*, ts_rank(search_vector, ?) AS rank, ts_headline('english', plot, ?, ?) AS headline",
+		tsquery, tsquery, searchHeadlineOptions)
+	if len(filter.Sort) == 0 {
+		matches = matches.Order("rank DESC")
+	}
+	for _, clause := range orderClauses(movieOrder(filter.Sort), false) {
+		matches = matches.Order(clause)
+	}
+
+	if filter.Limit > 0 {
+		matches = matches.Limit(filter.Limit)
+	}
+	if err := matches.Offset(filter.Offset).Find(&results).Error; err != nil {
+		log.Println("❌ Failed to search movies:", err)
+		return models.MovieSearchResponse{}, err
+	}
+
+	movies := make([]*models.MovieResponse, len(results))
+	for i := range results {
+		movies[i] = &results[i].MovieResponse
+	}
+	if err := loadGenres(r.db, movies...); err != nil {
+		return models.MovieSearchResponse{}, err
+	}
+	return models.MovieSearchResponse{Results: results, Count: int(totalCount)}, nil
+}
+
+func (r *PostgresMovieRepository) FindSimilar(title string, threshold float64, limit int) ([]models.SimilarMovie, error) {
+	movies := []models.SimilarMovie{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := setSimilarityThreshold(tx, threshold); err != nil {
+			return err
+		}
+		return tx.Model(&models.Movie{}).
+			Select("id, title, director, year, similarity(title, ?) AS score", title).
+			Where("title % ?", title).
+			Order("score DESC").Order("id ASC").
+			Limit(limit).
+			Scan(&movies).Error
+	})
+	if err != nil {
+		log.Println("❌ Failed to find similar movies:", err)
+		return nil, err
+	}
+	return movies, nil
+}
+
+func (r *PostgresMovieRepository) DuplicateClusters(threshold float64, limit, offset int) ([]models.DuplicateCluster, int, error) {
+	var rows []struct {
+		FirstID        uint
+		FirstTitle     string
+		FirstDirector  string
+		FirstYear      int
+		SecondID       uint
+		SecondTitle    string
+		SecondDirector string
+		SecondYear     int
+		Score          float64
+	}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := setSimilarityThreshold(tx, threshold); err != nil {
+			return err
+		}
+		return tx.Raw(`SELECT a.id AS first_id, a.title AS first_title, a.director AS first_director, a.year AS first_year,
+				b.id AS second_id, b.title AS second_title, b.director AS second_director, b.year AS second_year,
+				similarity(a.title, b.title) AS score
+			FROM movies a JOIN movies b ON a.id < b.id AND a.title % b.title
+			WHERE a.deleted_at IS NULL AND b.deleted_at IS NULL`).Scan(&rows).Error
+	})
+	if err != nil {
+		log.Println("❌ Failed to find duplicate movies:", err)
+		return nil, 0, err
+	}
+
+	pairs := make([]similarPair, len(rows))
+	for i, row := range rows {
+		pairs[i] = similarPair{
+			first:  models.SimilarMovie{ID: row.FirstID, Title: row.FirstTitle, Director: row.FirstDirector, Year: row.FirstYear},
+			second: models.SimilarMovie{ID: row.SecondID, Title: row.SecondTitle, Director: row.SecondDirector, Year: row.SecondYear},
+			score:  row.Score,
+		}
+	}
+	clusters, total := clusterPairs(pairs, limit, offset)
+	return clusters, total, nil
+}
+
+// setSimilarityThreshold sets the similarity from which the % operator matches, for the
+// rest of the transaction. SET takes no parameters, so the float is formatted in.
+func setSimilarityThreshold(tx *gorm.DB, threshold float64) error {
+	return tx.Exec(fmt.Sprintf("SET LOCAL pg_trgm.similarity_threshold = %g", threshold)).Error
+}
+
+// Values GROUPING reports for the grouping sets of the facets query, in which a set bit
+// marks a column the row is not grouped by.
+const (
+	facetByDecade   = 0b01111
+	facetByYear     = 0b10111
+	facetByDirector = 0b11011
+	facetByGenre    = 0b11101
+	facetByRating   = 0b11110
+	facetTotal      = 0b11111
+)
+
+func (r *PostgresMovieRepository) Facets(filter models.MovieFilter) (models.MovieFacetsResponse, error) {
+	var rows []struct {
+		Grouping  int
+		Decade    int
+		Year      int
+		Director  string
+		GenreSlug string
+		GenreName string
+		Rating    int
+		Count     int
+	}
+	matching := r.filtered(filter).Select("movies.id, movies.year, movies.director, movies.rating_average, movies.rating_count")
+	// Movies repeat once per genre in f, so every set counts distinct IDs
+	err := r.db.Raw(`SELECT GROUPING(f.decade, f.year, f.director, f.genre_slug, f.rating) AS grouping,
+			COALESCE(f.decade, 0) AS decade, COALESCE(f.year, 0) AS year, COALESCE(f.director, '') AS director,
+			COALESCE(f.genre_slug, '') AS genre_slug, COALESCE(MIN(f.genre_name), '') AS genre_name,
+			COALESCE(f.rating, 0) AS rating, COUNT(DISTINCT f.id) AS count
+		FROM (SELECT m.id, m.year / 10 * 10 AS decade, m.year, m.director, g.slug AS genre_slug, g.name AS genre_name,
+				CASE WHEN m.rating_count > 0 THEN FLOOR(m.rating_average)::int END AS rating
+			FROM (?) m
+			LEFT JOIN movie_genres mg ON mg.movie_id = m.id
+			LEFT JOIN genres g ON g.id = mg.genre_id) f
+		GROUP BY GROUPING SETS ((f.decade), (f.year), (f.director), (f.genre_slug), (f.rating), ())`, matching).
+		Scan(&rows).Error
+	if err != nil {
+		log.Println("❌ Failed to count movie facets:", err)
+		return models.MovieFacetsResponse{}, err
+	}
+
+	facets := emptyFacets()
+	for _, row := range rows {
+		switch row.Grouping {
+		case facetByDecade:
+			facets.Decades = append(facets.Decades, decadeFacet(row.Decade, row.Count))
+		case facetByYear:
+			facets.Years = append(facets.Years, models.FacetValue{Value: strconv.Itoa(row.Year), Count: row.Count})
+		case facetByDirector:
+			facets.Directors = append(facets.Directors, models.FacetValue{Value: row.Director, Count: row.Count})
+		case facetByGenre:
+			if row.GenreSlug != "" { // Movies without genres
+				facets.Genres = append(facets.Genres, models.FacetValue{Value: row.GenreSlug, Label: row.GenreName, Count: row.Count})
+			}
+		case facetByRating:
+			if row.Rating > 0 { // Unrated movies
+				facets.Ratings = append(facets.Ratings, models.FacetValue{Value: strconv.Itoa(row.Rating), Count: row.Count})
+			}
+		case facetTotal:
+			facets.Count = row.Count
+		}
+	}
+	return facets, nil
+}
+
+func (r *PostgresMovieRepository) GenreCounts(filter models.MovieFilter) (map[uint]int, error) {
+	var rows []struct {
+		GenreID uint
+		Count   int
+	}
+	err := r.db.Table("movie_genres").
+		Select("genre_id, COUNT(*) AS count").
+		Where("movie_id IN (?)", r.filtered(filter).Select("movies.id")).
+		Group("genre_id").
+		Scan(&rows).Error
+	if err != nil {
+		log.Println("❌ Failed to count movies by genre:", err)
+		return nil, err
+	}
+
+	counts := make(map[uint]int, len(rows))
+	for _, row := range rows {
+		counts[row.GenreID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *PostgresMovieRepository) Update(movie *models.UpdateMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	// A map keeps zero values from being skipped and Model scopes out soft-deleted rows.
+	return r.updateFields(movie.ID, movie.Version, map[string]interface{}{
+		"title":    movie.Title,
+		"director": movie.Director,
+		"year":     movie.Year,
+		"plot":     movie.Plot,
+	}, movie.Genres, record)
+}
+
+func (r *PostgresMovieRepository) Patch(movie *models.PatchMovieRequest, record RecordRevision) (*models.MovieResponse, error) {
+	fields := map[string]interface{}{}
+	if movie.Title != nil {
+		fields["title"] = *movie.Title
+	}
+	if movie.Director != nil {
+		fields["director"] = *movie.Director
+	}
+	if movie.Year != nil {
+		fields["year"] = *movie.Year
+	}
+	if movie.Plot != nil {
+		fields["plot"] = *movie.Plot
+	}
+
+	var genres []string
+	if movie.Genres != nil {
+		if genres = *movie.Genres; genres == nil {
+			genres = []string{} // Clears the genres rather than keeping them
+		}
+	}
+	return r.updateFields(movie.ID, movie.Version, fields, genres, record)
+}
+
+// Delete soft deletes by hand rather than through gorm's Delete so the version is bumped
+// in the same statement.
+func (r *PostgresMovieRepository) Delete(id, version uint, record RecordRevision) (*models.MovieResponse, error) {
+	return r.updateFields(id, version, map[string]interface{}{"deleted_at": time.Now()}, nil, record)
+}
+
+// updateFields is a compare-and-swap on the version column: the row only changes if it
+// still has the expected version, and the version is bumped in the same statement.
+// RETURNING hands back the row exactly as written. A non-nil genres replaces the movie's
+// genres in the same transaction, and a changed director is carried over to its credits.
+func (r *PostgresMovieRepository) updateFields(id, version uint, fields map[string]interface{}, genres []string, record RecordRevision) (*models.MovieResponse, error) {
+	var updated *models.MovieResponse
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var genreIDs []uint
+		if genres != nil {
+			var err error
+			if genreIDs, err = resolveGenres(tx, genres); err != nil {
+				return err
+			}
+		}
+
+		before, err := lockSnapshot(tx, id, false)
+		if err != nil {
+			return err
+		}
+		var movie models.Movie
+		if err := r.update(tx, &movie, id, version, fields); err != nil {
+			return err
+		}
+
+		if genres != nil {
+			if err := replaceGenres(tx, id, genreIDs); err != nil {
+				return err
+			}
+		}
+		if _, ok := fields["director"]; ok {
+			if err := setDirector(tx, id, movie.Director); err != nil {
+				return err
+			}
+		}
+
+		updated = toMovieResponse(&movie)
+		if err := loadGenres(tx, updated); err != nil {
+			return err
+		}
+		return writeRevision(tx, record, before, updated)
+	})
+	if err != nil {
+		log.Println("❌ Failed to update movie:", err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// lockSnapshot reads the movie, in the trash or out of it, as it is before a change and
+// locks its row until the change commits, so the snapshot is the one the change replaces.
+func lockSnapshot(tx *gorm.DB, id uint, deleted bool) (*models.MovieResponse, error) {
+	var movie models.Movie
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"})
+	if deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if err := query.Take(&movie, id).Error; err != nil {
+		return nil, err
+	}
+	locked := toMovieResponse(&movie)
+	return locked, loadGenres(tx, locked)
+}
+
+// writeRevision stores the revision of a change in the change's transaction.
+func writeRevision(tx *gorm.DB, record RecordRevision, before, after *models.MovieResponse) error {
+	return tx.Create(record(before, after)).Error
+}
+
+// update writes the fields into the movie at the expected version, bumping it, and reads
+// the row back into movie.
+func (r *PostgresMovieRepository) update(tx *gorm.DB, movie *models.Movie, id, version uint, fields map[string]interface{}) error {
+	fields["version"] = gorm.Expr("version + 1")
+
+	query := tx.Model(movie).Clauses(clause.Returning{}).Where("id = ?", id)
+	if version > 0 {
+		query = query.Where("version = ?", version)
+	}
+	result := query.Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.missingOrConflict(tx, id)
+	}
+	return nil
+}
+
+// missingOrConflict explains why a conditional write matched no row.
+func (r *PostgresMovieRepository) missingOrConflict(tx *gorm.DB, id uint) error {
+	var count int64
+	if err := tx.Model(&models.Movie{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		log.Println("❌ Failed to check movie:", err)
+		return err
+	}
+	if count == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return ErrVersionConflict
+}
+
+func (s *PostgresMovieRepository) BulkInsertMovies(movies *models.BulkInsertMoviesRequest, record RecordRevision) ([]models.MovieResponse, error) {
+	tx := s.db.Begin() // Start transaction
+	if tx.Error != nil {
+		log.Println("❌ Failed to start transaction:", tx.Error)
+		return nil, tx.Error
+	}
+	defer func() {
+		tx.Rollback()
+	}()
+
+	created := make([]models.MovieResponse, 0, len(movies.Movies))
+	for i := range movies.Movies {
+		movie, err := s.insert(tx, &movies.Movies[i])
+		if err == nil {
+			err = writeRevision(tx, record, nil, movie)
+		}
+		if err != nil {
+			tx.Rollback() // Rollback on failure
+			log.Println("❌ Failed to bulk insert movies:", err)
+			return nil, err
+		}
+		created = append(created, *movie)
+	}
+
+	// Commit transaction
+	if err := tx.Commit().Error; err != nil {
+		log.Println("❌ Failed to commit transaction:", err)
+		return nil, err
+	}
+
+	return created, nil
+}
+
+// errImportRolledBack rolls back the transaction of an import that must not be written.
+var errImportRolledBack = errors.New("import rolled back")
+
+// ImportMovies writes each movie under a savepoint, so that a failed one is undone
+// without aborting the transaction.
+func (r *PostgresMovieRepository) ImportMovies(movies []models.CreateMovieRequest, upsert, atomic, dryRun bool, record RecordRevision) ([]ImportOutcome, error) {
+	outcomes := make([]ImportOutcome, len(movies))
+	failed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i := range movies {
+			if err := tx.SavePoint("import_movie").Error; err != nil {
+				return err
+			}
+			outcomes[i] = r.importMovie(tx, &movies[i], upsert)
+			if outcomes[i].Err == nil {
+				outcomes[i].Err = writeRevision(tx, record, outcomes[i].Before, outcomes[i].Movie)
+			}
+			if outcomes[i].Err != nil {
+				failed = true
+				outcomes[i].Movie, outcomes[i].Before = nil, nil
+				if err := tx.RollbackTo("import_movie").Error; err != nil {
+					return err
+				}
+			}
+		}
+		if dryRun || (atomic && failed) {
+			return errImportRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, errImportRolledBack) {
+		for i := range outcomes {
+			outcomes[i].Movie, outcomes[i].Before = nil, nil
+		}
+		return outcomes, nil
+	}
+	if err != nil {
+		log.Println("❌ Failed to import movies:", err)
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// importMovie creates the movie or, with upsert, updates the movie with its title.
+func (r *PostgresMovieRepository) importMovie(tx *gorm.DB, movie *models.CreateMovieRequest, upsert bool) ImportOutcome {
+	var existing models.Movie
+	err := tx.Where("title = ?", movie.Title).Take(&existing).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		created, err := r.insert(tx, movie)
+		return ImportOutcome{Movie: created, Err: err}
+	}
+	if err != nil {
+		return ImportOutcome{Err: err}
+	}
+	if !upsert {
+		return ImportOutcome{Err: gorm.ErrDuplicatedKey}
+	}
+
+	before := toMovieResponse(&existing)
+	if err := loadGenres(tx, before); err != nil {
+		return ImportOutcome{Err: err}
+	}
+	var genreIDs []uint
+	if movie.Genres != nil {
+		if genreIDs, err = resolveGenres(tx, movie.Genres); err != nil {
+			return ImportOutcome{Err: err}
+		}
+	}
+	var updated models.Movie
+	fields := map[string]interface{}{"director": movie.Director, "year": movie.Year, "plot": movie.Plot}
+	if err := r.update(tx, &updated, existing.ID, 0, fields); err != nil {
+		return ImportOutcome{Err: err}
+	}
+	if movie.Genres != nil {
+		if err := replaceGenres(tx, existing.ID, genreIDs); err != nil {
+			return ImportOutcome{Err: err}
+		}
+	}
+	if err := setDirector(tx, existing.ID, updated.Director); err != nil {
+		return ImportOutcome{Err: err}
+	}
+	after := toMovieResponse(&updated)
+	return ImportOutcome{Movie: after, Before: before, Updated: true, Err: loadGenres(tx, after)}
+}
+
+func (r *PostgresMovieRepository) GetDeleted(limit, offset int) (models.MovieListResponse, error) {
+	var movies []models.Movie
+	var totalCount int64
+	query := r.db.Unscoped().Model(&models.Movie{}).Where("deleted_at IS NOT NULL")
+
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count deleted movies:", err)
+		return models.MovieListResponse{}, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("deleted_at DESC").Order("id ASC").Offset(offset).Find(&movies).Error; err != nil {
+		log.Println("❌ Failed to retrieve deleted movies:", err)
+		return models.MovieListResponse{}, err
+	}
+
+	count := int(totalCount)
+	response := models.MovieListResponse{Movies: make([]models.MovieResponse, 0, len(movies)), Count: &count}
+	for i := range movies {
+		response.Movies = append(response.Movies, *toMovieResponse(&movies[i]))
+	}
+	return response, loadGenres(r.db, moviePointers(response.Movies)...)
+}
+
+func (r *PostgresMovieRepository) GetDeletedByID(id uint) (*models.MovieResponse, error) {
+	var movie models.Movie
+	if err := r.db.Unscoped().First(&movie, "id = ? AND deleted_at IS NOT NULL", id).Error; err != nil {
+		log.Println("❌ Deleted movie not found:", err)
+		return nil, err
+	}
+	deleted := toMovieResponse(&movie)
+	return deleted, loadGenres(r.db, deleted)
+}
+
+func (r *PostgresMovieRepository) Restore(id, version uint, record RecordRevision) (*models.MovieResponse, error) {
+	var restored *models.MovieResponse
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		before, err := lockSnapshot(tx, id, true)
+		if err != nil {
+			return err
+		}
+		if version > 0 && before.Version != version {
+			return ErrVersionConflict
+		}
+
+		var movie models.Movie
+		err = tx.Unscoped().Model(&movie).Clauses(clause.Returning{}).Where("id = ?", id).
+			Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return err
+		}
+		restored = toMovieResponse(&movie)
+		if err := loadGenres(tx, restored); err != nil {
+			return err
+		}
+		return writeRevision(tx, record, before, restored)
+	})
+	if err != nil {
+		log.Println("❌ Failed to restore movie:", err)
+		return nil, err
+	}
+	return restored, nil
+}
+
+// HardDelete reads the movie and its genres first; the foreign keys drop its genre links
+// along with it.
+func (r *PostgresMovieRepository) HardDelete(id uint, record RecordRevision) (*models.MovieResponse, error) {
+	var removed *models.MovieResponse
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var movie models.Movie
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).First(&movie, id).Error; err != nil {
+			return err
+		}
+		removed = toMovieResponse(&movie)
+		if err := loadGenres(tx, removed); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Movie{}, id).Error; err != nil {
+			return err
+		}
+		return writeRevision(tx, record, removed, nil)
+	})
+	if err != nil {
+		log.Println("❌ Failed to permanently delete movie:", err)
+		return nil, err
+	}
+	return removed, nil
+}
+
+func (r *PostgresMovieRepository) PurgeDeleted(before time.Time, record RecordRevision) ([]models.MovieResponse, error) {
+	purged := []models.MovieResponse{}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var movies []models.Movie
+		err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Find(&movies).Error
+		if err != nil || len(movies) == 0 {
+			return err
+		}
+
+		ids := make([]uint, 0, len(movies))
+		for i := range movies {
+			ids = append(ids, movies[i].ID)
+			purged = append(purged, *toMovieResponse(&movies[i]))
+		}
+		if err := loadGenres(tx, moviePointers(purged)...); err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&models.Movie{}, ids).Error; err != nil {
+			return err
+		}
+		for i := range purged {
+			if err := writeRevision(tx, record, &purged[i], nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Println("❌ Failed to purge deleted movies:", err)
+		return nil, err
+	}
+	return purged, nil
+}
+
+func (r *PostgresMovieRepository) GetRelations(ids []uint, expand models.MovieExpansion) (map[uint]*models.MovieRelations, error) {
+	relations := emptyRelations(ids, expand)
+	if len(ids) == 0 {
+		return relations, nil
+	}
+
+	if expand.Genres {
+		var rows []struct {
+			MovieID uint
+			models.GenreRef
+		}
+		err := r.db.Table("movie_genres").
+			Select("movie_genres.movie_id, genres.id, genres.name, genres.slug").
+			Joins("JOIN genres ON genres.id = movie_genres.genre_id").
+			Where("movie_genres.movie_id IN ?", ids).
+			Order("genres.name ASC").
+			Scan(&rows).Error
+		if err != nil {
+			log.Println("❌ Failed to load movie genres:", err)
+			return nil, err
+		}
+		for _, row := range rows {
+			relations[row.MovieID].Genres = append(relations[row.MovieID].Genres, row.GenreRef)
+		}
+	}
+
+	if expand.Credits || expand.CreditsPerson {
+		var rows []struct {
+			MovieID uint
+			models.CreditResponse
+		}
+		err := r.db.Table("movie_credits").
+			Select("movie_credits.movie_id, movie_credits.person_id, people.name, movie_credits.role, movie_credits.character, movie_credits.billing").
+			Joins("JOIN people ON people.id = movie_credits.person_id").
+			Where("movie_credits.movie_id IN ?", ids).
+			Order("movie_credits.billing ASC, movie_credits.role ASC, movie_credits.id ASC").
+			Scan(&rows).Error
+		if err != nil {
+			log.Println("❌ Failed to load movie credits:", err)
+			return nil, err
+		}
+
+		people := make(map[uint]*models.PersonResponse)
+		if expand.CreditsPerson && len(rows) > 0 {
+			personIDs := make([]uint, 0, len(rows))
+			for _, row := range rows {
+				personIDs = append(personIDs, row.PersonID)
+			}
+			var found []models.Person
+			if err := r.db.Where("id IN ?", personIDs).Find(&found).Error; err != nil {
+				log.Println("❌ Failed to load credited people:", err)
+				return nil, err
+			}
+			for i := range found {
+				people[found[i].ID] = personResponse(&found[i])
+			}
+		}
+		for _, row := range rows {
+			relations[row.MovieID].Credits = append(relations[row.MovieID].Credits,
+				models.ExpandedCredit{CreditResponse: row.CreditResponse, Person: people[row.PersonID]})
+		}
+	}
+
+	if expand.Ratings {
+		var rows []struct {
+			MovieID uint
+			Rating  int
+			Count   int
+		}
+		err := r.db.Table("reviews").
+			Select("movie_id, rating, COUNT(*) AS count").
+			Where("movie_id IN ?", ids).
+			Group("movie_id, rating").
+			Scan(&rows).Error
+		if err != nil {
+			log.Println("❌ Failed to load movie ratings:", err)
+			return nil, err
+		}
+		distributions := make(map[uint]map[int]int)
+		for _, row := range rows {
+			if distributions[row.MovieID] == nil {
+				distributions[row.MovieID] = make(map[int]int)
+			}
+			distributions[row.MovieID][row.Rating] = row.Count
+		}
+		for id, distribution := range distributions {
+			relations[id].Ratings = ratingSummary(distribution)
+		}
+	}
+	return relations, nil
+}
+
+func (r *PostgresMovieRepository) GetCredits(movieID uint) ([]models.CreditResponse, error) {
+	credits := []models.CreditResponse{}
+	err := r.db.Table("movie_credits").
+		Select("movie_credits.person_id, people.name, movie_credits.role, movie_credits.character, movie_credits.billing").
+		Joins("JOIN people ON people.id = movie_credits.person_id").
+		Where("movie_credits.movie_id = ?", movieID).
+		Order("movie_credits.billing ASC, movie_credits.role ASC, movie_credits.id ASC").
+		Scan(&credits).Error
+	if err != nil {
+		log.Println("❌ Failed to retrieve movie credits:", err)
+		return nil, err
+	}
+	return credits, nil
+}
+
+func (r *PostgresMovieRepository) ReplaceCredits(request *models.ReplaceCreditsRequest, record RecordRevision) (*models.MovieResponse, error) {
+	credits := sortedCredits(request.Credits)
+
+	var updated *models.MovieResponse
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		names, err := creditedPeople(tx, credits)
+		if err != nil {
+			return err
+		}
+
+		before, err := lockSnapshot(tx, request.ID, false)
+		if err != nil {
+			return err
+		}
+		director, err := directorOf(credits, names)
+		if err != nil {
+			return err
+		}
+		var movie models.Movie
+		fields := map[string]interface{}{}
+		if director != "" {
+			fields["director"] = director
+		}
+		if err := r.update(tx, &movie, request.ID, request.Version, fields); err != nil {
+			return err
+		}
+
+		if err := tx.Where("movie_id = ?", request.ID).Delete(&models.MovieCredit{}).Error; err != nil {
+			return err
+		}
+		if len(credits) > 0 {
+			rows := make([]models.MovieCredit, 0, len(credits))
+			for _, credit := range credits {
+				rows = append(rows, models.MovieCredit{
+					MovieID:   request.ID,
+					PersonID:  credit.PersonID,
+					Role:      credit.Role,
+					Character: credit.Character,
+					Billing:   credit.Billing,
+				})
+			}
+			if err := tx.Omit(clause.Associations).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+
+		updated = toMovieResponse(&movie)
+		if err := loadGenres(tx, updated); err != nil {
+			return err
+		}
+		return writeRevision(tx, record, before, updated)
+	})
+	if err != nil {
+		log.Println("❌ Failed to replace movie credits:", err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (r *PostgresMovieRepository) GetFilmography(personID uint) ([]models.FilmographyEntry, error) {
+	entries := []models.FilmographyEntry{}
+	err := r.db.Table("movie_credits").
+		Select("movies.id AS movie_id, movies.title, movies.year, movie_credits.role, movie_credits.character, movie_credits.billing").
+		Joins("JOIN movies ON movies.id = movie_credits.movie_id").
+		Where("movie_credits.person_id = ? AND movies.deleted_at IS NULL", personID).
+		Order("movies.year DESC, movies.title ASC, movie_credits.role ASC").
+		Scan(&entries).Error
+	if err != nil {
+		log.Println("❌ Failed to retrieve filmography:", err)
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *PostgresMovieRepository) CountCredits(personID uint) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.MovieCredit{}).Where("person_id = ?", personID).Count(&count).Error; err != nil {
+		log.Println("❌ Failed to count credits:", err)
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// creditedPeople looks up the names of the credited people by ID, failing with
+// ErrUnknownPerson for IDs that match no person.
+func creditedPeople(tx *gorm.DB, credits []models.CreditRequest) (map[uint]string, error) {
+	if len(credits) == 0 {
+		return map[uint]string{}, nil
+	}
+
+	ids := make([]uint, 0, len(credits))
+	for _, credit := range credits {
+		ids = append(ids, credit.PersonID)
+	}
+	var people []models.Person
+	if err := tx.Select("id", "name").Where("id IN ?", ids).Find(&people).Error; err != nil {
+		return nil, err
+	}
+
+	names := make(map[uint]string, len(people))
+	for _, person := range people {
+		names[person.ID] = person.Name
+	}
+	if missing := missingPeople(ids, names); len(missing) > 0 {
+		return nil, unknownPeople(missing)
+	}
+	return names, nil
+}
+
+// setDirector keeps the movie's director credits in line with its director field. Credits
+// that already spell out the field are left alone; otherwise each of the comma-separated
+// names in the field is credited as a director, found by exact name or created.
+func setDirector(tx *gorm.DB, movieID uint, name string) error {
+	var current []string
+	err := tx.Table("movie_credits").
+		Joins("JOIN people ON people.id = movie_credits.person_id").
+		Where("movie_credits.movie_id = ? AND movie_credits.role = ?", movieID, models.CreditDirector).
+		Order("movie_credits.billing ASC, movie_credits.id ASC").
+		Pluck("people.name", &current).Error
+	if err != nil {
+		return err
+	}
+	if strings.Join(current, directorSeparator) == name {
+		return nil
+	}
+
+	err = tx.Where("movie_id = ? AND role = ?", movieID, models.CreditDirector).Delete(&models.MovieCredit{}).Error
+	if err != nil {
+		return err
+	}
+
+	for _, director := range directorNames(name) {
+		var people []models.Person
+		if err := tx.Where("name = ?", director).Order("id ASC").Limit(1).Find(&people).Error; err != nil {
+			return err
+		}
+		if len(people) == 0 {
+			people = append(people, models.Person{Name: director})
+			if err := tx.Create(&people[0]).Error; err != nil {
+				return err
+			}
+		}
+		credit := models.MovieCredit{MovieID: movieID, PersonID: people[0].ID, Role: models.CreditDirector}
+		if err := tx.Omit(clause.Associations).Create(&credit).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveGenres maps genre names to IDs by slug, failing with ErrUnknownGenre for names
+// that match no genre. Duplicates collapse into one ID.
+func resolveGenres(tx *gorm.DB, names []string) ([]uint, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	slugs := make([]string, 0, len(names))
+	for _, name := range names {
+		slugs = append(slugs, models.GenreSlug(name))
+	}
+	var genres []models.Genre
+	if err := tx.Where("slug IN ?", slugs).Find(&genres).Error; err != nil {
+		return nil, err
+	}
+
+	bySlug := make(map[string]uint, len(genres))
+	for _, genre := range genres {
+		bySlug[genre.Slug] = genre.ID
+	}
+	ids := make([]uint, 0, len(genres))
+	seen := make(map[uint]bool, len(genres))
+	var unknown []string
+	for i, slug := range slugs {
+		id, ok := bySlug[slug]
+		if !ok {
+			unknown = append(unknown, strings.TrimSpace(names[i]))
+			continue
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, unknownGenres(unknown)
+	}
+	return ids, nil
+}
+
+// replaceGenres sets the movie's genre links to exactly genreIDs.
+func replaceGenres(tx *gorm.DB, movieID uint, genreIDs []uint) error {
+	if err := tx.Where("movie_id = ?", movieID).Delete(&models.MovieGenre{}).Error; err != nil {
+		return err
+	}
+	if len(genreIDs) == 0 {
+		return nil
+	}
+
+	links := make([]models.MovieGenre, 0, len(genreIDs))
+	for _, genreID := range genreIDs {
+		links = append(links, models.MovieGenre{MovieID: movieID, GenreID: genreID})
+	}
+	return tx.Omit(clause.Associations).Create(&links).Error
+}
+
+// loadGenres fills in the genre names of the movies with a single query.
+func loadGenres(db *gorm.DB, movies ...*models.MovieResponse) error {
+	if len(movies) == 0 {
+		return nil
+	}
+
+	byID := make(map[uint]*models.MovieResponse, len(movies))
+	ids := make([]uint, 0, len(movies))
+	for _, movie := range movies {
+		movie.Genres = []string{}
+		byID[movie.ID] = movie
+		ids = append(ids, movie.ID)
+	}
+
+	var rows []struct {
+		MovieID uint
+		Name    string
+	}
+	err := db.Table("movie_genres").
+		Select("movie_genres.movie_id, genres.name").
+		Joins("JOIN genres ON genres.id = movie_genres.genre_id").
+		Where("movie_genres.movie_id IN ?", ids).
+		Order("genres.name ASC").
+		Scan(&rows).Error
+	if err != nil {
+		log.Println("❌ Failed to load movie genres:", err)
+		return err
+	}
+	for _, row := range rows {
+		byID[row.MovieID].Genres = append(byID[row.MovieID].Genres, row.Name)
+	}
+	return nil
+}
+
+func moviePointers(movies []models.MovieResponse) []*models.MovieResponse {
+	pointers := make([]*models.MovieResponse, len(movies))
+	for i := range movies {
+		pointers[i] = &movies[i]
+	}
+	return pointers
+}
@@ -0,0 +1,152 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MovieRevisionRepository stores the change history of movies.
+type MovieRevisionRepository interface {
+	Create(revision *models.MovieRevision) error
+	// GetByMovie lists the revisions of a movie, newest first, with the total count.
+	GetByMovie(movieID uint, limit, offset int) ([]models.MovieRevision, int, error)
+	// Get returns one revision, or gorm.ErrRecordNotFound.
+	Get(movieID, revision uint) (*models.MovieRevision, error)
+}
+
+// RecordRevision builds the history entry of a movie change from the movie as it was
+// before the change and as the change wrote it. before is nil for a new movie and after
+// for one removed for good. The movie repository calls it inside the change's transaction.
+type RecordRevision func(before, after *models.MovieResponse) *models.MovieRevision
+
+// NewMovieRevisionRepository keeps revisions next to the movies, as configured by MOVIE_STORE.
+func NewMovieRevisionRepository(cfg *config.Config, db *gorm.DB) MovieRevisionRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryMovieRevisionRepository()
+	}
+	return NewPostgresMovieRevisionRepository(db)
+}
+
+type PostgresMovieRevisionRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresMovieRevisionRepository(db *gorm.DB) *PostgresMovieRevisionRepository {
+	return &PostgresMovieRevisionRepository{db: db}
+}
+
+func (r *PostgresMovieRevisionRepository) Create(revision *models.MovieRevision) error {
+	if err := r.db.Create(revision).Error; err != nil {
+		log.Println("❌ Failed to create movie revision:", err)
+		return err
+	}
+	return nil
+}
+
+func (r *PostgresMovieRevisionRepository) GetByMovie(movieID uint, limit, offset int) ([]models.MovieRevision, int, error) {
+	var revisions []models.MovieRevision
+	var totalCount int64
+
+	query := r.db.Model(&models.MovieRevision{}).Where("movie_id = ?", movieID)
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count movie revisions:", err)
+		return nil, 0, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("revision DESC").Offset(offset).Find(&revisions).Error; err != nil {
+		log.Println("❌ Failed to retrieve movie revisions:", err)
+		return nil, 0, err
+	}
+
+	return revisions, int(totalCount), nil
+}
+
+func (r *PostgresMovieRevisionRepository) Get(movieID, revision uint) (*models.MovieRevision, error) {
+	var entry models.MovieRevision
+	if err := r.db.First(&entry, "movie_id = ? AND revision = ?", movieID, revision).Error; err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+type MemoryMovieRevisionRepository struct {
+	mu        sync.RWMutex
+	revisions map[uint][]models.MovieRevision
+	nextID    uint
+}
+
+func NewMemoryMovieRevisionRepository() *MemoryMovieRevisionRepository {
+	return &MemoryMovieRevisionRepository{revisions: make(map[uint][]models.MovieRevision), nextID: 1}
+}
+
+func (r *MemoryMovieRevisionRepository) Create(revision *models.MovieRevision) error {
+	return r.add(revision)
+}
+
+// add stores all of the revisions or, if one of them is taken, none.
+func (r *MemoryMovieRevisionRepository) add(revisions ...*models.MovieRevision) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct{ movieID, revision uint }
+	seen := make(map[key]bool, len(revisions))
+	for _, revision := range revisions {
+		if seen[key{revision.MovieID, revision.Revision}] {
+			return gorm.ErrDuplicatedKey
+		}
+		seen[key{revision.MovieID, revision.Revision}] = true
+		for _, existing := range r.revisions[revision.MovieID] {
+			if existing.Revision == revision.Revision {
+				return gorm.ErrDuplicatedKey
+			}
+		}
+	}
+
+	now := time.Now()
+	for _, revision := range revisions {
+		revision.ID = r.nextID
+		revision.CreatedAt = now
+		r.nextID++
+		r.revisions[revision.MovieID] = append(r.revisions[revision.MovieID], *revision)
+	}
+	return nil
+}
+
+func (r *MemoryMovieRevisionRepository) GetByMovie(movieID uint, limit, offset int) ([]models.MovieRevision, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	revisions := append([]models.MovieRevision(nil), r.revisions[movieID]...)
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+
+	total := len(revisions)
+	if offset >= total {
+		return []models.MovieRevision{}, total, nil
+	}
+	revisions = revisions[offset:]
+	if limit > 0 && limit < len(revisions) {
+		revisions = revisions[:limit]
+	}
+	return revisions, total, nil
+}
+
+func (r *MemoryMovieRevisionRepository) Get(movieID, revision uint) (*models.MovieRevision, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, entry := range r.revisions[movieID] {
+		if entry.Revision == revision {
+			return &entry, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
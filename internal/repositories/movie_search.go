@@ -0,0 +1,175 @@
+package repositories
+
+import (
+	"strings"
+	"unicode"
+)
+
+// The in-memory store approximates Postgres full-text search. Queries use the same
+// websearch syntax: words must all match, "quoted phrases" match consecutive words, a
+// leading - excludes a word or phrase and OR separates alternatives. Words match whole
+// words, case-insensitively, without the stemming and stop words of Postgres.
+
+// Ranks of a match in each field, after the default weights ts_rank gives to the A, B and
+// C labels of the search vector.
+const (
+	titleSearchWeight    = 1.0
+	directorSearchWeight = 0.4
+	plotSearchWeight     = 0.2
+)
+
+// searchHeadlineWords is the length of in-memory headlines, in words.
+const searchHeadlineWords = 35
+
+// searchTerm is a word or phrase of a search query, as lowercase words.
+type searchTerm struct {
+	words   []string
+	negated bool
+}
+
+// searchQuery is a parsed query: alternatives joined by OR, each a list of terms that
+// must all hold.
+type searchQuery [][]searchTerm
+
+// parseSearchQuery parses a websearch-style query. Terms without any word are dropped.
+func parseSearchQuery(query string) searchQuery {
+	var parsed searchQuery
+	var current []searchTerm
+	addTerm := func(text string, negated bool) {
+		if words := searchWords(text); len(words) > 0 {
+			current = append(current, searchTerm{words: words, negated: negated})
+		}
+	}
+
+	rest := strings.TrimSpace(query)
+	for rest != "" {
+		negated := strings.HasPrefix(rest, "-")
+		if negated {
+			rest = rest[1:]
+		}
+		if strings.HasPrefix(rest, `"`) {
+			phrase, after, _ := strings.Cut(rest[1:], `"`)
+			addTerm(phrase, negated)
+			rest = strings.TrimSpace(after)
+			continue
+		}
+
+		word, after, _ := strings.Cut(rest, " ")
+		if strings.EqualFold(word, "or") && !negated {
+			if len(current) > 0 {
+				parsed = append(parsed, current)
+				current = nil
+			}
+		} else {
+			addTerm(word, negated)
+		}
+		rest = strings.TrimSpace(after)
+	}
+	if len(current) > 0 {
+		parsed = append(parsed, current)
+	}
+	return parsed
+}
+
+// searchWords splits text into lowercase words of letters and digits.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchDocument is a movie's searchable text, split into words per field.
+type searchDocument struct {
+	title, director, plot []string
+}
+
+func newSearchDocument(title, director, plot string) searchDocument {
+	return searchDocument{title: searchWords(title), director: searchWords(director), plot: searchWords(plot)}
+}
+
+// rank reports whether the document matches the query and how relevant it is: the
+// weighted number of occurrences of the terms of the alternatives that match.
+func (d searchDocument) rank(query searchQuery) (float64, bool) {
+	rank, matched := 0.0, false
+	for _, terms := range query {
+		alternative, ok := 0.0, true
+		for _, term := range terms {
+			occurrences := titleSearchWeight*float64(phraseCount(d.title, term.words)) +
+				directorSearchWeight*float64(phraseCount(d.director, term.words)) +
+				plotSearchWeight*float64(phraseCount(d.plot, term.words))
+			if (occurrences > 0) == term.negated {
+				ok = false
+				break
+			}
+			alternative += occurrences
+		}
+		if ok {
+			rank += alternative
+			matched = true
+		}
+	}
+	return rank, matched
+}
+
+// phraseCount counts the occurrences of the phrase in the words.
+func phraseCount(words, phrase []string) int {
+	count := 0
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, word := range phrase {
+			if words[i+j] != word {
+				match = false
+				break
+			}
+		}
+		if match {
+			count++
+		}
+	}
+	return count
+}
+
+// searchHeadline returns a snippet of the text around the first word of the query's
+// terms, with those words wrapped in <mark> tags, or the start of the text when none of
+// them occurs.
+func searchHeadline(text string, query searchQuery) string {
+	marked := make(map[string]bool)
+	for _, terms := range query {
+		for _, term := range terms {
+			if !term.negated {
+				for _, word := range term.words {
+					marked[word] = true
+				}
+			}
+		}
+	}
+
+	tokens := strings.Fields(text)
+	first := -1
+	for i, token := range tokens {
+		if markedToken(token, marked) {
+			first = i
+			break
+		}
+	}
+	start := max(0, first-searchHeadlineWords/4)
+	end := min(len(tokens), start+searchHeadlineWords)
+
+	snippet := make([]string, 0, end-start)
+	for _, token := range tokens[start:end] {
+		if markedToken(token, marked) {
+			token = "<mark>" + token + "</mark>"
+		}
+		snippet = append(snippet, token)
+	}
+	return strings.Join(snippet, " ")
+}
+
+func markedToken(token string, marked map[string]bool) bool {
+	for _, word := range searchWords(token) {
+		if marked[word] {
+			return true
+		}
+	}
+	return false
+}
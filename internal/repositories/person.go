@@ -0,0 +1,273 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrUnknownPerson is returned when a credit refers to a person that does not exist.
+	ErrUnknownPerson = errors.New("unknown person")
+	// ErrPersonHasCredits is returned when deleting a person who is still credited on a movie.
+	ErrPersonHasCredits = errors.New("person has movie credits")
+)
+
+// unknownPeople wraps ErrUnknownPerson with the IDs that matched no person.
+func unknownPeople(ids []uint) error {
+	return fmt.Errorf("%w: %v", ErrUnknownPerson, ids)
+}
+
+// PersonRepository stores the people movies credit. A missing person is reported as
+// gorm.ErrRecordNotFound.
+type PersonRepository interface {
+	Create(person *models.PersonRequest) (*models.Person, error)
+	// GetAll lists people by name, optionally filtered by a case-insensitive name substring,
+	// along with the total number of matches.
+	GetAll(name string, limit, offset int) ([]models.Person, int, error)
+	GetByID(id uint) (*models.Person, error)
+	Update(id uint, person *models.PersonRequest) (*models.Person, error)
+	Delete(id uint) error
+}
+
+// NewPersonRepository keeps people next to the movies, as configured by MOVIE_STORE.
+func NewPersonRepository(cfg *config.Config, db *gorm.DB) PersonRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryPersonRepository()
+	}
+	return NewPostgresPersonRepository(db)
+}
+
+type PostgresPersonRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresPersonRepository(db *gorm.DB) *PostgresPersonRepository {
+	return &PostgresPersonRepository{db: db}
+}
+
+func (r *PostgresPersonRepository) Create(request *models.PersonRequest) (*models.Person, error) {
+	person := models.Person{Name: strings.TrimSpace(request.Name), Bio: request.Bio, BirthYear: request.BirthYear}
+	if err := r.db.Create(&person).Error; err != nil {
+		log.Println("❌ Failed to create person:", err)
+		return nil, err
+	}
+	return &person, nil
+}
+
+func (r *PostgresPersonRepository) GetAll(name string, limit, offset int) ([]models.Person, int, error) {
+	var people []models.Person
+	var totalCount int64
+
+	query := r.db.Model(&models.Person{})
+	if name != "" {
+		query = query.Where("name ILIKE ?", "%"+name+"%")
+	}
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count people:", err)
+		return nil, 0, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("name ASC").Order("id ASC").Offset(offset).Find(&people).Error; err != nil {
+		log.Println("❌ Failed to retrieve people:", err)
+		return nil, 0, err
+	}
+	return people, int(totalCount), nil
+}
+
+func (r *PostgresPersonRepository) GetByID(id uint) (*models.Person, error) {
+	var person models.Person
+	if err := r.db.First(&person, id).Error; err != nil {
+		log.Println("❌ Person not found:", err)
+		return nil, err
+	}
+	return &person, nil
+}
+
+func (r *PostgresPersonRepository) Update(id uint, request *models.PersonRequest) (*models.Person, error) {
+	person := models.Person{ID: id}
+	result := r.db.Model(&person).Clauses(clause.Returning{}).Updates(map[string]interface{}{
+		"name":       strings.TrimSpace(request.Name),
+		"bio":        request.Bio,
+		"birth_year": request.BirthYear,
+	})
+	if result.Error != nil {
+		log.Println("❌ Failed to update person:", result.Error)
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &person, nil
+}
+
+func (r *PostgresPersonRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.Person{}, id)
+	if errors.Is(result.Error, gorm.ErrForeignKeyViolated) {
+		return ErrPersonHasCredits
+	}
+	if result.Error != nil {
+		log.Println("❌ Failed to delete person:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MemoryPersonRepository keeps people in process memory for MemoryMovieRepository, which
+// resolves credited people through it. It cannot see credits itself, so PersonService
+// checks for them before deleting.
+type MemoryPersonRepository struct {
+	mu     sync.RWMutex
+	people map[uint]*models.Person
+	nextID uint
+}
+
+func NewMemoryPersonRepository() *MemoryPersonRepository {
+	return &MemoryPersonRepository{people: make(map[uint]*models.Person), nextID: 1}
+}
+
+func (r *MemoryPersonRepository) Create(request *models.PersonRequest) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := *r.insert(strings.TrimSpace(request.Name), request.Bio, request.BirthYear)
+	return &created, nil
+}
+
+func (r *MemoryPersonRepository) GetAll(name string, limit, offset int) ([]models.Person, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name = strings.ToLower(name)
+	people := make([]models.Person, 0, len(r.people))
+	for _, person := range r.people {
+		if name == "" || strings.Contains(strings.ToLower(person.Name), name) {
+			people = append(people, *person)
+		}
+	}
+	sort.Slice(people, func(i, j int) bool {
+		if people[i].Name != people[j].Name {
+			return people[i].Name < people[j].Name
+		}
+		return people[i].ID < people[j].ID
+	})
+
+	total := len(people)
+	if offset >= total {
+		return []models.Person{}, total, nil
+	}
+	people = people[offset:]
+	if limit > 0 && limit < len(people) {
+		people = people[:limit]
+	}
+	return people, total, nil
+}
+
+func (r *MemoryPersonRepository) GetByID(id uint) (*models.Person, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	person, ok := r.people[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *person
+	return &found, nil
+}
+
+func (r *MemoryPersonRepository) Update(id uint, request *models.PersonRequest) (*models.Person, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	person, ok := r.people[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	person.Name = strings.TrimSpace(request.Name)
+	person.Bio = request.Bio
+	person.BirthYear = request.BirthYear
+	person.UpdatedAt = time.Now()
+
+	updated := *person
+	return &updated, nil
+}
+
+func (r *MemoryPersonRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.people[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.people, id)
+	return nil
+}
+
+// findOrCreate returns the ID of the oldest person with exactly this name, creating one
+// if there is none, the way director names are turned into people.
+func (r *MemoryPersonRepository) findOrCreate(name string) uint {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var found uint
+	for id, person := range r.people {
+		if person.Name == name && (found == 0 || id < found) {
+			found = id
+		}
+	}
+	if found != 0 {
+		return found
+	}
+	return r.insert(name, "", nil).ID
+}
+
+// names returns the names of the people that exist, keyed by ID.
+func (r *MemoryPersonRepository) names(ids []uint) map[uint]string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make(map[uint]string, len(ids))
+	for _, id := range ids {
+		if person, ok := r.people[id]; ok {
+			names[id] = person.Name
+		}
+	}
+	return names
+}
+
+// responses renders the people that exist, by ID.
+func (r *MemoryPersonRepository) responses(ids []uint) map[uint]*models.PersonResponse {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	people := make(map[uint]*models.PersonResponse, len(ids))
+	for _, id := range ids {
+		if person, ok := r.people[id]; ok {
+			people[id] = personResponse(person)
+		}
+	}
+	return people
+}
+
+func (r *MemoryPersonRepository) insert(name, bio string, birthYear *int) *models.Person {
+	now := time.Now()
+	person := &models.Person{ID: r.nextID, Name: name, Bio: bio, BirthYear: birthYear, CreatedAt: now, UpdatedAt: now}
+	r.people[person.ID] = person
+	r.nextID++
+	return person
+}
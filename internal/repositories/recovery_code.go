@@ -0,0 +1,121 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecoveryCodeRepository stores the hashes of two-factor recovery codes.
+type RecoveryCodeRepository interface {
+	// Replace swaps every recovery code of the user for the given hashes at once.
+	Replace(userID uint, hashes []string) error
+	// Use marks an unused code as used, at most once even under concurrent attempts with
+	// the same code; it returns gorm.ErrRecordNotFound otherwise.
+	Use(userID uint, hash string) error
+	DeleteForUser(userID uint) error
+}
+
+// NewRecoveryCodeRepository keeps recovery codes next to the accounts, as configured by MOVIE_STORE.
+func NewRecoveryCodeRepository(cfg *config.Config, db *gorm.DB) RecoveryCodeRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryRecoveryCodeRepository()
+	}
+	return NewPostgresRecoveryCodeRepository(db)
+}
+
+type PostgresRecoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresRecoveryCodeRepository(db *gorm.DB) *PostgresRecoveryCodeRepository {
+	return &PostgresRecoveryCodeRepository{db: db}
+}
+
+// Replace uses a single transaction.
+func (r *PostgresRecoveryCodeRepository) Replace(userID uint, hashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			log.Println("❌ Failed to delete recovery codes:", err)
+			return err
+		}
+
+		codes := make([]models.RecoveryCode, 0, len(hashes))
+		for _, hash := range hashes {
+			codes = append(codes, models.RecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			log.Println("❌ Failed to create recovery codes:", err)
+			return err
+		}
+		return nil
+	})
+}
+
+// Use relies on a conditional update to let only one concurrent attempt through.
+func (r *PostgresRecoveryCodeRepository) Use(userID uint, hash string) error {
+	result := r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		log.Println("❌ Failed to use recovery code:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PostgresRecoveryCodeRepository) DeleteForUser(userID uint) error {
+	if err := r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		log.Println("❌ Failed to delete recovery codes:", err)
+		return err
+	}
+	return nil
+}
+
+// MemoryRecoveryCodeRepository keeps the unused code hashes of each user; used codes are
+// simply forgotten.
+type MemoryRecoveryCodeRepository struct {
+	mu    sync.Mutex
+	codes map[uint]map[string]bool
+}
+
+func NewMemoryRecoveryCodeRepository() *MemoryRecoveryCodeRepository {
+	return &MemoryRecoveryCodeRepository{codes: make(map[uint]map[string]bool)}
+}
+
+func (r *MemoryRecoveryCodeRepository) Replace(userID uint, hashes []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	codes := make(map[string]bool, len(hashes))
+	for _, hash := range hashes {
+		codes[hash] = true
+	}
+	r.codes[userID] = codes
+	return nil
+}
+
+func (r *MemoryRecoveryCodeRepository) Use(userID uint, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.codes[userID][hash] {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.codes[userID], hash)
+	return nil
+}
+
+func (r *MemoryRecoveryCodeRepository) DeleteForUser(userID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.codes, userID)
+	return nil
+}
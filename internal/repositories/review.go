@@ -0,0 +1,297 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReviewRepository stores movie reviews and keeps the rating aggregates of the movies in
+// step with them: every write recomputes the movie's average and count in the same
+// transaction. A missing review or movie is reported as gorm.ErrRecordNotFound and a
+// second review of a movie by the same user as gorm.ErrDuplicatedKey.
+type ReviewRepository interface {
+	Create(review *models.Review) (*models.Review, error)
+	// GetByMovie returns one page of the movie's reviews, newest first, along with the
+	// total number of reviews.
+	GetByMovie(movieID uint, limit, offset int) ([]models.Review, int, error)
+	GetByID(id uint) (*models.Review, error)
+	Update(id uint, request *models.ReviewRequest) (*models.Review, error)
+	Delete(id uint) error
+}
+
+// NewReviewRepository keeps reviews next to the movies, as configured by MOVIE_STORE. The
+// in-memory store writes the aggregates into the in-memory movie repository.
+func NewReviewRepository(cfg *config.Config, db *gorm.DB, movies MovieRepository) (ReviewRepository, error) {
+	if cfg.MovieStore == "memory" {
+		memory, err := memoryMovies(movies)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemoryReviewRepository(memory), nil
+	}
+	return NewPostgresReviewRepository(db), nil
+}
+
+type PostgresReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresReviewRepository(db *gorm.DB) *PostgresReviewRepository {
+	return &PostgresReviewRepository{db: db}
+}
+
+func (r *PostgresReviewRepository) Create(review *models.Review) (*models.Review, error) {
+	created := *review
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockMovie(tx, review.MovieID); err != nil {
+			return err
+		}
+		if err := tx.Omit(clause.Associations).Create(&created).Error; err != nil {
+			return err
+		}
+		return refreshRating(tx, review.MovieID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to create review:", err)
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *PostgresReviewRepository) GetByMovie(movieID uint, limit, offset int) ([]models.Review, int, error) {
+	var reviews []models.Review
+	var totalCount int64
+
+	query := r.db.Model(&models.Review{}).Where("movie_id = ?", movieID)
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count reviews:", err)
+		return nil, 0, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("created_at DESC").Order("id DESC").Offset(offset).Find(&reviews).Error; err != nil {
+		log.Println("❌ Failed to retrieve reviews:", err)
+		return nil, 0, err
+	}
+	return reviews, int(totalCount), nil
+}
+
+func (r *PostgresReviewRepository) GetByID(id uint) (*models.Review, error) {
+	var review models.Review
+	if err := r.db.First(&review, id).Error; err != nil {
+		log.Println("❌ Review not found:", err)
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *PostgresReviewRepository) Update(id uint, request *models.ReviewRequest) (*models.Review, error) {
+	review := models.Review{ID: id}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		movieID, err := reviewedMovie(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := lockMovie(tx, movieID); err != nil {
+			return err
+		}
+
+		result := tx.Model(&review).Clauses(clause.Returning{}).Updates(map[string]interface{}{
+			"rating": request.Rating,
+			"body":   request.Body,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return refreshRating(tx, movieID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to update review:", err)
+		return nil, err
+	}
+	return &review, nil
+}
+
+func (r *PostgresReviewRepository) Delete(id uint) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		movieID, err := reviewedMovie(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := lockMovie(tx, movieID); err != nil {
+			return err
+		}
+
+		result := tx.Delete(&models.Review{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return refreshRating(tx, movieID)
+	})
+	if err != nil {
+		log.Println("❌ Failed to delete review:", err)
+		return err
+	}
+	return nil
+}
+
+// reviewedMovie returns the ID of the movie a review is on.
+func reviewedMovie(tx *gorm.DB, reviewID uint) (uint, error) {
+	var review models.Review
+	if err := tx.Select("id", "movie_id").First(&review, reviewID).Error; err != nil {
+		return 0, err
+	}
+	return review.MovieID, nil
+}
+
+// lockMovie takes the movie's row lock, so concurrent review writes on the same movie
+// queue up and each recomputes the aggregates from every committed review.
+func lockMovie(tx *gorm.DB, movieID uint) error {
+	var movie models.Movie
+	return tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&movie, movieID).Error
+}
+
+// refreshRating recomputes the movie's rating average and count from its reviews. It
+// writes the columns directly, leaving the version and updated_at alone.
+func refreshRating(tx *gorm.DB, movieID uint) error {
+	return tx.Exec(`UPDATE movies SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE movie_id = ?), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE movie_id = ?)
+		WHERE id = ?`, movieID, movieID, movieID).Error
+}
+
+// MemoryReviewRepository keeps reviews in process memory and writes the aggregates into
+// a MemoryMovieRepository.
+type MemoryReviewRepository struct {
+	mu      sync.RWMutex
+	reviews map[uint]*models.Review
+	movies  *MemoryMovieRepository
+	nextID  uint
+}
+
+func NewMemoryReviewRepository(movies *MemoryMovieRepository) *MemoryReviewRepository {
+	return &MemoryReviewRepository{reviews: make(map[uint]*models.Review), movies: movies, nextID: 1}
+}
+
+func (r *MemoryReviewRepository) Create(review *models.Review) (*models.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.movies.exists(review.MovieID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	for _, existing := range r.reviews {
+		if existing.MovieID == review.MovieID && existing.UserID == review.UserID {
+			return nil, gorm.ErrDuplicatedKey
+		}
+	}
+
+	now := time.Now()
+	stored := *review
+	stored.ID = r.nextID
+	stored.CreatedAt = now
+	stored.UpdatedAt = now
+	r.reviews[stored.ID] = &stored
+	r.nextID++
+	r.refreshRating(stored.MovieID)
+
+	created := stored
+	return &created, nil
+}
+
+func (r *MemoryReviewRepository) GetByMovie(movieID uint, limit, offset int) ([]models.Review, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	reviews := make([]models.Review, 0)
+	for _, review := range r.reviews {
+		if review.MovieID == movieID {
+			reviews = append(reviews, *review)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID > reviews[j].ID
+	})
+
+	total := len(reviews)
+	if offset >= total {
+		return []models.Review{}, total, nil
+	}
+	reviews = reviews[offset:]
+	if limit > 0 && limit < len(reviews) {
+		reviews = reviews[:limit]
+	}
+	return reviews, total, nil
+}
+
+func (r *MemoryReviewRepository) GetByID(id uint) (*models.Review, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *review
+	return &found, nil
+}
+
+func (r *MemoryReviewRepository) Update(id uint, request *models.ReviewRequest) (*models.Review, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, ok := r.reviews[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	review.Rating = request.Rating
+	review.Body = request.Body
+	review.UpdatedAt = time.Now()
+	r.refreshRating(review.MovieID)
+
+	updated := *review
+	return &updated, nil
+}
+
+func (r *MemoryReviewRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	review, ok := r.reviews[id]
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.reviews, id)
+	r.refreshRating(review.MovieID)
+	return nil
+}
+
+// refreshRating recounts the movie's reviews per rating, from which the movie repository
+// derives the aggregates.
+func (r *MemoryReviewRepository) refreshRating(movieID uint) {
+	distribution := make(map[int]int)
+	for _, review := range r.reviews {
+		if review.MovieID == movieID {
+			distribution[review.Rating]++
+		}
+	}
+	r.movies.setRating(movieID, distribution)
+}
@@ -0,0 +1,174 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TokenRevocationStore is the access token denylist checked by AuthMiddleware on every request.
+type TokenRevocationStore interface {
+	// RevokeToken denylists a single token by jti until expiresAt.
+	RevokeToken(jti string, userID uint, expiresAt time.Time) error
+	// RevokeUserTokensBefore invalidates every token of the user issued before the given time.
+	RevokeUserTokensBefore(userID uint, before time.Time) error
+	// IsRevoked reports whether a token with the given jti, owner and issue time is revoked.
+	IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+	// DeleteExpired drops entries for tokens that have expired on their own.
+	DeleteExpired() error
+}
+
+// NewTokenRevocationStore picks the store implementation configured by TOKEN_REVOCATION_STORE.
+func NewTokenRevocationStore(cfg *config.Config, db *gorm.DB) TokenRevocationStore {
+	if cfg.TokenRevocationStore == "memory" {
+		return NewMemoryTokenRevocationStore()
+	}
+	return NewPostgresTokenRevocationStore(db)
+}
+
+// cutoffExpiry is how long a per-user cutoff matters: tokens older than the access
+// token TTL are expired regardless of the cutoff.
+func cutoffExpiry(before time.Time) time.Time {
+	return before.Add(config.AccessTokenTTL)
+}
+
+type MemoryTokenRevocationStore struct {
+	mu      sync.RWMutex
+	tokens  map[string]time.Time
+	cutoffs map[uint]time.Time
+}
+
+func NewMemoryTokenRevocationStore() *MemoryTokenRevocationStore {
+	return &MemoryTokenRevocationStore{
+		tokens:  make(map[string]time.Time),
+		cutoffs: make(map[uint]time.Time),
+	}
+}
+
+func (s *MemoryTokenRevocationStore) RevokeToken(jti string, _ uint, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+func (s *MemoryTokenRevocationStore) RevokeUserTokensBefore(userID uint, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.cutoffs[userID]; !ok || before.After(current) {
+		s.cutoffs[userID] = before
+	}
+	return nil
+}
+
+func (s *MemoryTokenRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	now := time.Now()
+	if expiresAt, ok := s.tokens[jti]; ok && jti != "" && now.Before(expiresAt) {
+		return true, nil
+	}
+	if cutoff, ok := s.cutoffs[userID]; ok && now.Before(cutoffExpiry(cutoff)) && issuedAt.Before(cutoff) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *MemoryTokenRevocationStore) DeleteExpired() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for jti, expiresAt := range s.tokens {
+		if !now.Before(expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, cutoff := range s.cutoffs {
+		if !now.Before(cutoffExpiry(cutoff)) {
+			delete(s.cutoffs, userID)
+		}
+	}
+	return nil
+}
+
+type PostgresTokenRevocationStore struct {
+	db *gorm.DB
+}
+
+func NewPostgresTokenRevocationStore(db *gorm.DB) *PostgresTokenRevocationStore {
+	return &PostgresTokenRevocationStore{db: db}
+}
+
+func (s *PostgresTokenRevocationStore) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	token := models.RevokedToken{JTI: jti, UserID: userID, ExpiresAt: expiresAt}
+	err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "jti"}},
+		DoUpdates: clause.AssignmentColumns([]string{"expires_at"}),
+	}).Create(&token).Error
+	if err != nil {
+		log.Println("❌ Failed to revoke token:", err)
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresTokenRevocationStore) RevokeUserTokensBefore(userID uint, before time.Time) error {
+	cutoff := models.UserTokenCutoff{UserID: userID, RevokedBefore: before, ExpiresAt: cutoffExpiry(before)}
+	// Never move an existing cutoff backwards.
+	err := s.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Set{
+			{Column: clause.Column{Name: "revoked_before"}, Value: gorm.Expr("GREATEST(user_token_cutoffs.revoked_before, EXCLUDED.revoked_before)")},
+			{Column: clause.Column{Name: "expires_at"}, Value: gorm.Expr("GREATEST(user_token_cutoffs.expires_at, EXCLUDED.expires_at)")},
+			{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("EXCLUDED.updated_at")},
+		},
+	}).Create(&cutoff).Error
+	if err != nil {
+		log.Println("❌ Failed to revoke user tokens:", err)
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresTokenRevocationStore) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	now := time.Now()
+
+	if jti != "" {
+		var count int64
+		if err := s.db.Model(&models.RevokedToken{}).Where("jti = ? AND expires_at > ?", jti, now).Count(&count).Error; err != nil {
+			log.Println("❌ Failed to check revoked token:", err)
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+
+	var cutoffs []models.UserTokenCutoff
+	if err := s.db.Where("user_id = ? AND expires_at > ?", userID, now).Limit(1).Find(&cutoffs).Error; err != nil {
+		log.Println("❌ Failed to check token cutoff:", err)
+		return false, err
+	}
+	if len(cutoffs) > 0 && issuedAt.Before(cutoffs[0].RevokedBefore) {
+		return true, nil
+	}
+	return false, nil
+}
+
+func (s *PostgresTokenRevocationStore) DeleteExpired() error {
+	now := time.Now()
+	if err := s.db.Where("expires_at <= ?", now).Delete(&models.RevokedToken{}).Error; err != nil {
+		log.Println("❌ Failed to delete expired revoked tokens:", err)
+		return err
+	}
+	if err := s.db.Where("expires_at <= ?", now).Delete(&models.UserTokenCutoff{}).Error; err != nil {
+		log.Println("❌ Failed to delete expired token cutoffs:", err)
+		return err
+	}
+	return nil
+}
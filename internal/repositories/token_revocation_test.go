@@ -0,0 +1,35 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryTokenRevocationStoreCutoffIsExact(t *testing.T) {
+	store := NewMemoryTokenRevocationStore()
+	cutoff := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	if err := store.RevokeUserTokensBefore(1, cutoff); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{cutoff.Add(-time.Millisecond), true},
+		{cutoff, false},
+		// Issued later within the same second as the cutoff.
+		{cutoff.Add(time.Millisecond), false},
+	} {
+		revoked, err := store.IsRevoked("", 1, tc.issuedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if revoked != tc.revoked {
+			t.Fatalf("token issued %v relative to the cutoff: expected revoked=%v", tc.issuedAt.Sub(cutoff), tc.revoked)
+		}
+	}
+	if revoked, _ := store.IsRevoked("", 2, cutoff.Add(-time.Millisecond)); revoked {
+		t.Fatal("the cutoff applied to another user")
+	}
+}
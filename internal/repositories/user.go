@@ -0,0 +1,301 @@
+package repositories
+
+import (
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository stores user accounts. A missing user is reported as
+// gorm.ErrRecordNotFound and a taken username or external ID as gorm.ErrDuplicatedKey.
+type UserRepository interface {
+	Create(user *models.User) (uint, error)
+	GetByID(id uint) (*models.User, error)
+	GetByUsername(username string) (*models.User, error)
+	GetByExternalID(externalID string) (*models.User, error)
+	// GetAll returns one page of the users by ID along with their total number.
+	GetAll(limit, offset int) ([]models.User, int, error)
+	Count() (int, error)
+	// Update persists the given columns of a user.
+	Update(id uint, fields map[string]interface{}) error
+	// AdvanceTOTPCounter records the time step of an accepted TOTP code. It only moves the
+	// counter forward, so a code can be accepted once even under concurrent requests; it
+	// returns gorm.ErrRecordNotFound when the code's step was already used.
+	AdvanceTOTPCounter(id uint, counter int64) error
+	Delete(id uint) error
+}
+
+// NewUserRepository keeps accounts next to the movies, as configured by MOVIE_STORE.
+func NewUserRepository(cfg *config.Config, db *gorm.DB) UserRepository {
+	if cfg.MovieStore == "memory" {
+		return NewMemoryUserRepository()
+	}
+	return NewPostgresUserRepository(db)
+}
+
+type PostgresUserRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresUserRepository(db *gorm.DB) *PostgresUserRepository {
+	return &PostgresUserRepository{db: db}
+}
+
+func (r *PostgresUserRepository) Create(user *models.User) (uint, error) {
+	if err := r.db.Create(user).Error; err != nil {
+		log.Println("❌ Failed to create user:", err)
+		return 0, err
+	}
+	return user.ID, nil
+}
+
+func (r *PostgresUserRepository) GetByID(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+		log.Println("❌ User not found:", err)
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) GetByUsername(username string) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "username = ?", username).Error; err != nil {
+		log.Println("❌ User not found:", err)
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) GetByExternalID(externalID string) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "external_id = ?", externalID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *PostgresUserRepository) GetAll(limit, offset int) ([]models.User, int, error) {
+	var users []models.User
+	var totalCount int64
+
+	query := r.db.Model(&models.User{})
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count users:", err)
+		return nil, 0, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if err := query.Order("id ASC").Offset(offset).Find(&users).Error; err != nil {
+		log.Println("❌ Failed to retrieve users:", err)
+		return nil, 0, err
+	}
+
+	return users, int(totalCount), nil
+}
+
+func (r *PostgresUserRepository) Count() (int, error) {
+	var totalCount int64
+	if err := r.db.Model(&models.User{}).Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count users:", err)
+		return 0, err
+	}
+	return int(totalCount), nil
+}
+
+// Update persists the given columns of a user. Using a map keeps zero values
+// such as totp_enabled=false or totp_last_counter=0 from being skipped by GORM.
+func (r *PostgresUserRepository) Update(id uint, fields map[string]interface{}) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields)
+	if result.Error != nil {
+		log.Println("❌ Failed to update user:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) AdvanceTOTPCounter(id uint, counter int64) error {
+	result := r.db.Model(&models.User{}).
+		Where("id = ? AND totp_last_counter < ?", id, counter).
+		Update("totp_last_counter", counter)
+	if result.Error != nil {
+		log.Println("❌ Failed to update TOTP counter:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *PostgresUserRepository) Delete(id uint) error {
+	result := r.db.Where("id = ?", id).Delete(&models.User{})
+	if result.Error != nil {
+		log.Println("❌ Failed to soft delete user:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+type MemoryUserRepository struct {
+	mu     sync.RWMutex
+	users  map[uint]*models.User
+	nextID uint
+}
+
+func NewMemoryUserRepository() *MemoryUserRepository {
+	return &MemoryUserRepository{users: make(map[uint]*models.User), nextID: 1}
+}
+
+func (r *MemoryUserRepository) Create(user *models.User) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username ||
+			(user.ExternalID != nil && existing.ExternalID != nil && *existing.ExternalID == *user.ExternalID) {
+			return 0, gorm.ErrDuplicatedKey
+		}
+	}
+	if user.Role == "" {
+		user.Role = models.RoleViewer
+	}
+	now := time.Now()
+	user.ID = r.nextID
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.nextID++
+
+	stored := *user
+	r.users[user.ID] = &stored
+	return user.ID, nil
+}
+
+func (r *MemoryUserRepository) GetByID(id uint) (*models.User, error) {
+	return r.find(func(user *models.User) bool { return user.ID == id })
+}
+
+func (r *MemoryUserRepository) GetByUsername(username string) (*models.User, error) {
+	return r.find(func(user *models.User) bool { return user.Username == username })
+}
+
+func (r *MemoryUserRepository) GetByExternalID(externalID string) (*models.User, error) {
+	return r.find(func(user *models.User) bool { return user.ExternalID != nil && *user.ExternalID == externalID })
+}
+
+func (r *MemoryUserRepository) GetAll(limit, offset int) ([]models.User, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]models.User, 0, len(r.users))
+	for _, user := range r.users {
+		users = append(users, *user)
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	total := len(users)
+	if offset >= total {
+		return []models.User{}, total, nil
+	}
+	users = users[offset:]
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+	return users, total, nil
+}
+
+func (r *MemoryUserRepository) Count() (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.users), nil
+}
+
+// Update applies the columns the services change; any other column is an error.
+func (r *MemoryUserRepository) Update(id uint, fields map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, found := r.users[id]
+	if !found {
+		return gorm.ErrRecordNotFound
+	}
+	updated := *user
+	for column, value := range fields {
+		var ok bool
+		switch column {
+		case "password_hash":
+			updated.PasswordHash, ok = value.(string)
+		case "role":
+			updated.Role, ok = value.(string)
+		case "totp_secret":
+			updated.TOTPSecret, ok = value.(string)
+		case "totp_enabled":
+			updated.TOTPEnabled, ok = value.(bool)
+		case "totp_last_counter":
+			switch counter := value.(type) {
+			case int:
+				updated.TOTPLastCounter, ok = int64(counter), true
+			case int64:
+				updated.TOTPLastCounter, ok = counter, true
+			}
+		}
+		if !ok {
+			return fmt.Errorf("cannot update user column %s to %v", column, value)
+		}
+	}
+	updated.UpdatedAt = time.Now()
+	r.users[id] = &updated
+	return nil
+}
+
+func (r *MemoryUserRepository) AdvanceTOTPCounter(id uint, counter int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.TOTPLastCounter >= counter {
+		return gorm.ErrRecordNotFound
+	}
+	user.TOTPLastCounter = counter
+	return nil
+}
+
+// Delete forgets the user. Postgres only soft deletes accounts, but nothing reads
+// deleted ones back there either.
+func (r *MemoryUserRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *MemoryUserRepository) find(match func(user *models.User) bool) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if match(user) {
+			found := *user
+			return &found, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
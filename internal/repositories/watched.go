@@ -0,0 +1,197 @@
+package repositories
+
+import (
+	"itv-task/config"
+	"itv-task/internal/models"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// WatchHistoryRepository stores the log of movies users watched. Entries of movies in
+// the trash are kept but left out of listings. A missing entry or movie is reported as
+// gorm.ErrRecordNotFound.
+type WatchHistoryRepository interface {
+	Create(entry *models.WatchedEntry) (*models.WatchedEntry, error)
+	// GetByUser returns one page of the user's visible entries, most recently watched
+	// first, along with their total number.
+	GetByUser(userID uint, limit, offset int) ([]models.WatchedResponse, int, error)
+	GetByID(id uint) (*models.WatchedEntry, error)
+	Delete(id uint) error
+}
+
+// NewWatchHistoryRepository keeps the watch log next to the movies, as configured by MOVIE_STORE.
+func NewWatchHistoryRepository(cfg *config.Config, db *gorm.DB, movies MovieRepository) (WatchHistoryRepository, error) {
+	if cfg.MovieStore == "memory" {
+		memory, err := memoryMovies(movies)
+		if err != nil {
+			return nil, err
+		}
+		return NewMemoryWatchHistoryRepository(memory), nil
+	}
+	return NewPostgresWatchHistoryRepository(db), nil
+}
+
+type PostgresWatchHistoryRepository struct {
+	db *gorm.DB
+}
+
+func NewPostgresWatchHistoryRepository(db *gorm.DB) *PostgresWatchHistoryRepository {
+	return &PostgresWatchHistoryRepository{db: db}
+}
+
+func (r *PostgresWatchHistoryRepository) Create(entry *models.WatchedEntry) (*models.WatchedEntry, error) {
+	created := *entry
+	if err := r.db.Omit(clause.Associations).Create(&created).Error; err != nil {
+		log.Println("❌ Failed to log watched movie:", err)
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *PostgresWatchHistoryRepository) GetByUser(userID uint, limit, offset int) ([]models.WatchedResponse, int, error) {
+	entries := []models.WatchedResponse{}
+	var totalCount int64
+
+	query := r.db.Table("watched_entries").
+		Joins("JOIN movies ON movies.id = watched_entries.movie_id AND movies.deleted_at IS NULL").
+		Where("watched_entries.user_id = ?", userID)
+	if err := query.Count(&totalCount).Error; err != nil {
+		log.Println("❌ Failed to count watched movies:", err)
+		return nil, 0, err
+	}
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Select("watched_entries.id, watched_entries.movie_id, movies.title, movies.year, watched_entries.watched_at").
+		Order("watched_entries.watched_at DESC, watched_entries.id DESC").
+		Offset(offset).
+		Scan(&entries).Error
+	if err != nil {
+		log.Println("❌ Failed to retrieve watched movies:", err)
+		return nil, 0, err
+	}
+	return entries, int(totalCount), nil
+}
+
+func (r *PostgresWatchHistoryRepository) GetByID(id uint) (*models.WatchedEntry, error) {
+	var entry models.WatchedEntry
+	if err := r.db.First(&entry, id).Error; err != nil {
+		log.Println("❌ Watched entry not found:", err)
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (r *PostgresWatchHistoryRepository) Delete(id uint) error {
+	result := r.db.Delete(&models.WatchedEntry{}, id)
+	if result.Error != nil {
+		log.Println("❌ Failed to delete watched entry:", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MemoryWatchHistoryRepository keeps the watch log in process memory.
+type MemoryWatchHistoryRepository struct {
+	mu      sync.RWMutex
+	entries map[uint]*models.WatchedEntry
+	movies  *MemoryMovieRepository
+	nextID  uint
+}
+
+func NewMemoryWatchHistoryRepository(movies *MemoryMovieRepository) *MemoryWatchHistoryRepository {
+	return &MemoryWatchHistoryRepository{entries: make(map[uint]*models.WatchedEntry), movies: movies, nextID: 1}
+}
+
+func (r *MemoryWatchHistoryRepository) Create(entry *models.WatchedEntry) (*models.WatchedEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.movies.exists(entry.MovieID) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	stored := *entry
+	stored.ID = r.nextID
+	stored.CreatedAt = time.Now()
+	r.entries[stored.ID] = &stored
+	r.nextID++
+
+	created := stored
+	return &created, nil
+}
+
+func (r *MemoryWatchHistoryRepository) GetByUser(userID uint, limit, offset int) ([]models.WatchedResponse, int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var owned []*models.WatchedEntry
+	var movieIDs []uint
+	for _, entry := range r.entries {
+		if entry.UserID == userID {
+			owned = append(owned, entry)
+			movieIDs = append(movieIDs, entry.MovieID)
+		}
+	}
+	movies := r.movies.activeMovies(movieIDs)
+
+	entries := make([]models.WatchedResponse, 0, len(owned))
+	for _, entry := range owned {
+		if movie, ok := movies[entry.MovieID]; ok {
+			entries = append(entries, models.WatchedResponse{
+				ID:        entry.ID,
+				MovieID:   entry.MovieID,
+				Title:     movie.Title,
+				Year:      movie.Year,
+				WatchedAt: entry.WatchedAt,
+			})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].WatchedAt.Equal(entries[j].WatchedAt) {
+			return entries[i].WatchedAt.After(entries[j].WatchedAt)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+
+	total := len(entries)
+	if offset >= total {
+		return []models.WatchedResponse{}, total, nil
+	}
+	entries = entries[offset:]
+	if limit > 0 && limit < len(entries) {
+		entries = entries[:limit]
+	}
+	return entries, total, nil
+}
+
+func (r *MemoryWatchHistoryRepository) GetByID(id uint) (*models.WatchedEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	entry, ok := r.entries[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	found := *entry
+	return &found, nil
+}
+
+func (r *MemoryWatchHistoryRepository) Delete(id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[id]; !ok {
+		return gorm.ErrRecordNotFound
+	}
+	delete(r.entries, id)
+	return nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"itv-task/pkg/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// apiKeyPrefix marks our keys so they are recognisable in logs and secret scanners.
+const apiKeyPrefix = "itv_"
+
+// lastUsedResolution limits how often last_used_at is written for a busy key.
+const lastUsedResolution = time.Minute
+
+var (
+	ErrInvalidAPIKey      = errors.New("invalid API key")
+	ErrAPIKeyExpiryInPast = errors.New("expires_at must be in the future")
+)
+
+type APIKeyService struct {
+	repo repositories.APIKeyRepository
+	log  logger.Logger
+}
+
+func NewAPIKeyService(repo repositories.APIKeyRepository, log logger.Logger) *APIKeyService {
+	return &APIKeyService{repo: repo, log: log}
+}
+
+// CreateAPIKey generates a new key. The plain key is only part of this response.
+func (s *APIKeyService) CreateAPIKey(request models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	s.log.Info("Creating API key", zap.String("name", request.Name), zap.Strings("scopes", request.Scopes))
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInPast
+	}
+
+	secret, err := utils.RandomHex(20)
+	if err != nil {
+		return nil, err
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := &models.APIKey{
+		Name:        request.Name,
+		Prefix:      rawKey[:len(apiKeyPrefix)+8],
+		KeyHash:     utils.HashToken(rawKey),
+		Scopes:      strings.Join(uniqueScopes(request.Scopes), ","),
+		CreatedByID: request.CreatedByID,
+		ExpiresAt:   request.ExpiresAt,
+	}
+	if err := s.repo.Create(key); err != nil {
+		s.log.Error("Failed to create API key", zap.String("name", request.Name), zap.Error(err))
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{APIKeyResponse: toAPIKeyResponse(key), Key: rawKey}, nil
+}
+
+func (s *APIKeyService) GetAllAPIKeys() (models.APIKeyListResponse, error) {
+	keys, err := s.repo.GetAll()
+	if err != nil {
+		s.log.Error("Failed to fetch API keys", zap.Error(err))
+		return models.APIKeyListResponse{}, err
+	}
+
+	response := models.APIKeyListResponse{APIKeys: make([]models.APIKeyResponse, 0, len(keys)), Count: len(keys)}
+	for i := range keys {
+		response.APIKeys = append(response.APIKeys, toAPIKeyResponse(&keys[i]))
+	}
+	return response, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(id uint) error {
+	s.log.Info("Revoking API key", zap.Uint("id", id))
+
+	if err := s.repo.Revoke(id); err != nil {
+		s.log.Error("Failed to revoke API key", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AuthenticateAPIKey resolves a raw key presented in the X-API-Key header.
+func (s *APIKeyService) AuthenticateAPIKey(rawKey string) (*models.APIKey, error) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByHash(utils.HashToken(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(key.ID, now); err != nil {
+			s.log.Warn("Failed to record API key use", zap.Uint("id", key.ID), zap.Error(err))
+		}
+		key.LastUsedAt = &now
+	}
+
+	return key, nil
+}
+
+func uniqueScopes(scopes []string) []string {
+	seen := make(map[string]bool, len(scopes))
+	unique := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !seen[scope] {
+			seen[scope] = true
+			unique = append(unique, scope)
+		}
+	}
+	return unique
+}
+
+func toAPIKeyResponse(key *models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -4,60 +4,413 @@ import (
 	"errors"
 	"itv-task/config"
 	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
 	"itv-task/pkg/utils"
+	"time"
 
 	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+var (
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrUsernameTaken       = errors.New("username already taken")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenRevoked = errors.New("refresh token revoked")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidChallenge    = errors.New("invalid or expired two-factor challenge")
+)
+
+// dummyPasswordHash is checked when a login names no password-based account, so that
+// failed logins take as long whether or not the username exists.
+const dummyPasswordHash = "$2a$10$CWkKKaABsHhW22xSEdsbxOIvlHEPzXVK.T4VdXqe2qO8e8GdrULPm"
+
 type AuthService struct {
-	Config *config.Config
+	Config      *config.Config
+	keys        *utils.KeySet
+	userRepo    repositories.UserRepository
+	tokenRepo   repositories.AuthTokenRepository
+	revocations repositories.TokenRevocationStore
+	throttle    *LoginThrottle
+	auditRepo   repositories.AuthAuditRepository
+	twoFactor   *TwoFactorService
+	log         logger.Logger
 }
 
-func NewAuthService(cfg *config.Config) *AuthService {
-	return &AuthService{Config: cfg}
+func NewAuthService(cfg *config.Config, keys *utils.KeySet, userRepo repositories.UserRepository, tokenRepo repositories.AuthTokenRepository,
+	revocations repositories.TokenRevocationStore, throttle *LoginThrottle, auditRepo repositories.AuthAuditRepository,
+	twoFactor *TwoFactorService, log logger.Logger) *AuthService {
+	return &AuthService{Config: cfg, keys: keys, userRepo: userRepo, tokenRepo: tokenRepo, revocations: revocations,
+		throttle: throttle, auditRepo: auditRepo, twoFactor: twoFactor, log: log}
 }
 
-// Login authenticates a user and generates JWT tokens.
+// Login authenticates a user and generates JWT tokens. While the username or client IP
+// is locked out after repeated failures it returns a *LoginLockedError. Users with
+// two-factor authentication get a challenge token instead, see VerifyTwoFactor.
 func (s *AuthService) Login(request models.LoginRequest) (models.LoginResponse, error) {
-	const HardcodedUsername = "admin"
-	const HardcodedPassword = "password123"
+	if wait := s.throttle.Check(request.Username, request.IP); wait > 0 {
+		lockErr := &LoginLockedError{RetryAfter: wait}
+		s.audit(request, models.AuthEventLoginLocked, nil, lockErr.Error())
+		return models.LoginResponse{}, lockErr
+	}
+
+	user, err := s.userRepo.GetByUsername(request.Username)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.LoginResponse{}, err
+	}
+
+	if !checkUserPassword(user, request.Password) {
+		s.log.Warn("Failed login attempt", zap.String("username", request.Username), zap.String("ip", request.IP))
+		s.throttle.RecordFailure(request.Username, request.IP)
+		reason := "invalid password"
+		var userID *uint
+		if user == nil {
+			reason = "unknown username"
+		} else {
+			userID = &user.ID
+		}
+		s.audit(request, models.AuthEventLoginFailure, userID, reason)
+		return models.LoginResponse{}, ErrInvalidCredentials
+	}
 
-	if request.Username != HardcodedUsername || request.Password != HardcodedPassword {
-		return models.LoginResponse{}, errors.New("invalid credentials")
+	if user.TOTPEnabled {
+		// The failure counter is only reset once the second factor is verified too.
+		challenge, err := utils.GenerateChallengeToken(*user, s.keys)
+		if err != nil {
+			return models.LoginResponse{}, err
+		}
+		s.audit(request, models.AuthEventTwoFactorChallenge, &user.ID, "")
+		return models.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
 	}
 
-	accessToken, refreshToken, err := utils.GenerateTokens(request.Username, s.Config)
+	s.throttle.RecordSuccess(request.Username)
+	s.audit(request, models.AuthEventLoginSuccess, &user.ID, "")
+
+	return s.StartSession(*user)
+}
+
+// VerifyTwoFactor completes a login by exchanging the challenge token from Login and a
+// TOTP or recovery code for a token pair. Wrong codes count towards the login lockout.
+func (s *AuthService) VerifyTwoFactor(request models.TwoFactorVerifyRequest) (models.LoginResponse, error) {
+	claims, err := utils.ValidateChallengeToken(request.ChallengeToken, s.keys)
 	if err != nil {
+		return models.LoginResponse{}, ErrInvalidChallenge
+	}
+	userID, ok := utils.UserIDFromClaims(claims)
+	if !ok {
+		return models.LoginResponse{}, ErrInvalidChallenge
+	}
+	username, _ := claims["username"].(string)
+	loginRequest := models.LoginRequest{Username: username, IP: request.IP, UserAgent: request.UserAgent}
+
+	if wait := s.throttle.Check(username, request.IP); wait > 0 {
+		lockErr := &LoginLockedError{RetryAfter: wait}
+		s.audit(loginRequest, models.AuthEventLoginLocked, &userID, lockErr.Error())
+		return models.LoginResponse{}, lockErr
+	}
+
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.LoginResponse{}, ErrInvalidChallenge
+		}
 		return models.LoginResponse{}, err
 	}
+	if !user.TOTPEnabled {
+		return models.LoginResponse{}, ErrInvalidChallenge
+	}
 
-	return models.LoginResponse{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-	}, nil
+	usedRecoveryCode, err := s.twoFactor.VerifyCode(user, request.Code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.throttle.RecordFailure(username, request.IP)
+			s.audit(loginRequest, models.AuthEventTwoFactorFailure, &user.ID, "invalid code")
+		}
+		return models.LoginResponse{}, err
+	}
+
+	s.throttle.RecordSuccess(username)
+	if usedRecoveryCode {
+		s.audit(loginRequest, models.AuthEventRecoveryCodeUsed, &user.ID, "")
+	}
+	s.audit(loginRequest, models.AuthEventLoginSuccess, &user.ID, "")
+
+	return s.StartSession(*user)
+}
+
+// GetAuditLog lists auth audit log entries, newest first.
+func (s *AuthService) GetAuditLog(username, event string, limit, offset int) (models.AuthAuditLogListResponse, error) {
+	entries, count, err := s.auditRepo.GetAll(username, event, limit, offset)
+	if err != nil {
+		return models.AuthAuditLogListResponse{}, err
+	}
+
+	response := models.AuthAuditLogListResponse{Entries: make([]models.AuthAuditLogResponse, 0, len(entries)), Count: count}
+	for _, entry := range entries {
+		response.Entries = append(response.Entries, models.AuthAuditLogResponse{
+			ID:        entry.ID,
+			Event:     entry.Event,
+			Username:  entry.Username,
+			UserID:    entry.UserID,
+			IP:        entry.IP,
+			UserAgent: entry.UserAgent,
+			Reason:    entry.Reason,
+			CreatedAt: entry.CreatedAt,
+		})
+	}
+	return response, nil
+}
+
+// audit records a login event. Failing to write it must not block the login itself.
+func (s *AuthService) audit(request models.LoginRequest, event string, userID *uint, reason string) {
+	userAgent := request.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	username := request.Username
+	if len(username) > 255 {
+		username = username[:255]
+	}
+
+	entry := &models.AuthAuditLog{
+		Event:     event,
+		Username:  username,
+		UserID:    userID,
+		IP:        request.IP,
+		UserAgent: userAgent,
+		Reason:    reason,
+	}
+	if err := s.auditRepo.Create(entry); err != nil {
+		s.log.Error("Failed to write auth audit log", zap.String("event", event), zap.Error(err))
+	}
+}
+
+// StartSession issues a token pair for an authenticated user, starting a new refresh token family.
+func (s *AuthService) StartSession(user models.User) (models.LoginResponse, error) {
+	familyID, err := utils.RandomHex(16)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	response, token, err := s.issueTokens(user, familyID)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+	if err := s.tokenRepo.Create(token); err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	return response, nil
+}
+
+// Register creates an account with the viewer role.
+func (s *AuthService) Register(request models.RegisterRequest) (*models.UserResponse, error) {
+	s.log.Info("Registering user", zap.String("username", request.Username))
+
+	user, err := createUser(s.userRepo, request.Username, request.Password, models.RoleViewer)
+	if err != nil {
+		s.log.Error("Failed to register user", zap.String("username", request.Username), zap.Error(err))
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+// ChangePassword replaces the password of the user after verifying the current one.
+// Wrong passwords count towards the login lockout, which also applies here.
+func (s *AuthService) ChangePassword(request models.ChangePasswordRequest) error {
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return err
+	}
+
+	loginRequest := models.LoginRequest{Username: user.Username, IP: request.IP, UserAgent: request.UserAgent}
+	if wait := s.throttle.Check(user.Username, request.IP); wait > 0 {
+		lockErr := &LoginLockedError{RetryAfter: wait}
+		s.audit(loginRequest, models.AuthEventLoginLocked, &user.ID, lockErr.Error())
+		return lockErr
+	}
+	if !checkUserPassword(user, request.CurrentPassword) {
+		s.log.Warn("Failed password change", zap.Uint("id", user.ID), zap.String("ip", request.IP))
+		s.throttle.RecordFailure(user.Username, request.IP)
+		s.audit(loginRequest, models.AuthEventLoginFailure, &user.ID, "invalid current password")
+		return ErrInvalidCredentials
+	}
+	s.throttle.RecordSuccess(user.Username)
+
+	hash, err := utils.HashPassword(request.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.Update(user.ID, map[string]interface{}{"password_hash": hash}); err != nil {
+		s.log.Error("Failed to change password", zap.Uint("id", user.ID), zap.Error(err))
+		return err
+	}
+
+	s.log.Info("Password changed", zap.Uint("id", user.ID))
+	return nil
 }
 
-// RefreshToken generates a new access token using a refresh token.
+// RefreshToken rotates a refresh token: the presented token is revoked and a new pair
+// from the same family is issued. Presenting an already rotated token revokes the family.
 func (s *AuthService) RefreshToken(request models.RefreshTokenRequest) (models.LoginResponse, error) {
-	claims, err := utils.ValidateToken(request.RefreshToken, true, s.Config)
+	if _, err := utils.ValidateToken(request.RefreshToken, true, s.keys); err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	stored, err := s.tokenRepo.GetByHash(utils.HashToken(request.RefreshToken))
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.LoginResponse{}, ErrInvalidRefreshToken
+		}
 		return models.LoginResponse{}, err
 	}
 
-	username, ok := claims["username"].(string)
-	if !ok {
-		return models.LoginResponse{}, errors.New("invalid token payload")
+	if stored.RevokedAt != nil {
+		if stored.RevokedReason == models.TokenRevokedRotated {
+			return models.LoginResponse{}, s.handleReuse(stored)
+		}
+		return models.LoginResponse{}, ErrRefreshTokenRevoked
+	}
+	if time.Now().After(stored.ExpiresAt) {
+		return models.LoginResponse{}, errors.New("token expired")
+	}
+
+	// Reload the user so deleted accounts cannot refresh and privilege changes apply.
+	user, err := s.userRepo.GetByID(stored.UserID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.LoginResponse{}, ErrInvalidRefreshToken
+		}
+		return models.LoginResponse{}, err
 	}
 
-	accessToken, refreshToken, err := utils.GenerateTokens(username, s.Config)
+	response, token, err := s.issueTokens(*user, stored.FamilyID)
 	if err != nil {
 		return models.LoginResponse{}, err
 	}
+	if err := s.tokenRepo.Rotate(stored.ID, token); err != nil {
+		if errors.Is(err, repositories.ErrTokenAlreadyRevoked) {
+			// Another request rotated this token between our read and write.
+			return models.LoginResponse{}, s.handleReuse(stored)
+		}
+		return models.LoginResponse{}, err
+	}
+
+	return response, nil
+}
+
+// Logout revokes the session (token family) the given refresh token belongs to
+// and denylists the access token used for the call.
+func (s *AuthService) Logout(request models.LogoutRequest) error {
+	stored, err := s.tokenRepo.GetByHash(utils.HashToken(request.RefreshToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	if stored.UserID != request.UserID {
+		return ErrInvalidRefreshToken
+	}
+
+	s.log.Info("Logging out session", zap.Uint("user_id", request.UserID), zap.String("family_id", stored.FamilyID))
+	if err := s.tokenRepo.RevokeFamily(stored.FamilyID, models.TokenRevokedLogout); err != nil {
+		return err
+	}
+
+	if request.AccessTokenJTI == "" {
+		return nil
+	}
+	return s.revocations.RevokeToken(request.AccessTokenJTI, request.UserID, request.AccessTokenExpiresAt)
+}
+
+// LogoutAll revokes every refresh and access token of the user, ending all sessions.
+func (s *AuthService) LogoutAll(userID uint) error {
+	s.log.Info("Logging out all sessions", zap.Uint("user_id", userID))
+	if err := s.tokenRepo.RevokeAllForUser(userID, models.TokenRevokedLogoutAll); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUserTokensBefore(userID, time.Now())
+}
+
+// RevokeAccessToken denylists a single access token by jti. Without an explicit expiry the
+// entry is kept for the full access token TTL, which outlives any token issued so far.
+func (s *AuthService) RevokeAccessToken(request models.RevokeTokenRequest) error {
+	expiresAt := time.Now().Add(config.AccessTokenTTL)
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+
+	s.log.Info("Revoking access token", zap.String("jti", request.JTI), zap.Uint("user_id", request.UserID))
+	return s.revocations.RevokeToken(request.JTI, request.UserID, expiresAt)
+}
+
+// RevokeUserTokens invalidates every access and refresh token the user was issued before
+// the given time (now when omitted).
+func (s *AuthService) RevokeUserTokens(request models.RevokeUserTokensRequest) error {
+	if _, err := s.userRepo.GetByID(request.UserID); err != nil {
+		return err
+	}
+
+	before := time.Now()
+	if request.Before != nil {
+		before = *request.Before
+	}
+
+	s.log.Info("Revoking user tokens", zap.Uint("user_id", request.UserID), zap.Time("before", before))
+	if err := s.tokenRepo.RevokeForUserBefore(request.UserID, before, models.TokenRevokedByAdmin); err != nil {
+		return err
+	}
+	return s.revocations.RevokeUserTokensBefore(request.UserID, before)
+}
+
+func (s *AuthService) handleReuse(stored *models.AuthToken) error {
+	s.log.Warn("Refresh token reuse detected, revoking token family",
+		zap.Uint("user_id", stored.UserID), zap.String("family_id", stored.FamilyID))
+	if err := s.tokenRepo.RevokeFamily(stored.FamilyID, models.TokenRevokedReuseDetected); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens signs a new token pair and prepares the refresh token record for storage.
+func (s *AuthService) issueTokens(user models.User, familyID string) (models.LoginResponse, *models.AuthToken, error) {
+	accessToken, refreshToken, err := utils.GenerateTokens(user, s.keys)
+	if err != nil {
+		return models.LoginResponse{}, nil, err
+	}
+
+	token := &models.AuthToken{
+		UserID:    user.ID,
+		Username:  user.Username,
+		TokenHash: utils.HashToken(refreshToken),
+		FamilyID:  familyID,
+		ExpiresAt: time.Now().Add(config.RefreshTokenTTL),
+	}
 
 	return models.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-	}, nil
+	}, token, nil
+}
+
+// JWKS returns the public keys other services use to verify issued tokens.
+func (s *AuthService) JWKS() utils.JWKS {
+	return s.keys.JWKS()
+}
+
+// checkUserPassword reports whether the password matches the user's. Without a user, or
+// for federated users who have no password, it still spends a bcrypt comparison.
+func checkUserPassword(user *models.User, password string) bool {
+	if user == nil || user.PasswordHash == "" {
+		utils.CheckPassword(dummyPasswordHash, password)
+		return false
+	}
+	return utils.CheckPassword(user.PasswordHash, password)
 }
 
 // ProvideAuthService is for fx dependency injection.
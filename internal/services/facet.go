@@ -0,0 +1,139 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxFacetCacheEntries bounds memory use. Once it is reached, expired entries are swept
+// and, if none had expired, the oldest entry makes room for the new one.
+const maxFacetCacheEntries = 1000
+
+type facetCacheEntry struct {
+	facets  models.MovieFacetsResponse
+	expires time.Time
+}
+
+// FacetService counts movies along the facet dimensions. Counts are cached per filter for
+// MOVIE_FACETS_CACHE_TTL, so they can trail changes to the catalogue by that long.
+type FacetService struct {
+	movies repositories.MovieRepository
+	ttl    time.Duration
+	log    logger.Logger
+
+	mu    sync.Mutex
+	cache map[string]facetCacheEntry
+}
+
+func NewFacetService(cfg *config.Config, movies repositories.MovieRepository, log logger.Logger) *FacetService {
+	return &FacetService{movies: movies, ttl: cfg.MovieFacetsCacheTTL, log: log, cache: make(map[string]facetCacheEntry)}
+}
+
+// GetMovieFacets counts the movies matching the filter by decade, year, director, genre
+// and rating, keeping the limit most frequent values of each dimension.
+func (s *FacetService) GetMovieFacets(filter models.MovieFilter, limit int) (models.MovieFacetsResponse, error) {
+	key := facetCacheKey(filter)
+	facets, ok := s.cached(key)
+	if !ok {
+		var err error
+		facets, err = s.movies.Facets(filter)
+		if err != nil {
+			s.log.Error("Failed to count movie facets", zap.Any("request", filter), zap.Error(err))
+			return models.MovieFacetsResponse{}, err
+		}
+		for _, values := range []*[]models.FacetValue{&facets.Decades, &facets.Years, &facets.Directors, &facets.Genres, &facets.Ratings} {
+			sortFacetValues(*values)
+		}
+		s.store(key, facets)
+	}
+
+	facets.Decades = topFacetValues(facets.Decades, limit)
+	facets.Years = topFacetValues(facets.Years, limit)
+	facets.Directors = topFacetValues(facets.Directors, limit)
+	facets.Genres = topFacetValues(facets.Genres, limit)
+	facets.Ratings = topFacetValues(facets.Ratings, limit)
+	return facets, nil
+}
+
+func (s *FacetService) cached(key string) (models.MovieFacetsResponse, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.cache[key]
+	if !ok || time.Now().After(entry.expires) {
+		return models.MovieFacetsResponse{}, false
+	}
+	return entry.facets, true
+}
+
+func (s *FacetService) store(key string, facets models.MovieFacetsResponse) {
+	if s.ttl <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := s.cache[key]; !ok && len(s.cache) >= maxFacetCacheEntries {
+		s.evict(now)
+	}
+	s.cache[key] = facetCacheEntry{facets: facets, expires: now.Add(s.ttl)}
+}
+
+// evict sweeps the expired entries or, if there are none, drops the oldest one, which is
+// the one expiring first since every entry lives for the same TTL.
+func (s *FacetService) evict(now time.Time) {
+	oldestKey, oldest := "", time.Time{}
+	for cachedKey, entry := range s.cache {
+		if now.After(entry.expires) {
+			delete(s.cache, cachedKey)
+		} else if oldestKey == "" || entry.expires.Before(oldest) {
+			oldestKey, oldest = cachedKey, entry.expires
+		}
+	}
+	if len(s.cache) >= maxFacetCacheEntries {
+		delete(s.cache, oldestKey)
+	}
+}
+
+// facetCacheKey identifies the filter by the criteria facets count by. The sort, paging,
+// cursor and fields only shape listings, so they are left out, and the filter expression
+// is keyed by its canonical text rather than its pointers.
+func facetCacheKey(filter models.MovieFilter) string {
+	genres := slices.Clone(filter.Genres)
+	sort.Strings(genres)
+	expression := ""
+	if filter.Expression != nil {
+		expression = filter.Expression.String()
+	}
+	return fmt.Sprintf("title=%q director=%q year=%d genres=%q match=%q rating=%g filter=%q",
+		filter.Title, filter.Director, filter.Year, genres, filter.GenreMatch, filter.MinRating, expression)
+}
+
+// sortFacetValues orders values by count, highest first, then by value.
+func sortFacetValues(values []models.FacetValue) {
+	slices.SortFunc(values, func(a, b models.FacetValue) int {
+		if a.Count != b.Count {
+			return cmp.Compare(b.Count, a.Count)
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+}
+
+// topFacetValues returns the first limit values; the cached slice is never modified.
+func topFacetValues(values []models.FacetValue, limit int) []models.FacetValue {
+	if len(values) > limit {
+		return values[:limit:limit]
+	}
+	return values
+}
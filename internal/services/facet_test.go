@@ -0,0 +1,127 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+)
+
+// countingFacets counts the facet queries that reach the repository.
+type countingFacets struct {
+	repositories.MovieRepository
+	calls int
+}
+
+func (r *countingFacets) Facets(filter models.MovieFilter) (models.MovieFacetsResponse, error) {
+	r.calls++
+	return models.MovieFacetsResponse{Years: []models.FacetValue{{Value: fmt.Sprint(filter.Year), Count: 1}}}, nil
+}
+
+func newTestFacetService() (*FacetService, *countingFacets) {
+	movies := &countingFacets{}
+	cfg := &config.Config{MovieFacetsCacheTTL: time.Minute}
+	return NewFacetService(cfg, movies, logger.New(logger.LevelError, "test")), movies
+}
+
+func TestFacetCacheKeyIgnoresListingOptions(t *testing.T) {
+	filter := models.MovieFilter{Director: "nolan", Genres: []string{"drama", "sci-fi"}, MinRating: 7}
+	listing := filter
+	listing.Genres = []string{"sci-fi", "drama"}
+	listing.Sort = []models.SortField{{Field: "year", Desc: true}}
+	listing.Limit, listing.Offset = 5, 10
+	listing.Cursor = &models.MovieCursor{Sort: "-year", Keys: []string{"2010"}, ID: 3}
+	listing.Fields = []string{"title", "year"}
+	listing.SkipTotal = true
+
+	if facetCacheKey(filter) != facetCacheKey(listing) {
+		t.Fatalf("listing options changed the key:\n%s\n%s", facetCacheKey(filter), facetCacheKey(listing))
+	}
+
+	// Two cursors at the same position are different pointers.
+	other := listing
+	other.Cursor = &models.MovieCursor{Sort: "-year", Keys: []string{"2010"}, ID: 3}
+	if facetCacheKey(listing) != facetCacheKey(other) {
+		t.Fatal("the cursor pointer changed the key")
+	}
+
+	for _, changed := range []models.MovieFilter{
+		{Director: "nolan", Genres: []string{"drama"}, MinRating: 7},
+		{Director: "nolan", Genres: []string{"drama", "sci-fi"}, GenreMatch: models.GenreMatchAll, MinRating: 7},
+		{Director: "nolan", Genres: []string{"drama", "sci-fi"}, MinRating: 7.5},
+		{Title: "nolan", Genres: []string{"drama", "sci-fi"}, MinRating: 7},
+	} {
+		if facetCacheKey(changed) == facetCacheKey(filter) {
+			t.Fatalf("different criteria share a key: %+v", changed)
+		}
+	}
+}
+
+func TestFacetCacheEvictsOldestWhenFull(t *testing.T) {
+	service, movies := newTestFacetService()
+
+	for year := 1; year <= maxFacetCacheEntries; year++ {
+		if _, err := service.GetMovieFacets(models.MovieFilter{Year: year}, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Set the first entry apart as the oldest, whatever the clock resolution.
+	service.mu.Lock()
+	first := service.cache[facetCacheKey(models.MovieFilter{Year: 1})]
+	first.expires = first.expires.Add(-time.Second)
+	service.cache[facetCacheKey(models.MovieFilter{Year: 1})] = first
+	service.mu.Unlock()
+
+	if _, err := service.GetMovieFacets(models.MovieFilter{Year: maxFacetCacheEntries + 1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.cache) != maxFacetCacheEntries {
+		t.Fatalf("expected the cache to stay at %d entries, got %d", maxFacetCacheEntries, len(service.cache))
+	}
+
+	calls := movies.calls
+	if _, err := service.GetMovieFacets(models.MovieFilter{Year: maxFacetCacheEntries + 1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := service.GetMovieFacets(models.MovieFilter{Year: 2}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if movies.calls != calls {
+		t.Fatal("expected the new and the younger entries to be cached")
+	}
+	if _, err := service.GetMovieFacets(models.MovieFilter{Year: 1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if movies.calls != calls+1 {
+		t.Fatal("expected the oldest entry to have been evicted")
+	}
+}
+
+func TestFacetCacheSweepsExpiredEntries(t *testing.T) {
+	service, _ := newTestFacetService()
+
+	for year := 1; year <= maxFacetCacheEntries; year++ {
+		if _, err := service.GetMovieFacets(models.MovieFilter{Year: year}, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service.mu.Lock()
+	for key, entry := range service.cache {
+		if key != facetCacheKey(models.MovieFilter{Year: 1}) {
+			entry.expires = time.Now().Add(-time.Second)
+			service.cache[key] = entry
+		}
+	}
+	service.mu.Unlock()
+
+	if _, err := service.GetMovieFacets(models.MovieFilter{Year: maxFacetCacheEntries + 1}, 10); err != nil {
+		t.Fatal(err)
+	}
+	if len(service.cache) != 2 {
+		t.Fatalf("expected only the live entries to remain, got %d", len(service.cache))
+	}
+}
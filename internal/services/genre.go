@@ -0,0 +1,97 @@
+package services
+
+import (
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+type GenreService struct {
+	repo   repositories.GenreRepository
+	movies repositories.MovieRepository
+	log    logger.Logger
+}
+
+func NewGenreService(repo repositories.GenreRepository, movies repositories.MovieRepository, log logger.Logger) *GenreService {
+	return &GenreService{repo: repo, movies: movies, log: log}
+}
+
+func (s *GenreService) CreateGenre(request *models.GenreRequest) (*models.GenreResponse, error) {
+	s.log.Info("Creating genre", zap.Any("request", request))
+	genre, err := s.repo.Create(request)
+	if err != nil {
+		s.log.Error("Failed to create genre", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	response := toGenreResponse(genre, 0)
+	return &response, nil
+}
+
+// GetAllGenres lists every genre with the number of movies matching the filter that
+// have it, which makes the list usable as a facet next to GET /movies.
+func (s *GenreService) GetAllGenres(filter models.MovieFilter) (models.GenreListResponse, error) {
+	genres, err := s.repo.GetAll()
+	if err != nil {
+		s.log.Error("Failed to fetch genres", zap.Error(err))
+		return models.GenreListResponse{}, err
+	}
+	counts, err := s.movies.GenreCounts(filter)
+	if err != nil {
+		s.log.Error("Failed to count movies by genre", zap.Any("request", filter), zap.Error(err))
+		return models.GenreListResponse{}, err
+	}
+
+	response := models.GenreListResponse{Genres: make([]models.GenreResponse, 0, len(genres)), Count: len(genres)}
+	for i := range genres {
+		response.Genres = append(response.Genres, toGenreResponse(&genres[i], counts[genres[i].ID]))
+	}
+	return response, nil
+}
+
+func (s *GenreService) GetGenreByID(id uint) (*models.GenreResponse, error) {
+	genre, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch genre", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	counts, err := s.movies.GenreCounts(models.MovieFilter{Genres: []string{genre.Slug}})
+	if err != nil {
+		s.log.Error("Failed to count movies by genre", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	response := toGenreResponse(genre, counts[genre.ID])
+	return &response, nil
+}
+
+func (s *GenreService) UpdateGenre(id uint, request *models.GenreRequest) (*models.GenreResponse, error) {
+	s.log.Info("Updating genre", zap.Uint("id", id), zap.Any("request", request))
+	if _, err := s.repo.Update(id, request); err != nil {
+		s.log.Error("Failed to update genre", zap.Uint("id", id), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return s.GetGenreByID(id)
+}
+
+// DeleteGenre removes the genre and unlinks it from every movie.
+func (s *GenreService) DeleteGenre(id uint) error {
+	s.log.Info("Deleting genre", zap.Uint("id", id))
+	if err := s.repo.Delete(id); err != nil {
+		s.log.Error("Failed to delete genre", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func toGenreResponse(genre *models.Genre, movieCount int) models.GenreResponse {
+	return models.GenreResponse{
+		ID:         genre.ID,
+		Name:       genre.Name,
+		Slug:       genre.Slug,
+		MovieCount: movieCount,
+		CreatedAt:  genre.CreatedAt,
+	}
+}
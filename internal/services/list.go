@@ -0,0 +1,281 @@
+package services
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrListOwnerRequired is returned when a caller that is not a user, such as an API
+	// key, uses lists or the watch log.
+	ErrListOwnerRequired = errors.New("lists belong to users")
+	// ErrNotListOwner is returned when a user changes someone else's public list.
+	ErrNotListOwner = errors.New("list belongs to another user")
+	// ErrDefaultList is returned when renaming or deleting the watchlist, or naming
+	// another list after it.
+	ErrDefaultList = errors.New("the watchlist cannot be renamed or deleted")
+)
+
+// ListService manages users' movie lists and watch logs. Lists are addressed by ID, with
+// ID 0 standing for the caller's watchlist. Private lists of other users are reported as
+// missing rather than forbidden so their existence does not leak.
+type ListService struct {
+	lists   repositories.MovieListRepository
+	watched repositories.WatchHistoryRepository
+	movies  repositories.MovieRepository
+	log     logger.Logger
+}
+
+func NewListService(lists repositories.MovieListRepository, watched repositories.WatchHistoryRepository,
+	movies repositories.MovieRepository, log logger.Logger) *ListService {
+	return &ListService{lists: lists, watched: watched, movies: movies, log: log}
+}
+
+// GetMyLists returns the caller's lists, creating the watchlist on first use.
+func (s *ListService) GetMyLists(actor models.Actor) (models.ListsResponse, error) {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return models.ListsResponse{}, err
+	}
+	if _, err := s.lists.GetDefault(userID); err != nil {
+		s.log.Error("Failed to fetch watchlist", zap.Uint("user_id", userID), zap.Error(err))
+		return models.ListsResponse{}, err
+	}
+	return s.getLists(userID, false)
+}
+
+// GetPublicLists returns another user's public lists.
+func (s *ListService) GetPublicLists(userID uint) (models.ListsResponse, error) {
+	return s.getLists(userID, true)
+}
+
+func (s *ListService) getLists(userID uint, publicOnly bool) (models.ListsResponse, error) {
+	lists, err := s.lists.GetByUser(userID, publicOnly)
+	if err != nil {
+		s.log.Error("Failed to fetch lists", zap.Uint("user_id", userID), zap.Error(err))
+		return models.ListsResponse{}, err
+	}
+	return models.ListsResponse{Lists: lists, Count: len(lists)}, nil
+}
+
+func (s *ListService) CreateList(request *models.ListRequest, actor models.Actor) (*models.ListResponse, error) {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return nil, err
+	}
+	if request.Name == models.DefaultListName {
+		return nil, ErrDefaultList
+	}
+
+	s.log.Info("Creating list", zap.Uint("user_id", userID), zap.Any("request", request))
+	list, err := s.lists.Create(&models.MovieList{UserID: userID, Name: request.Name, Public: request.Public})
+	if err != nil {
+		s.log.Error("Failed to create list", zap.Uint("user_id", userID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return list, nil
+}
+
+// GetList returns a list the caller can see, with its visible entries.
+func (s *ListService) GetList(id uint, actor models.Actor) (*models.ListDetailResponse, error) {
+	list, err := s.list(id, actor, false)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.lists.GetEntries(list.ID)
+	if err != nil {
+		s.log.Error("Failed to fetch list entries", zap.Uint("id", list.ID), zap.Error(err))
+		return nil, err
+	}
+	return &models.ListDetailResponse{ListResponse: *list, Entries: entries}, nil
+}
+
+// UpdateList renames the list and sets its visibility. The watchlist keeps its name.
+func (s *ListService) UpdateList(id uint, request *models.ListRequest, actor models.Actor) (*models.ListResponse, error) {
+	list, err := s.list(id, actor, true)
+	if err != nil {
+		return nil, err
+	}
+	if list.Default != (request.Name == models.DefaultListName) {
+		return nil, ErrDefaultList
+	}
+
+	s.log.Info("Updating list", zap.Uint("id", list.ID), zap.Any("request", request))
+	updated, err := s.lists.Update(list.ID, request)
+	if err != nil {
+		s.log.Error("Failed to update list", zap.Uint("id", list.ID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (s *ListService) DeleteList(id uint, actor models.Actor) error {
+	list, err := s.list(id, actor, true)
+	if err != nil {
+		return err
+	}
+	if list.Default {
+		return ErrDefaultList
+	}
+
+	s.log.Info("Deleting list", zap.Uint("id", list.ID))
+	if err := s.lists.Delete(list.ID); err != nil {
+		s.log.Error("Failed to delete list", zap.Uint("id", list.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AddEntry puts a movie outside the trash on the list. A movie already on it fails with
+// gorm.ErrDuplicatedKey.
+func (s *ListService) AddEntry(id uint, request *models.ListEntryRequest, actor models.Actor) (*models.ListDetailResponse, error) {
+	list, err := s.list(id, actor, true)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.movies.GetByID(request.MovieID); err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", request.MovieID), zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.lists.AddEntry(list.ID, request.MovieID, request.Position); err != nil {
+		s.log.Error("Failed to add list entry", zap.Uint("id", list.ID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return s.GetList(list.ID, actor)
+}
+
+func (s *ListService) RemoveEntry(id, movieID uint, actor models.Actor) error {
+	list, err := s.list(id, actor, true)
+	if err != nil {
+		return err
+	}
+	if err := s.lists.RemoveEntry(list.ID, movieID); err != nil {
+		s.log.Error("Failed to remove list entry", zap.Uint("id", list.ID), zap.Uint("movie_id", movieID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ReorderList puts the visible entries in the given order, which must name each of them
+// once, see MovieListRepository.Reorder.
+func (s *ListService) ReorderList(id uint, request *models.ReorderListRequest, actor models.Actor) (*models.ListDetailResponse, error) {
+	list, err := s.list(id, actor, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lists.Reorder(list.ID, request.MovieIDs); err != nil {
+		s.log.Error("Failed to reorder list", zap.Uint("id", list.ID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return s.GetList(list.ID, actor)
+}
+
+// LogWatched records that the caller watched a movie outside the trash, by default now.
+func (s *ListService) LogWatched(request *models.WatchedRequest, actor models.Actor) (*models.WatchedResponse, error) {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return nil, err
+	}
+	movie, err := s.movies.GetByID(request.MovieID)
+	if err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", request.MovieID), zap.Error(err))
+		return nil, err
+	}
+
+	watchedAt := time.Now()
+	if request.WatchedAt != nil {
+		watchedAt = *request.WatchedAt
+	}
+	entry, err := s.watched.Create(&models.WatchedEntry{UserID: userID, MovieID: movie.ID, WatchedAt: watchedAt})
+	if err != nil {
+		s.log.Error("Failed to log watched movie", zap.Uint("user_id", userID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return &models.WatchedResponse{
+		ID:        entry.ID,
+		MovieID:   movie.ID,
+		Title:     movie.Title,
+		Year:      movie.Year,
+		WatchedAt: entry.WatchedAt,
+	}, nil
+}
+
+func (s *ListService) GetWatched(limit, offset int, actor models.Actor) (models.WatchedListResponse, error) {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return models.WatchedListResponse{}, err
+	}
+	entries, count, err := s.watched.GetByUser(userID, limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch watched movies", zap.Uint("user_id", userID), zap.Error(err))
+		return models.WatchedListResponse{}, err
+	}
+	return models.WatchedListResponse{Entries: entries, Count: count}, nil
+}
+
+// DeleteWatched removes an entry from the caller's watch log. Other users' entries are
+// reported as missing.
+func (s *ListService) DeleteWatched(id uint, actor models.Actor) error {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return err
+	}
+	entry, err := s.watched.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch watched entry", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	if entry.UserID != userID {
+		return gorm.ErrRecordNotFound
+	}
+
+	if err := s.watched.Delete(id); err != nil {
+		s.log.Error("Failed to delete watched entry", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// list resolves a list ID for the caller, checking it can read it or, with write set,
+// change it.
+func (s *ListService) list(id uint, actor models.Actor, write bool) (*models.ListResponse, error) {
+	userID, err := listOwner(actor)
+	if err != nil {
+		return nil, err
+	}
+
+	var list *models.ListResponse
+	if id == 0 {
+		list, err = s.lists.GetDefault(userID)
+	} else {
+		list, err = s.lists.GetByID(id)
+	}
+	if err != nil {
+		s.log.Error("Failed to fetch list", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	if list.UserID != userID {
+		if !list.Public {
+			return nil, gorm.ErrRecordNotFound
+		}
+		if write {
+			return nil, ErrNotListOwner
+		}
+	}
+	return list, nil
+}
+
+func listOwner(actor models.Actor) (uint, error) {
+	if actor.UserID == nil {
+		return 0, ErrListOwnerRequired
+	}
+	return *actor.UserID, nil
+}
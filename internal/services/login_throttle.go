@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"itv-task/config"
+	"sync"
+	"time"
+)
+
+// maxThrottleEntries bounds memory use; stale entries are swept once it is exceeded.
+const maxThrottleEntries = 10000
+
+// LoginLockedError is returned while a username or client IP is locked out.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+type throttleEntry struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// LoginThrottle tracks failed logins per username and per client IP. Once a key reaches
+// its failure threshold it is locked, and every further failure doubles the lock
+// duration up to the configured maximum. Failures are forgotten after a quiet window.
+type LoginThrottle struct {
+	maxUserFailures int
+	maxIPFailures   int
+	baseLockout     time.Duration
+	maxLockout      time.Duration
+	failureWindow   time.Duration
+
+	mu    sync.Mutex
+	users map[string]*throttleEntry
+	ips   map[string]*throttleEntry
+}
+
+func NewLoginThrottle(cfg *config.Config) *LoginThrottle {
+	return &LoginThrottle{
+		maxUserFailures: cfg.LoginMaxFailures,
+		maxIPFailures:   cfg.LoginIPMaxFailures,
+		baseLockout:     cfg.LoginLockoutBase,
+		maxLockout:      cfg.LoginLockoutMax,
+		failureWindow:   cfg.LoginFailureWindow,
+		users:           make(map[string]*throttleEntry),
+		ips:             make(map[string]*throttleEntry),
+	}
+}
+
+// Check returns how long the caller must wait before trying again, or zero if allowed.
+func (t *LoginThrottle) Check(username, ip string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	wait := t.lockedFor(t.users, username, now)
+	if ipWait := t.lockedFor(t.ips, ip, now); ipWait > wait {
+		wait = ipWait
+	}
+	return wait
+}
+
+// RecordFailure counts a failed attempt for both the username and the IP.
+func (t *LoginThrottle) RecordFailure(username, ip string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.fail(t.users, username, t.maxUserFailures, now)
+	t.fail(t.ips, ip, t.maxIPFailures, now)
+
+	if len(t.users)+len(t.ips) > maxThrottleEntries {
+		t.sweep(t.users, now)
+		t.sweep(t.ips, now)
+	}
+}
+
+// RecordSuccess clears the failures of the username. The IP counter is kept so a valid
+// account cannot be used to reset guessing against other accounts.
+func (t *LoginThrottle) RecordSuccess(username string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.users, username)
+}
+
+func (t *LoginThrottle) lockedFor(entries map[string]*throttleEntry, key string, now time.Time) time.Duration {
+	entry, ok := entries[key]
+	if !ok {
+		return 0
+	}
+	if t.expired(entry, now) {
+		delete(entries, key)
+		return 0
+	}
+	if now.Before(entry.lockedUntil) {
+		return entry.lockedUntil.Sub(now)
+	}
+	return 0
+}
+
+func (t *LoginThrottle) fail(entries map[string]*throttleEntry, key string, threshold int, now time.Time) {
+	if threshold <= 0 {
+		return
+	}
+
+	entry, ok := entries[key]
+	if !ok || t.expired(entry, now) {
+		entry = &throttleEntry{}
+		entries[key] = entry
+	}
+	entry.failures++
+	entry.lastFailure = now
+
+	if entry.failures >= threshold {
+		lockout := t.baseLockout
+		for i := threshold; i < entry.failures && lockout < t.maxLockout; i++ {
+			lockout *= 2
+		}
+		if lockout > t.maxLockout {
+			lockout = t.maxLockout
+		}
+		entry.lockedUntil = now.Add(lockout)
+	}
+}
+
+func (t *LoginThrottle) expired(entry *throttleEntry, now time.Time) bool {
+	return now.After(entry.lockedUntil) && now.Sub(entry.lastFailure) > t.failureWindow
+}
+
+func (t *LoginThrottle) sweep(entries map[string]*throttleEntry, now time.Time) {
+	for key, entry := range entries {
+		if t.expired(entry, now) {
+			delete(entries, key)
+		}
+	}
+}
@@ -1,21 +1,35 @@
 package services
 
 import (
+	"encoding/json"
+	"errors"
+	"itv-task/config"
 	"itv-task/internal/models"
 	"itv-task/internal/repositories"
 	"itv-task/pkg/logger"
+	"itv-task/pkg/utils"
+	"time"
 
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 )
 
+// ErrRevisionNotRestorable is returned when rolling back to a revision without a snapshot
+// to restore, such as the one recording the movie's deletion.
+var ErrRevisionNotRestorable = errors.New("revision has no movie snapshot to restore")
+
 type MovieService struct {
-	repo *repositories.MovieRepository
-	log  logger.Logger
+	repo               repositories.MovieRepository
+	revisions          repositories.MovieRevisionRepository
+	duplicateThreshold float64
+	expandMaxDepth     int
+	cursors            *utils.CursorSigner
+	log                logger.Logger
 }
 
-func NewMovieService(repo *repositories.MovieRepository, log logger.Logger) *MovieService {
-	return &MovieService{repo: repo, log: log}
+func NewMovieService(cfg *config.Config, repo repositories.MovieRepository, revisions repositories.MovieRevisionRepository, log logger.Logger) *MovieService {
+	return &MovieService{repo: repo, revisions: revisions, duplicateThreshold: cfg.MovieDuplicateThreshold,
+		expandMaxDepth: cfg.MovieExpandMaxDepth, cursors: utils.NewCursorSigner(cfg.CursorSecret), log: log}
 }
 
 // Provide the service to the Fx container
@@ -23,15 +37,14 @@ var MovieServiceModule = fx.Module("movieService",
 	fx.Provide(NewMovieService),
 )
 
-func (s *MovieService) CreateMovie(movie *models.CreateMovieRequest) (uint, error) {
+func (s *MovieService) CreateMovie(movie *models.CreateMovieRequest, actor models.Actor) (*models.MovieResponse, error) {
 	s.log.Info("Creating movie", zap.Any("request", movie))
-	id, err := s.repo.Create(movie)
+	created, err := s.repo.Create(movie, s.revision(models.RevisionCreate, actor, nil))
 	if err != nil {
 		s.log.Error("Failed to create movie", zap.Any("request", movie), zap.Error(err))
-		return 0, err
+		return nil, err
 	}
-
-	return id, nil
+	return created, nil
 }
 
 func (s *MovieService) GetMovieByID(id uint) (*models.MovieResponse, error) {
@@ -46,68 +59,365 @@ func (s *MovieService) GetMovieByID(id uint) (*models.MovieResponse, error) {
 	return movie, nil
 }
 
-func (s *MovieService) GetAllMovies(title, director string, year int, sortBy, sortOrder string, limit, offset int) (models.MovieListResponse, error) {
-	s.log.Info("Getting movies", zap.Any("request", map[string]interface{}{
-		"title":     title,
-		"director":  director,
-		"year":      year,
-		"sortBy":    sortBy,
-		"sortOrder": sortOrder,
-		"limit":     limit,
-		"offset":    offset}))
-	movies, err := s.repo.GetAll(title, director, year, sortBy, sortOrder, limit, offset)
-	if err != nil {
-		s.log.Error("Failed to fetch movies", zap.Any("request", map[string]interface{}{
-			"title":     title,
-			"director":  director,
-			"year":      year,
-			"sortBy":    sortBy,
-			"sortOrder": sortOrder,
-			"limit":     limit,
-			"offset":    offset,
-		}), zap.Error(err))
+func (s *MovieService) GetAllMovies(filter models.MovieFilter) (models.MovieListResponse, error) {
+	s.log.Info("Getting movies", zap.Any("request", filter))
+	movies, err := s.repo.GetAll(filter)
+	if err != nil {
+		s.log.Error("Failed to fetch movies", zap.Any("request", filter), zap.Error(err))
 		return models.MovieListResponse{}, err
 	}
 
+	if movies.Next != nil {
+		if movies.NextCursor, err = s.cursors.Encode(movies.Next); err != nil {
+			return models.MovieListResponse{}, err
+		}
+	}
+	if movies.Prev != nil {
+		if movies.PrevCursor, err = s.cursors.Encode(movies.Prev); err != nil {
+			return models.MovieListResponse{}, err
+		}
+	}
 	return movies, nil
 }
 
-func (s *MovieService) UpdateMovie(movie *models.UpdateMovieRequest) error {
+// DecodeMovieCursor verifies a cursor issued by GetAllMovies and returns its position.
+func (s *MovieService) DecodeMovieCursor(cursor string) (*models.MovieCursor, error) {
+	var position models.MovieCursor
+	if err := s.cursors.Decode(cursor, &position); err != nil {
+		return nil, err
+	}
+	return &position, nil
+}
+
+// SearchMovies runs a full-text search over the movies matching the filter.
+func (s *MovieService) SearchMovies(query string, filter models.MovieFilter) (models.MovieSearchResponse, error) {
+	s.log.Info("Searching movies", zap.String("query", query), zap.Any("request", filter))
+	results, err := s.repo.Search(query, filter)
+	if err != nil {
+		s.log.Error("Failed to search movies", zap.String("query", query), zap.Any("request", filter), zap.Error(err))
+		return models.MovieSearchResponse{}, err
+	}
+
+	return results, nil
+}
+
+// SearchSimilarMovies lists up to limit movies whose titles have at least the given
+// similarity to the title, most similar first.
+func (s *MovieService) SearchSimilarMovies(title string, threshold float64, limit int) (models.SimilarMoviesResponse, error) {
+	s.log.Info("Searching similar movies", zap.String("title", title), zap.Float64("threshold", threshold))
+	movies, err := s.repo.FindSimilar(title, threshold, limit)
+	if err != nil {
+		s.log.Error("Failed to search similar movies", zap.String("title", title), zap.Error(err))
+		return models.SimilarMoviesResponse{}, err
+	}
+	return models.SimilarMoviesResponse{Results: movies}, nil
+}
+
+// maxDuplicateMatches is how many existing movies are reported per possible duplicate.
+const maxDuplicateMatches = 5
+
+// FindPossibleDuplicates returns, for each title that is close to titles already in the
+// catalogue, the closest movies, using MOVIE_DUPLICATE_THRESHOLD.
+func (s *MovieService) FindPossibleDuplicates(titles ...string) ([]models.PossibleDuplicate, error) {
+	var duplicates []models.PossibleDuplicate
+	for _, title := range titles {
+		matches, err := s.repo.FindSimilar(title, s.duplicateThreshold, maxDuplicateMatches)
+		if err != nil {
+			s.log.Error("Failed to check for duplicate movies", zap.String("title", title), zap.Error(err))
+			return nil, err
+		}
+		if len(matches) > 0 {
+			duplicates = append(duplicates, models.PossibleDuplicate{Title: title, Matches: matches})
+		}
+	}
+	return duplicates, nil
+}
+
+// GetDuplicateClusters groups the catalogue's movies with similar titles. A zero threshold
+// uses MOVIE_DUPLICATE_THRESHOLD.
+func (s *MovieService) GetDuplicateClusters(threshold float64, limit, offset int) (models.DuplicateClustersResponse, error) {
+	if threshold == 0 {
+		threshold = s.duplicateThreshold
+	}
+	clusters, total, err := s.repo.DuplicateClusters(threshold, limit, offset)
+	if err != nil {
+		s.log.Error("Failed to find duplicate movies", zap.Float64("threshold", threshold), zap.Error(err))
+		return models.DuplicateClustersResponse{}, err
+	}
+	return models.DuplicateClustersResponse{Clusters: clusters, Count: total}, nil
+}
+
+// UpdateMovie replaces the movie's fields and returns the updated movie. A non-zero
+// version makes it conditional, see MovieRepository.
+func (s *MovieService) UpdateMovie(movie *models.UpdateMovieRequest, actor models.Actor) (*models.MovieResponse, error) {
 	s.log.Info("Updating movie", zap.Any("request", movie))
 
-	err := s.repo.Update(movie)
+	updated, err := s.repo.Update(movie, s.revision(models.RevisionUpdate, actor, nil))
 	if err != nil {
 		s.log.Error("Failed to update movie", zap.Any("request", movie), zap.Error(err))
-		return err
+		return nil, err
 	}
 
 	s.log.Info("Movie updated successfully", zap.Uint("id", movie.ID))
-	return nil
+	return updated, nil
 }
 
-func (s *MovieService) DeleteMovie(id uint) error {
-	s.log.Info("Deleting movie", zap.Uint("request", id))
+// PatchMovie applies the changed fields and returns the updated movie. An empty patch
+// changes nothing, so it neither bumps the version nor records a revision.
+func (s *MovieService) PatchMovie(movie *models.PatchMovieRequest, actor models.Actor) (*models.MovieResponse, error) {
+	s.log.Info("Patching movie", zap.Any("request", movie))
 
-	err := s.repo.Delete(id)
+	if movie.Empty() {
+		current, err := s.repo.GetByID(movie.ID)
+		if err != nil {
+			s.log.Error("Failed to patch movie", zap.Any("request", movie), zap.Error(err))
+			return nil, err
+		}
+		return current, nil
+	}
+
+	patched, err := s.repo.Patch(movie, s.revision(models.RevisionUpdate, actor, nil))
 	if err != nil {
+		s.log.Error("Failed to patch movie", zap.Any("request", movie), zap.Error(err))
+		return nil, err
+	}
+	return patched, nil
+}
+
+// DeleteMovie soft deletes a movie. A non-zero version makes it conditional, see MovieRepository.
+func (s *MovieService) DeleteMovie(id, version uint, actor models.Actor) error {
+	s.log.Info("Deleting movie", zap.Uint("request", id))
+
+	if _, err := s.repo.Delete(id, version, s.revision(models.RevisionDelete, actor, nil)); err != nil {
 		s.log.Error("Failed to delete movie", zap.Uint("id", id), zap.Error(err))
 		return err
 	}
-
 	return nil
 }
 
-func (s *MovieService) BulkInsertMovies(movies *models.BulkInsertMoviesRequest) error {
+func (s *MovieService) BulkInsertMovies(movies *models.BulkInsertMoviesRequest, actor models.Actor) error {
 	s.log.Info("Creating movie", zap.Any("request", movies))
-	err := s.repo.BulkInsertMovies(movies)
-	if err != nil {
+	if _, err := s.repo.BulkInsertMovies(movies, s.revision(models.RevisionCreate, actor, nil)); err != nil {
 		s.log.Error("Failed to create movie", zap.Any("request", movies), zap.Error(err))
 		return err
 	}
+	return nil
+}
+
+// GetTrash lists the soft-deleted movies, most recently deleted first.
+func (s *MovieService) GetTrash(limit, offset int) (models.MovieListResponse, error) {
+	movies, err := s.repo.GetDeleted(limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch deleted movies", zap.Int("limit", limit), zap.Int("offset", offset), zap.Error(err))
+		return models.MovieListResponse{}, err
+	}
+	return movies, nil
+}
+
+func (s *MovieService) GetDeletedMovieByID(id uint) (*models.MovieResponse, error) {
+	movie, err := s.repo.GetDeletedByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch deleted movie", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return movie, nil
+}
 
+// RestoreMovie takes a movie out of the trash. It fails with gorm.ErrDuplicatedKey when
+// another movie has taken its title in the meantime.
+func (s *MovieService) RestoreMovie(id, version uint, actor models.Actor) (*models.MovieResponse, error) {
+	s.log.Info("Restoring movie", zap.Uint("id", id))
+
+	restored, err := s.repo.Restore(id, version, s.revision(models.RevisionRestore, actor, nil))
+	if err != nil {
+		s.log.Error("Failed to restore movie", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return restored, nil
+}
+
+// HardDeleteMovie permanently removes a movie, whether or not it is in the trash.
+func (s *MovieService) HardDeleteMovie(id uint, actor models.Actor) error {
+	s.log.Info("Permanently deleting movie", zap.Uint("id", id))
+
+	if _, err := s.repo.HardDelete(id, s.revision(models.RevisionPurge, actor, nil)); err != nil {
+		s.log.Error("Failed to permanently delete movie", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
 	return nil
 }
 
+// PurgeTrash permanently removes the movies that have been in the trash for longer than
+// retention and returns how many there were.
+func (s *MovieService) PurgeTrash(retention time.Duration) (int, error) {
+	actor := models.Actor{Username: "system:trash-purge"}
+	purged, err := s.repo.PurgeDeleted(time.Now().Add(-retention), s.revision(models.RevisionPurge, actor, nil))
+	if err != nil {
+		s.log.Error("Failed to purge trash", zap.Duration("retention", retention), zap.Error(err))
+		return 0, err
+	}
+
+	if len(purged) > 0 {
+		s.log.Info("Purged trash", zap.Int("count", len(purged)))
+	}
+	return len(purged), nil
+}
+
+// GetMovieHistory lists a movie's revisions, newest first. Deleted movies keep their
+// history, so it does not require the movie to exist.
+func (s *MovieService) GetMovieHistory(id uint, limit, offset int) (models.MovieRevisionListResponse, error) {
+	revisions, total, err := s.revisions.GetByMovie(id, limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch movie history", zap.Uint("id", id), zap.Error(err))
+		return models.MovieRevisionListResponse{}, err
+	}
+
+	response := models.MovieRevisionListResponse{
+		Revisions: make([]models.MovieRevisionResponse, 0, len(revisions)),
+		Count:     total,
+	}
+	for i := range revisions {
+		response.Revisions = append(response.Revisions, toRevisionResponse(&revisions[i]))
+	}
+	return response, nil
+}
+
+func (s *MovieService) GetMovieRevision(id, revision uint) (*models.MovieRevisionResponse, error) {
+	entry, err := s.revisions.Get(id, revision)
+	if err != nil {
+		s.log.Error("Failed to fetch movie revision", zap.Uint("id", id), zap.Uint("revision", revision), zap.Error(err))
+		return nil, err
+	}
+
+	response := toRevisionResponse(entry)
+	return &response, nil
+}
+
+// RollbackMovie restores the movie to the snapshot taken after the given revision. The
+// rollback is itself an update, recorded as a new revision that points at its source.
+func (s *MovieService) RollbackMovie(request *models.RollbackMovieRequest, actor models.Actor) (*models.MovieResponse, error) {
+	s.log.Info("Rolling back movie", zap.Any("request", request))
+
+	entry, err := s.revisions.Get(request.ID, request.Revision)
+	if err != nil {
+		s.log.Error("Failed to fetch movie revision", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	var snapshot models.MovieResponse
+	if len(entry.After) == 0 || string(entry.After) == "null" {
+		return nil, ErrRevisionNotRestorable
+	}
+	if err := json.Unmarshal(entry.After, &snapshot); err != nil {
+		s.log.Error("Failed to decode movie revision", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	source := entry.Revision
+	updated, err := s.repo.Update(&models.UpdateMovieRequest{
+		ID:       request.ID,
+		Version:  request.Version,
+		Title:    snapshot.Title,
+		Director: snapshot.Director,
+		Year:     snapshot.Year,
+		Plot:     snapshot.Plot,
+		Genres:   snapshot.Genres, // Snapshots from before genres existed have none and keep the current ones
+	}, s.revision(models.RevisionRollback, actor, &source))
+	if err != nil {
+		s.log.Error("Failed to roll back movie", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+	return updated, nil
+}
+
+// GetMovieCredits lists the cast and crew of a movie outside the trash.
+func (s *MovieService) GetMovieCredits(id uint) (*models.MovieCreditsResponse, error) {
+	movie, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	credits, err := s.repo.GetCredits(id)
+	if err != nil {
+		s.log.Error("Failed to fetch movie credits", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return &models.MovieCreditsResponse{MovieID: id, Version: movie.Version, Credits: credits}, nil
+}
+
+// ReplaceMovieCredits replaces the cast and crew of a movie. It is an update of the movie,
+// since the director field follows the director credits, and is recorded as one.
+func (s *MovieService) ReplaceMovieCredits(request *models.ReplaceCreditsRequest, actor models.Actor) (*models.MovieCreditsResponse, error) {
+	s.log.Info("Replacing movie credits", zap.Any("request", request))
+
+	updated, err := s.repo.ReplaceCredits(request, s.revision(models.RevisionUpdate, actor, nil))
+	if err != nil {
+		s.log.Error("Failed to replace movie credits", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	credits, err := s.repo.GetCredits(request.ID)
+	if err != nil {
+		s.log.Error("Failed to fetch movie credits", zap.Uint("id", request.ID), zap.Error(err))
+		return nil, err
+	}
+	return &models.MovieCreditsResponse{MovieID: request.ID, Credits: credits, Version: updated.Version}, nil
+}
+
+// revision returns how a change made by the actor is recorded in the movie's history.
+// The repository writes the entry along with the change, so a failure to write it fails
+// the change. Entries are numbered by the version the change moved the movie to, or one
+// past the last version for a movie removed for good.
+func (s *MovieService) revision(action string, actor models.Actor, source *uint) repositories.RecordRevision {
+	return func(before, after *models.MovieResponse) *models.MovieRevision {
+		entry := &models.MovieRevision{
+			Action:         action,
+			ActorUserID:    actor.UserID,
+			ActorName:      actor.Username,
+			APIKeyID:       actor.APIKeyID,
+			RequestID:      actor.RequestID,
+			SourceRevision: source,
+			Before:         snapshot(before),
+			After:          snapshot(after),
+		}
+		switch {
+		case after == nil:
+			entry.MovieID, entry.Revision = before.ID, before.Version+1
+		case action == models.RevisionDelete:
+			// The delete revision keeps only the before snapshot.
+			entry.MovieID, entry.Revision, entry.After = after.ID, after.Version, snapshot(nil)
+		default:
+			entry.MovieID, entry.Revision = after.ID, after.Version
+		}
+		return entry
+	}
+}
+
+func snapshot(movie *models.MovieResponse) json.RawMessage {
+	if movie == nil {
+		return json.RawMessage("null")
+	}
+	data, err := json.Marshal(movie)
+	if err != nil {
+		return json.RawMessage("null")
+	}
+	return data
+}
+
+func toRevisionResponse(entry *models.MovieRevision) models.MovieRevisionResponse {
+	return models.MovieRevisionResponse{
+		Revision:       entry.Revision,
+		Action:         entry.Action,
+		ActorUserID:    entry.ActorUserID,
+		ActorName:      entry.ActorName,
+		APIKeyID:       entry.APIKeyID,
+		RequestID:      entry.RequestID,
+		SourceRevision: entry.SourceRevision,
+		Before:         entry.Before,
+		After:          entry.After,
+		CreatedAt:      entry.CreatedAt,
+	}
+}
+
 func (s *MovieService) GetMovieByTitle(title string) (*models.MovieResponse, error) {
 	s.log.Info("getting movie by title", zap.Any("request", title))
 
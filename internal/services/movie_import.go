@@ -0,0 +1,362 @@
+package services
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidImport is returned for import files or options that cannot be read at all, as
+// opposed to single rows, which are rejected in the report.
+var ErrInvalidImport = errors.New("invalid import")
+
+const (
+	maxImportRows       = 10000
+	maxImportLineLength = 1 << 20
+	// importGenreSeparator separates the genres in a CSV or TSV cell, like "Sci-Fi|Thriller".
+	importGenreSeparator = "|"
+)
+
+// importFields are the movie fields an import reads; the first three are required.
+var importFields = []string{"title", "director", "year", "plot", "genres"}
+
+// importRow is a row of an import file read into a movie, with the reasons it could not
+// be read cleanly.
+type importRow struct {
+	line    int
+	movie   models.CreateMovieRequest
+	reasons []string
+}
+
+// ImportMovies reads the movies of an import file and writes the valid ones as the mode
+// says, or only checks them in a dry run. All-or-nothing imports with a rejected row
+// write nothing, but still check every row, so the report lists every problem at once.
+func (s *MovieService) ImportMovies(file io.Reader, options models.MovieImportOptions, actor models.Actor) (models.MovieImportReport, error) {
+	s.log.Info("Importing movies", zap.String("format", options.Format), zap.String("mode", options.Mode), zap.Bool("dry_run", options.DryRun))
+	rows, err := readImport(file, options)
+	if err != nil {
+		return models.MovieImportReport{}, err
+	}
+
+	results := make([]models.MovieImportRowResult, len(rows))
+	movies := make([]models.CreateMovieRequest, 0, len(rows))
+	indexes := make([]int, 0, len(rows)) // Row of each of movies
+	firstRow := make(map[string]int)
+	for i, row := range rows {
+		reasons := append(row.reasons, validateImportMovie(&row.movie)...)
+		if title := row.movie.Title; title != "" {
+			if first, ok := firstRow[title]; ok {
+				reasons = append(reasons, fmt.Sprintf("title repeats row %d", first))
+			} else {
+				firstRow[title] = row.line
+			}
+		}
+
+		results[i] = models.MovieImportRowResult{Row: row.line, Title: row.movie.Title}
+		if len(reasons) > 0 {
+			results[i].Status, results[i].Reasons = models.ImportRowRejected, reasons
+			continue
+		}
+		movies = append(movies, row.movie)
+		indexes = append(indexes, i)
+	}
+
+	atomic := options.Mode == models.ImportAllOrNothing
+	upsert := options.Mode == models.ImportUpsert
+	dryRun := options.DryRun || (atomic && len(movies) < len(rows))
+	created, updated := s.revision(models.RevisionCreate, actor, nil), s.revision(models.RevisionUpdate, actor, nil)
+	outcomes, err := s.repo.ImportMovies(movies, upsert, atomic, dryRun, func(before, after *models.MovieResponse) *models.MovieRevision {
+		if before == nil {
+			return created(before, after)
+		}
+		return updated(before, after)
+	})
+	if err != nil {
+		s.log.Error("Failed to import movies", zap.Int("rows", len(rows)), zap.Error(err))
+		return models.MovieImportReport{}, err
+	}
+
+	for j, outcome := range outcomes {
+		result := &results[indexes[j]]
+		switch {
+		case outcome.Err != nil:
+			result.Status, result.Reasons = models.ImportRowRejected, []string{s.importFailure(result.Row, outcome.Err)}
+		case outcome.Updated:
+			result.Status = models.ImportRowUpdated
+		default:
+			result.Status = models.ImportRowCreated
+		}
+		if outcome.Movie != nil {
+			result.MovieID = outcome.Movie.ID
+		}
+	}
+
+	report := models.MovieImportReport{
+		Mode:   options.Mode,
+		DryRun: options.DryRun,
+		Total:  len(rows),
+		Errors: []models.MovieImportRowResult{},
+	}
+	for _, result := range results {
+		switch result.Status {
+		case models.ImportRowRejected:
+			report.Rejected++
+			report.Errors = append(report.Errors, result)
+		case models.ImportRowCreated:
+			report.Created++
+		case models.ImportRowUpdated:
+			report.Updated++
+		}
+	}
+	report.Committed = !options.DryRun && !(atomic && report.Rejected > 0)
+	if !report.Committed && !options.DryRun {
+		report.Created, report.Updated = 0, 0
+	}
+	if options.DryRun {
+		report.Rows = results
+	}
+	return report, nil
+}
+
+// importFailure explains why the repository rejected a row.
+func (s *MovieService) importFailure(row int, err error) string {
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return "a movie with this title already exists"
+	case errors.Is(err, repositories.ErrUnknownGenre):
+		return err.Error()
+	default:
+		s.log.Error("Failed to import movie", zap.Int("row", row), zap.Error(err))
+		return "the movie could not be saved"
+	}
+}
+
+// validateImportMovie applies the rules of CreateMovieRequest, which rows do not go
+// through binding for.
+func validateImportMovie(movie *models.CreateMovieRequest) []string {
+	var reasons []string
+	for _, field := range []struct{ name, value string }{{"title", movie.Title}, {"director", movie.Director}} {
+		if field.value == "" {
+			reasons = append(reasons, field.name+" is required")
+		} else if utf8.RuneCountInString(field.value) > 255 {
+			reasons = append(reasons, field.name+" must be at most 255 characters")
+		}
+	}
+	if movie.Year < 1888 || movie.Year > 2025 {
+		reasons = append(reasons, "year must be between 1888 and 2025")
+	}
+	if len(movie.Genres) > 20 {
+		reasons = append(reasons, "a movie can have at most 20 genres")
+	}
+	for _, genre := range movie.Genres {
+		if utf8.RuneCountInString(genre) > 64 {
+			reasons = append(reasons, fmt.Sprintf("genre %q is longer than 64 characters", genre))
+		}
+	}
+	return reasons
+}
+
+// readImport reads the rows of an import file in the options' format.
+func readImport(file io.Reader, options models.MovieImportOptions) ([]importRow, error) {
+	for field, column := range options.Mapping {
+		if !slices.Contains(importFields, field) {
+			return nil, fmt.Errorf("%w: cannot map %q; the fields are %s", ErrInvalidImport, field, strings.Join(importFields, ", "))
+		}
+		if strings.TrimSpace(column) == "" {
+			return nil, fmt.Errorf("%w: the column mapped to %s is blank", ErrInvalidImport, field)
+		}
+	}
+
+	switch options.Format {
+	case models.ImportCSV:
+		return readDelimited(file, ',', options.Mapping)
+	case models.ImportTSV:
+		return readDelimited(file, '\t', options.Mapping)
+	case models.ImportNDJSON:
+		return readNDJSON(file, options.Mapping)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInvalidImport, options.Format)
+	}
+}
+
+// readDelimited reads a CSV or TSV file whose first record is a header naming the
+// columns. Headers are matched ignoring case and surrounding space.
+func readDelimited(file io.Reader, comma rune, mapping map[string]string) ([]importRow, error) {
+	reader := csv.NewReader(file)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = comma == '\t' // Quotes in TSV are usually meant literally
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	columns := make(map[string]int, len(importFields))
+	for i, field := range importFields {
+		name, mapped := mapping[field]
+		if !mapped {
+			name = field
+		}
+		index := slices.IndexFunc(header, func(column string) bool {
+			return strings.EqualFold(strings.TrimSpace(column), strings.TrimSpace(name))
+		})
+		switch {
+		case index >= 0:
+			columns[field] = index
+		case mapped:
+			return nil, fmt.Errorf("%w: the header has no column %q, mapped to %s", ErrInvalidImport, name, field)
+		case i < 3:
+			return nil, fmt.Errorf("%w: the header has no %s column; add one or map another column to it", ErrInvalidImport, field)
+		}
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("%w: imports are limited to %d rows", ErrInvalidImport, maxImportRows)
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := importRow{line: line}
+		cell := func(field string) (string, bool) {
+			index, ok := columns[field]
+			if !ok || index >= len(record) {
+				return "", ok
+			}
+			return strings.TrimSpace(record[index]), true
+		}
+		row.movie.Title, _ = cell("title")
+		row.movie.Director, _ = cell("director")
+		row.movie.Plot, _ = cell("plot")
+		if year, _ := cell("year"); year != "" {
+			if row.movie.Year, err = strconv.Atoi(year); err != nil {
+				row.reasons = append(row.reasons, fmt.Sprintf("year %q is not a whole number", year))
+			}
+		}
+		if genres, ok := cell("genres"); ok {
+			row.movie.Genres = splitGenres(genres)
+		}
+		rows = append(rows, row)
+	}
+}
+
+// readNDJSON reads a file with a JSON object per line. Blank lines are skipped, and lines
+// that are not objects are rejected rather than failing the file.
+func readNDJSON(file io.Reader, mapping map[string]string) ([]importRow, error) {
+	key := func(field string) string {
+		if name, ok := mapping[field]; ok {
+			return name
+		}
+		return field
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineLength)
+	var rows []importRow
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		if text == "" {
+			continue
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("%w: imports are limited to %d rows", ErrInvalidImport, maxImportRows)
+		}
+
+		row := importRow{line: line}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(text), &object); err != nil {
+			row.reasons = append(row.reasons, "the line is not a JSON object")
+			rows = append(rows, row)
+			continue
+		}
+		for _, field := range []struct {
+			name  string
+			value *string
+		}{{"title", &row.movie.Title}, {"director", &row.movie.Director}, {"plot", &row.movie.Plot}} {
+			if raw, ok := object[key(field.name)]; ok && json.Unmarshal(raw, field.value) != nil {
+				row.reasons = append(row.reasons, field.name+" must be a string")
+			}
+			*field.value = strings.TrimSpace(*field.value)
+		}
+		if raw, ok := object[key("year")]; ok {
+			if year, ok := jsonYear(raw); ok {
+				row.movie.Year = year
+			} else {
+				row.reasons = append(row.reasons, "year must be a whole number")
+			}
+		}
+		if raw, ok := object[key("genres")]; ok {
+			var text string
+			if json.Unmarshal(raw, &row.movie.Genres) == nil {
+				row.movie.Genres = splitGenres(strings.Join(row.movie.Genres, importGenreSeparator))
+			} else if json.Unmarshal(raw, &text) == nil {
+				row.movie.Genres = splitGenres(text)
+			} else {
+				row.reasons = append(row.reasons, "genres must be a list of strings")
+			}
+		}
+		rows = append(rows, row)
+	}
+	if errors.Is(scanner.Err(), bufio.ErrTooLong) {
+		return nil, fmt.Errorf("%w: line %d is longer than %d bytes", ErrInvalidImport, line+1, maxImportLineLength)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	return rows, nil
+}
+
+// jsonYear reads a year given as a number or a numeric string.
+func jsonYear(raw json.RawMessage) (int, bool) {
+	var year int
+	if json.Unmarshal(raw, &year) == nil {
+		return year, true
+	}
+	var text string
+	if json.Unmarshal(raw, &text) != nil {
+		return 0, false
+	}
+	year, err := strconv.Atoi(strings.TrimSpace(text))
+	return year, err == nil
+}
+
+// splitGenres splits a cell of genres, dropping blanks. The result is never nil, so an
+// empty cell clears the genres of an upserted movie.
+func splitGenres(cell string) []string {
+	genres := []string{}
+	for _, genre := range strings.Split(cell, importGenreSeparator) {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			genres = append(genres, genre)
+		}
+	}
+	return genres
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"itv-task/internal/models"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrInvalidMovieView is returned for fields or expansions that movies do not have, or
+// expansions deeper than MOVIE_EXPAND_MAX_DEPTH.
+var ErrInvalidMovieView = errors.New("invalid fields or expand")
+
+// movieViewFields are the fields of MovieResponse that ?fields= can select.
+var movieViewFields = []string{"id", "title", "director", "year", "plot", "version", "rating_average",
+	"rating_count", "created_at", "updated_at", "genres"}
+
+// movieExpansions are the relations ?expand= can embed.
+var movieExpansions = []string{models.ExpandGenres, models.ExpandCredits, models.ExpandCreditsPerson, models.ExpandRatings}
+
+// MovieView resolves the comma-separated fields and expand parameters into a view.
+func (s *MovieService) MovieView(fields, expand string) (models.MovieView, error) {
+	var view models.MovieView
+	for _, field := range splitList(fields) {
+		if !slices.Contains(movieViewFields, field) {
+			return view, fmt.Errorf("%w: unknown field %q; use %s", ErrInvalidMovieView, field, strings.Join(movieViewFields, ", "))
+		}
+		if len(view.Fields) == 0 && field != "id" {
+			view.Fields = append(view.Fields, "id")
+		}
+		if !slices.Contains(view.Fields, field) {
+			view.Fields = append(view.Fields, field)
+		}
+	}
+
+	for _, path := range splitList(expand) {
+		if !slices.Contains(movieExpansions, path) {
+			return view, fmt.Errorf("%w: cannot expand %q; use %s", ErrInvalidMovieView, path, strings.Join(movieExpansions, ", "))
+		}
+		if depth := strings.Count(path, ".") + 1; depth > s.expandMaxDepth {
+			return view, fmt.Errorf("%w: %q is %d levels deep, and expansion is limited to %d", ErrInvalidMovieView, path, depth, s.expandMaxDepth)
+		}
+		switch path {
+		case models.ExpandGenres:
+			view.Expand.Genres = true
+		case models.ExpandCredits:
+			view.Expand.Credits = true
+		case models.ExpandCreditsPerson:
+			view.Expand.Credits, view.Expand.CreditsPerson = true, true
+		case models.ExpandRatings:
+			view.Expand.Ratings = true
+		}
+	}
+	return view, nil
+}
+
+// splitList splits a comma-separated parameter, dropping blanks.
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// GetMovieFields retrieves a movie, loading only the fields of the view.
+func (s *MovieService) GetMovieFields(id uint, fields []string) (*models.MovieResponse, error) {
+	s.log.Info("getting movie", zap.Uint("id", id), zap.Strings("fields", fields))
+	movie, err := s.repo.GetByIDFields(id, fields)
+	if err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return movie, nil
+}
+
+// RenderMovies renders the movies in the view, loading the relations it expands.
+func (s *MovieService) RenderMovies(movies []models.MovieResponse, view models.MovieView) ([]models.MovieDocument, error) {
+	var relations map[uint]*models.MovieRelations
+	if view.Expand != (models.MovieExpansion{}) {
+		ids := make([]uint, len(movies))
+		for i := range movies {
+			ids[i] = movies[i].ID
+		}
+		var err error
+		if relations, err = s.repo.GetRelations(ids, view.Expand); err != nil {
+			s.log.Error("Failed to load movie relations", zap.Any("expand", view.Expand), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	documents := make([]models.MovieDocument, 0, len(movies))
+	for i := range movies {
+		document, err := movieDocument(&movies[i], view, relations[movies[i].ID])
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+// movieDocument keeps the fields of the movie's JSON that the view includes and adds
+// the expanded relations, which replace the genre names with genre objects.
+func movieDocument(movie *models.MovieResponse, view models.MovieView, relations *models.MovieRelations) (models.MovieDocument, error) {
+	encoded, err := json.Marshal(movie)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &fields); err != nil {
+		return nil, err
+	}
+
+	document := make(models.MovieDocument, len(fields)+3)
+	for name, value := range fields {
+		if view.Includes(name) {
+			document[name] = value
+		}
+	}
+	if relations != nil {
+		if view.Expand.Genres {
+			document["genres"] = relations.Genres
+		}
+		if view.Expand.Credits {
+			document["credits"] = relations.Credits
+		}
+		if view.Expand.Ratings {
+			document["ratings"] = relations.Ratings
+		}
+	}
+	return document, nil
+}
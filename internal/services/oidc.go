@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"itv-task/pkg/oidc"
+	"itv-task/pkg/utils"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// OIDCLoginTTL is how long a started login may take before its state expires.
+const OIDCLoginTTL = 10 * time.Minute
+
+var (
+	ErrOIDCDisabled     = errors.New("oidc login is not configured")
+	ErrOIDCInvalidState = errors.New("unknown or expired login state")
+	ErrOIDCNoRole       = errors.New("identity provider groups grant no role")
+)
+
+type oidcPendingLogin struct {
+	nonce        string
+	codeVerifier string
+	expiresAt    time.Time
+}
+
+// OIDCService signs employees in through the company identity provider and issues
+// the service's own tokens for them.
+type OIDCService struct {
+	Config *config.Config
+	// HTTPClient is used for all calls to the identity provider.
+	HTTPClient *http.Client
+
+	auth        *AuthService
+	userRepo    repositories.UserRepository
+	log         logger.Logger
+	roleMapping map[string]string
+
+	mu       sync.Mutex
+	provider *oidc.Provider
+	pending  map[string]oidcPendingLogin
+}
+
+func NewOIDCService(cfg *config.Config, auth *AuthService, userRepo repositories.UserRepository, log logger.Logger) (*OIDCService, error) {
+	roleMapping, err := parseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.OIDCDefaultRole != "" && !models.RoleAtLeast(cfg.OIDCDefaultRole, models.RoleViewer) {
+		return nil, fmt.Errorf("invalid OIDC_DEFAULT_ROLE %q", cfg.OIDCDefaultRole)
+	}
+
+	return &OIDCService{
+		Config:      cfg,
+		HTTPClient:  &http.Client{Timeout: 10 * time.Second},
+		auth:        auth,
+		userRepo:    userRepo,
+		log:         log,
+		roleMapping: roleMapping,
+		pending:     make(map[string]oidcPendingLogin),
+	}, nil
+}
+
+// StartLogin begins the authorization code flow and returns the URL to send the browser to,
+// along with the state. The caller binds the state to the browser (a cookie) so that
+// CompleteLogin only accepts the callback in the browser that started the login.
+func (s *OIDCService) StartLogin(ctx context.Context) (string, string, error) {
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := utils.RandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := utils.RandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	codeVerifier, err := utils.RandomHex(32)
+	if err != nil {
+		return "", "", err
+	}
+
+	s.mu.Lock()
+	now := time.Now()
+	for key, login := range s.pending {
+		if now.After(login.expiresAt) {
+			delete(s.pending, key)
+		}
+	}
+	s.pending[state] = oidcPendingLogin{nonce: nonce, codeVerifier: codeVerifier, expiresAt: now.Add(OIDCLoginTTL)}
+	s.mu.Unlock()
+
+	return provider.AuthCodeURL(state, nonce, codeVerifier), state, nil
+}
+
+// CompleteLogin handles the provider callback: it exchanges the code, verifies the ID token,
+// syncs the local user and issues our own token pair. browserState is the state bound to the
+// browser by StartLogin's caller; a callback arriving without it is a login CSRF attempt.
+func (s *OIDCService) CompleteLogin(ctx context.Context, state, browserState, code string) (models.LoginResponse, error) {
+	provider, err := s.getProvider(ctx)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	// The state is single use, whatever the outcome.
+	s.mu.Lock()
+	login, ok := s.pending[state]
+	delete(s.pending, state)
+	s.mu.Unlock()
+	if !ok || time.Now().After(login.expiresAt) {
+		return models.LoginResponse{}, ErrOIDCInvalidState
+	}
+	if subtle.ConstantTimeCompare([]byte(state), []byte(browserState)) != 1 {
+		s.log.Warn("OIDC callback state does not match the browser that started the login")
+		return models.LoginResponse{}, ErrOIDCInvalidState
+	}
+
+	tokens, err := provider.Exchange(ctx, code, login.codeVerifier)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+	claims, err := provider.VerifyIDToken(ctx, tokens.IDToken, login.nonce)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	role := s.mapRole(claims)
+	if role == "" {
+		s.log.Warn("OIDC login denied, no role for groups", zap.Any("groups", claims[s.Config.OIDCGroupsClaim]))
+		return models.LoginResponse{}, ErrOIDCNoRole
+	}
+
+	user, err := s.syncUser(claims, role)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	s.log.Info("OIDC login", zap.Uint("user_id", user.ID), zap.String("username", user.Username), zap.String("role", role))
+	return s.auth.StartSession(*user)
+}
+
+func (s *OIDCService) getProvider(ctx context.Context) (*oidc.Provider, error) {
+	if s.Config.OIDCIssuerURL == "" || s.Config.OIDCClientID == "" {
+		return nil, ErrOIDCDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.provider != nil {
+		return s.provider, nil
+	}
+
+	// Discovered lazily so the API still starts while the identity provider is unreachable.
+	provider, err := oidc.Discover(ctx, oidc.Config{
+		IssuerURL:    s.Config.OIDCIssuerURL,
+		ClientID:     s.Config.OIDCClientID,
+		ClientSecret: s.Config.OIDCClientSecret,
+		RedirectURL:  s.Config.OIDCRedirectURL,
+		Scopes:       strings.Fields(s.Config.OIDCScopes),
+	}, s.HTTPClient)
+	if err != nil {
+		s.log.Error("OIDC discovery failed", zap.String("issuer", s.Config.OIDCIssuerURL), zap.Error(err))
+		return nil, err
+	}
+	s.provider = provider
+	return provider, nil
+}
+
+// mapRole picks the highest role granted by the user's IdP groups, falling back to the default role.
+func (s *OIDCService) mapRole(claims jwt.MapClaims) string {
+	role := ""
+	for _, group := range groupsFromClaims(claims[s.Config.OIDCGroupsClaim]) {
+		mapped, ok := s.roleMapping[group]
+		if ok && (role == "" || models.RoleAtLeast(mapped, role)) {
+			role = mapped
+		}
+	}
+	if role == "" {
+		role = s.Config.OIDCDefaultRole
+	}
+	return role
+}
+
+// syncUser finds the local account linked to the IdP subject, creating it on first login.
+// The role always follows the IdP groups.
+func (s *OIDCService) syncUser(claims jwt.MapClaims, role string) (*models.User, error) {
+	subject, _ := claims.GetSubject()
+	externalID := s.Config.OIDCIssuerURL + "|" + subject
+
+	user, err := s.userRepo.GetByExternalID(externalID)
+	if err == nil {
+		if user.Role != role {
+			if err := s.userRepo.Update(user.ID, map[string]interface{}{"role": role}); err != nil {
+				return nil, err
+			}
+			user.Role = role
+		}
+		return user, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	username := firstClaim(claims, "preferred_username", "email")
+	if username == "" {
+		username = subject
+	}
+	// Never link to an existing local account by name; that would let the IdP take it over.
+	if _, err := s.userRepo.GetByUsername(username); err == nil {
+		username = username + "-" + utils.HashToken(externalID)[:8]
+	}
+
+	user = &models.User{
+		Username:   username,
+		Role:       role,
+		ExternalID: &externalID,
+		// No password: federated users can only sign in through the IdP.
+		PasswordHash: "",
+	}
+	if _, err := s.userRepo.Create(user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func parseRoleMapping(mapping string) (map[string]string, error) {
+	roles := make(map[string]string)
+	for _, entry := range strings.Split(mapping, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		group, role, found := strings.Cut(entry, "=")
+		if !found || group == "" || !models.RoleAtLeast(role, models.RoleViewer) {
+			return nil, fmt.Errorf("invalid OIDC_ROLE_MAPPING entry %q, expected group=viewer|editor|admin", entry)
+		}
+		roles[group] = role
+	}
+	return roles, nil
+}
+
+// groupsFromClaims accepts the groups claim as a list or a single string.
+func groupsFromClaims(value interface{}) []string {
+	switch groups := value.(type) {
+	case string:
+		return []string{groups}
+	case []interface{}:
+		result := make([]string, 0, len(groups))
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				result = append(result, name)
+			}
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+func firstClaim(claims jwt.MapClaims, names ...string) string {
+	for _, name := range names {
+		if value, ok := claims[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
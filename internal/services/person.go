@@ -0,0 +1,118 @@
+package services
+
+import (
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+type PersonService struct {
+	repo   repositories.PersonRepository
+	movies repositories.MovieRepository
+	log    logger.Logger
+}
+
+func NewPersonService(repo repositories.PersonRepository, movies repositories.MovieRepository, log logger.Logger) *PersonService {
+	return &PersonService{repo: repo, movies: movies, log: log}
+}
+
+func (s *PersonService) CreatePerson(request *models.PersonRequest) (*models.PersonResponse, error) {
+	s.log.Info("Creating person", zap.Any("request", request))
+	person, err := s.repo.Create(request)
+	if err != nil {
+		s.log.Error("Failed to create person", zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	response := toPersonResponse(person)
+	return &response, nil
+}
+
+// GetAllPeople lists people by name, optionally only those whose name contains the given text.
+func (s *PersonService) GetAllPeople(name string, limit, offset int) (models.PersonListResponse, error) {
+	people, count, err := s.repo.GetAll(name, limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch people", zap.String("name", name), zap.Error(err))
+		return models.PersonListResponse{}, err
+	}
+
+	response := models.PersonListResponse{People: make([]models.PersonResponse, 0, len(people)), Count: count}
+	for i := range people {
+		response.People = append(response.People, toPersonResponse(&people[i]))
+	}
+	return response, nil
+}
+
+func (s *PersonService) GetPersonByID(id uint) (*models.PersonResponse, error) {
+	person, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch person", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	response := toPersonResponse(person)
+	return &response, nil
+}
+
+func (s *PersonService) UpdatePerson(id uint, request *models.PersonRequest) (*models.PersonResponse, error) {
+	s.log.Info("Updating person", zap.Uint("id", id), zap.Any("request", request))
+	person, err := s.repo.Update(id, request)
+	if err != nil {
+		s.log.Error("Failed to update person", zap.Uint("id", id), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	response := toPersonResponse(person)
+	return &response, nil
+}
+
+// DeletePerson removes a person who is credited on no movie, failing with
+// repositories.ErrPersonHasCredits otherwise. Credits on movies in the trash count too.
+func (s *PersonService) DeletePerson(id uint) error {
+	s.log.Info("Deleting person", zap.Uint("id", id))
+	if _, err := s.repo.GetByID(id); err != nil {
+		s.log.Error("Failed to delete person", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	credits, err := s.movies.CountCredits(id)
+	if err != nil {
+		s.log.Error("Failed to delete person", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	if credits > 0 {
+		return repositories.ErrPersonHasCredits
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		s.log.Error("Failed to delete person", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// GetFilmography lists the person's credits on movies outside the trash, newest movie first.
+func (s *PersonService) GetFilmography(id uint) (*models.FilmographyResponse, error) {
+	person, err := s.GetPersonByID(id)
+	if err != nil {
+		return nil, err
+	}
+	credits, err := s.movies.GetFilmography(id)
+	if err != nil {
+		s.log.Error("Failed to fetch filmography", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+	return &models.FilmographyResponse{Person: *person, Credits: credits}, nil
+}
+
+func toPersonResponse(person *models.Person) models.PersonResponse {
+	return models.PersonResponse{
+		ID:        person.ID,
+		Name:      person.Name,
+		Bio:       person.Bio,
+		BirthYear: person.BirthYear,
+		CreatedAt: person.CreatedAt,
+		UpdatedAt: person.UpdatedAt,
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"errors"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrReviewerRequired is returned when a review is written by a caller that is not a
+	// user, such as an API key.
+	ErrReviewerRequired = errors.New("reviews are written by users")
+	// ErrNotReviewAuthor is returned when a user changes a review written by someone else.
+	ErrNotReviewAuthor = errors.New("review belongs to another user")
+)
+
+type ReviewService struct {
+	repo   repositories.ReviewRepository
+	movies repositories.MovieRepository
+	log    logger.Logger
+}
+
+func NewReviewService(repo repositories.ReviewRepository, movies repositories.MovieRepository, log logger.Logger) *ReviewService {
+	return &ReviewService{repo: repo, movies: movies, log: log}
+}
+
+// CreateReview adds the actor's review of a movie outside the trash. A second review of
+// the same movie fails with gorm.ErrDuplicatedKey.
+func (s *ReviewService) CreateReview(movieID uint, request *models.ReviewRequest, actor models.Actor) (*models.ReviewResponse, error) {
+	if actor.UserID == nil {
+		return nil, ErrReviewerRequired
+	}
+	s.log.Info("Creating review", zap.Uint("movie_id", movieID), zap.Uint("user_id", *actor.UserID), zap.Any("request", request))
+
+	if _, err := s.movies.GetByID(movieID); err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", movieID), zap.Error(err))
+		return nil, err
+	}
+	review, err := s.repo.Create(&models.Review{
+		MovieID:  movieID,
+		UserID:   *actor.UserID,
+		Username: actor.Username,
+		Rating:   request.Rating,
+		Body:     request.Body,
+	})
+	if err != nil {
+		s.log.Error("Failed to create review", zap.Uint("movie_id", movieID), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	response := toReviewResponse(review)
+	return &response, nil
+}
+
+// GetMovieReviews lists the reviews of a movie outside the trash, newest first.
+func (s *ReviewService) GetMovieReviews(movieID uint, limit, offset int) (models.ReviewListResponse, error) {
+	if _, err := s.movies.GetByID(movieID); err != nil {
+		s.log.Error("Failed to fetch movie", zap.Uint("id", movieID), zap.Error(err))
+		return models.ReviewListResponse{}, err
+	}
+	reviews, count, err := s.repo.GetByMovie(movieID, limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch reviews", zap.Uint("movie_id", movieID), zap.Error(err))
+		return models.ReviewListResponse{}, err
+	}
+
+	response := models.ReviewListResponse{Reviews: make([]models.ReviewResponse, 0, len(reviews)), Count: count}
+	for i := range reviews {
+		response.Reviews = append(response.Reviews, toReviewResponse(&reviews[i]))
+	}
+	return response, nil
+}
+
+func (s *ReviewService) GetReviewByID(id uint) (*models.ReviewResponse, error) {
+	review, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch review", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	response := toReviewResponse(review)
+	return &response, nil
+}
+
+// UpdateReview changes a review, which only its author may do.
+func (s *ReviewService) UpdateReview(id uint, request *models.ReviewRequest, actor models.Actor) (*models.ReviewResponse, error) {
+	s.log.Info("Updating review", zap.Uint("id", id), zap.Any("request", request))
+	if err := s.authorize(id, actor, false); err != nil {
+		return nil, err
+	}
+
+	review, err := s.repo.Update(id, request)
+	if err != nil {
+		s.log.Error("Failed to update review", zap.Uint("id", id), zap.Any("request", request), zap.Error(err))
+		return nil, err
+	}
+
+	response := toReviewResponse(review)
+	return &response, nil
+}
+
+// DeleteReview removes a review. Authors delete their own reviews and moderators, with
+// moderate set, anyone's.
+func (s *ReviewService) DeleteReview(id uint, actor models.Actor, moderate bool) error {
+	s.log.Info("Deleting review", zap.Uint("id", id))
+	if err := s.authorize(id, actor, moderate); err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(id); err != nil {
+		s.log.Error("Failed to delete review", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// authorize checks that the review exists and that the actor wrote it, unless moderating.
+func (s *ReviewService) authorize(id uint, actor models.Actor, moderate bool) error {
+	review, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch review", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	if moderate {
+		return nil
+	}
+	if actor.UserID == nil {
+		return ErrReviewerRequired
+	}
+	if review.UserID != *actor.UserID {
+		return ErrNotReviewAuthor
+	}
+	return nil
+}
+
+func toReviewResponse(review *models.Review) models.ReviewResponse {
+	return models.ReviewResponse{
+		ID:        review.ID,
+		MovieID:   review.MovieID,
+		UserID:    review.UserID,
+		Username:  review.Username,
+		Rating:    review.Rating,
+		Body:      review.Body,
+		CreatedAt: review.CreatedAt,
+		UpdatedAt: review.UpdatedAt,
+	}
+}
@@ -0,0 +1,201 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"itv-task/pkg/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is how many recovery codes are issued when 2FA is enabled.
+const recoveryCodeCount = 10
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+)
+
+// TwoFactorService manages TOTP enrollment and recovery codes.
+type TwoFactorService struct {
+	Config       *config.Config
+	userRepo     repositories.UserRepository
+	recoveryRepo repositories.RecoveryCodeRepository
+	auditRepo    repositories.AuthAuditRepository
+	throttle     *LoginThrottle
+	log          logger.Logger
+}
+
+func NewTwoFactorService(cfg *config.Config, userRepo repositories.UserRepository, recoveryRepo repositories.RecoveryCodeRepository,
+	auditRepo repositories.AuthAuditRepository, throttle *LoginThrottle, log logger.Logger) *TwoFactorService {
+	return &TwoFactorService{Config: cfg, userRepo: userRepo, recoveryRepo: recoveryRepo, auditRepo: auditRepo, throttle: throttle, log: log}
+}
+
+// Enroll generates a new TOTP secret for the user. It is not enforced until confirmed,
+// so an abandoned enrollment cannot lock the user out.
+func (s *TwoFactorService) Enroll(userID uint) (models.TwoFactorEnrollResponse, error) {
+	user, err := s.userRepo.GetByID(userID)
+	if err != nil {
+		return models.TwoFactorEnrollResponse{}, err
+	}
+	if user.TOTPEnabled {
+		return models.TwoFactorEnrollResponse{}, ErrTwoFactorAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return models.TwoFactorEnrollResponse{}, err
+	}
+	if err := s.userRepo.Update(userID, map[string]interface{}{"totp_secret": secret, "totp_last_counter": 0}); err != nil {
+		return models.TwoFactorEnrollResponse{}, err
+	}
+
+	s.log.Info("Started two-factor enrollment", zap.Uint("user_id", userID))
+	return models.TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(s.Config.TOTPIssuer, user.Username, secret),
+	}, nil
+}
+
+// Confirm enables 2FA once the user proves their authenticator produces valid codes,
+// and returns a fresh set of recovery codes.
+func (s *TwoFactorService) Confirm(request models.TwoFactorConfirmRequest) (models.TwoFactorConfirmResponse, error) {
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return models.TwoFactorConfirmResponse{}, err
+	}
+	if user.TOTPEnabled {
+		return models.TwoFactorConfirmResponse{}, ErrTwoFactorAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return models.TwoFactorConfirmResponse{}, ErrTwoFactorNotEnrolled
+	}
+
+	counter, ok := utils.ValidateTOTP(user.TOTPSecret, request.Code, time.Now())
+	if !ok {
+		return models.TwoFactorConfirmResponse{}, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return models.TwoFactorConfirmResponse{}, err
+	}
+	if err := s.recoveryRepo.Replace(user.ID, hashes); err != nil {
+		return models.TwoFactorConfirmResponse{}, err
+	}
+	if err := s.userRepo.Update(user.ID, map[string]interface{}{"totp_enabled": true, "totp_last_counter": counter}); err != nil {
+		return models.TwoFactorConfirmResponse{}, err
+	}
+
+	s.log.Info("Enabled two-factor authentication", zap.Uint("user_id", user.ID))
+	s.audit(user, models.AuthEventTwoFactorEnabled, "")
+	return models.TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// Disable turns 2FA off. It requires both the password and a current code so a stolen
+// access token alone cannot remove the second factor. Wrong passwords and codes count
+// towards the login lockout, which also applies here.
+func (s *TwoFactorService) Disable(request models.TwoFactorDisableRequest) error {
+	user, err := s.userRepo.GetByID(request.UserID)
+	if err != nil {
+		return err
+	}
+	if wait := s.throttle.Check(user.Username, request.IP); wait > 0 {
+		lockErr := &LoginLockedError{RetryAfter: wait}
+		s.audit(user, models.AuthEventLoginLocked, lockErr.Error())
+		return lockErr
+	}
+	if !checkUserPassword(user, request.Password) {
+		s.throttle.RecordFailure(user.Username, request.IP)
+		s.audit(user, models.AuthEventLoginFailure, "invalid password when disabling two-factor authentication")
+		return ErrInvalidCredentials
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+	if _, err := s.VerifyCode(user, request.Code); err != nil {
+		if errors.Is(err, ErrInvalidTwoFactorCode) {
+			s.throttle.RecordFailure(user.Username, request.IP)
+			s.audit(user, models.AuthEventTwoFactorFailure, "invalid code when disabling two-factor authentication")
+		}
+		return err
+	}
+	s.throttle.RecordSuccess(user.Username)
+
+	if err := s.userRepo.Update(user.ID, map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_counter": 0}); err != nil {
+		return err
+	}
+	if err := s.recoveryRepo.DeleteForUser(user.ID); err != nil {
+		return err
+	}
+
+	s.log.Info("Disabled two-factor authentication", zap.Uint("user_id", user.ID))
+	s.audit(user, models.AuthEventTwoFactorDisabled, "")
+	return nil
+}
+
+// VerifyCode checks a TOTP code, or a recovery code which is consumed. It reports
+// whether a recovery code was used.
+func (s *TwoFactorService) VerifyCode(user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if len(code) == utils.TOTPDigits {
+		counter, ok := utils.ValidateTOTP(user.TOTPSecret, code, time.Now())
+		if !ok {
+			return false, ErrInvalidTwoFactorCode
+		}
+		if err := s.userRepo.AdvanceTOTPCounter(user.ID, counter); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, ErrInvalidTwoFactorCode // Replayed code
+			}
+			return false, err
+		}
+		return false, nil
+	}
+
+	if err := s.recoveryRepo.Use(user.ID, utils.HashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrInvalidTwoFactorCode
+		}
+		return false, err
+	}
+	s.log.Warn("Recovery code used", zap.Uint("user_id", user.ID))
+	return true, nil
+}
+
+func (s *TwoFactorService) audit(user *models.User, event, reason string) {
+	entry := &models.AuthAuditLog{Event: event, Username: user.Username, UserID: &user.ID, Reason: reason}
+	if err := s.auditRepo.Create(entry); err != nil {
+		s.log.Error("Failed to write auth audit log", zap.String("event", event), zap.Error(err))
+	}
+}
+
+// generateRecoveryCodes returns codes formatted as xxxx-xxxx-xxxx along with their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		raw, err := utils.RandomHex(6)
+		if err != nil {
+			return nil, nil, err
+		}
+		code := fmt.Sprintf("%s-%s-%s", raw[0:4], raw[4:8], raw[8:12])
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode makes recovery codes insensitive to case, spaces and dashes.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"errors"
+	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
+	"itv-task/pkg/logger"
+	"itv-task/pkg/utils"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type UserService struct {
+	Config      *config.Config
+	repo        repositories.UserRepository
+	tokenRepo   repositories.AuthTokenRepository
+	revocations repositories.TokenRevocationStore
+	log         logger.Logger
+}
+
+func NewUserService(cfg *config.Config, repo repositories.UserRepository, tokenRepo repositories.AuthTokenRepository,
+	revocations repositories.TokenRevocationStore, log logger.Logger) *UserService {
+	return &UserService{Config: cfg, repo: repo, tokenRepo: tokenRepo, revocations: revocations, log: log}
+}
+
+// EnsureDefaultAdmin seeds the configured admin account when the users table is empty,
+// so a fresh deployment can still log in and create the rest of the team.
+func (s *UserService) EnsureDefaultAdmin() error {
+	count, err := s.repo.Count()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	if _, err := createUser(s.repo, s.Config.AdminUsername, s.Config.AdminPassword, models.RoleAdmin); err != nil {
+		s.log.Error("Failed to seed default admin", zap.String("username", s.Config.AdminUsername), zap.Error(err))
+		return err
+	}
+
+	s.log.Info("Seeded default admin user", zap.String("username", s.Config.AdminUsername))
+	return nil
+}
+
+func (s *UserService) CreateUser(request models.CreateUserRequest) (*models.UserResponse, error) {
+	if request.Role == "" {
+		request.Role = models.RoleViewer
+	}
+	s.log.Info("Creating user", zap.String("username", request.Username), zap.String("role", request.Role))
+
+	user, err := createUser(s.repo, request.Username, request.Password, request.Role)
+	if err != nil {
+		s.log.Error("Failed to create user", zap.String("username", request.Username), zap.Error(err))
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (s *UserService) GetUserByID(id uint) (*models.UserResponse, error) {
+	user, err := s.repo.GetByID(id)
+	if err != nil {
+		s.log.Error("Failed to fetch user", zap.Uint("id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return toUserResponse(user), nil
+}
+
+func (s *UserService) GetAllUsers(limit, offset int) (models.UserListResponse, error) {
+	users, count, err := s.repo.GetAll(limit, offset)
+	if err != nil {
+		s.log.Error("Failed to fetch users", zap.Int("limit", limit), zap.Int("offset", offset), zap.Error(err))
+		return models.UserListResponse{}, err
+	}
+
+	response := models.UserListResponse{Users: make([]models.UserResponse, 0, len(users)), Count: count}
+	for i := range users {
+		response.Users = append(response.Users, *toUserResponse(&users[i]))
+	}
+	return response, nil
+}
+
+func (s *UserService) UpdateUser(request models.UpdateUserRequest) (*models.UserResponse, error) {
+	s.log.Info("Updating user", zap.Uint("id", request.ID))
+
+	fields := map[string]interface{}{}
+	if request.Password != nil {
+		hash, err := utils.HashPassword(*request.Password)
+		if err != nil {
+			return nil, err
+		}
+		fields["password_hash"] = hash
+	}
+
+	if len(fields) > 0 {
+		if err := s.repo.Update(request.ID, fields); err != nil {
+			s.log.Error("Failed to update user", zap.Uint("id", request.ID), zap.Error(err))
+			return nil, err
+		}
+	}
+	if request.Password != nil {
+		if err := s.revokeSessions(request.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetUserByID(request.ID)
+}
+
+// AssignRole changes the role of a user. Tokens carry the role, so the user's existing
+// sessions are revoked and the new role takes effect on the next login.
+func (s *UserService) AssignRole(request models.UpdateUserRoleRequest) (*models.UserResponse, error) {
+	s.log.Info("Assigning role", zap.Uint("id", request.ID), zap.String("role", request.Role))
+
+	if err := s.repo.Update(request.ID, map[string]interface{}{"role": request.Role}); err != nil {
+		s.log.Error("Failed to assign role", zap.Uint("id", request.ID), zap.Error(err))
+		return nil, err
+	}
+	if err := s.revokeSessions(request.ID); err != nil {
+		return nil, err
+	}
+
+	return s.GetUserByID(request.ID)
+}
+
+func (s *UserService) DeleteUser(id uint) error {
+	s.log.Info("Deleting user", zap.Uint("id", id))
+
+	if err := s.repo.Delete(id); err != nil {
+		s.log.Error("Failed to delete user", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+
+	return s.revokeSessions(id)
+}
+
+// revokeSessions invalidates every refresh and access token issued to the user so far,
+// so a changed password, role or a deleted account cannot keep using old tokens.
+func (s *UserService) revokeSessions(id uint) error {
+	if err := s.tokenRepo.RevokeAllForUser(id, models.TokenRevokedByAdmin); err != nil {
+		s.log.Error("Failed to revoke refresh tokens", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	if err := s.revocations.RevokeUserTokensBefore(id, time.Now()); err != nil {
+		s.log.Error("Failed to revoke access tokens", zap.Uint("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// createUser hashes the password and stores a new account, rejecting taken usernames
+// with ErrUsernameTaken.
+func createUser(repo repositories.UserRepository, username, password, role string) (*models.User, error) {
+	if _, err := repo.GetByUsername(username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+	}
+	if _, err := repo.Create(user); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			// Someone else took the username since the lookup above.
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func toUserResponse(user *models.User) *models.UserResponse {
+	return &models.UserResponse{
+		ID:               user.ID,
+		Username:         user.Username,
+		Role:             user.Role,
+		TwoFactorEnabled: user.TOTPEnabled,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
+	}
+}
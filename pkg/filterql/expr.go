@@ -0,0 +1,191 @@
+package filterql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Expr is a parsed and checked filter expression.
+type Expr interface {
+	// String renders the expression in canonical form, which parses back to the same
+	// expression.
+	String() string
+	build(sql *strings.Builder, vars *[]interface{})
+	eval(value func(field string) interface{}) bool
+}
+
+// Logical combines two expressions with AND or OR.
+type Logical struct {
+	Op          string
+	Left, Right Expr
+}
+
+// Not negates an expression.
+type Not struct {
+	Operand Expr
+}
+
+// Comparison compares a field with a value, which is a string, int64, float64 or
+// time.Time according to the field's kind.
+type Comparison struct {
+	Field  string
+	Column string
+	Kind   Kind
+	Op     string
+	Value  interface{}
+}
+
+func (e *Logical) String() string {
+	return "(" + e.Left.String() + " " + e.Op + " " + e.Right.String() + ")"
+}
+
+func (e *Not) String() string {
+	return "NOT " + e.Operand.String()
+}
+
+func (e *Comparison) String() string {
+	var value string
+	switch v := e.Value.(type) {
+	case string:
+		value = quote(v)
+	case time.Time:
+		value = quote(v.Format(time.RFC3339Nano))
+	case float64:
+		// Without an exponent, which numbers cannot have in expressions.
+		value = strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		value = fmt.Sprint(v)
+	}
+	return e.Field + e.Op + value
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// SQL compiles the expression into a parameterized condition for a GORM Where.
+func SQL(e Expr) clause.Expr {
+	var sql strings.Builder
+	var vars []interface{}
+	e.build(&sql, &vars)
+	return clause.Expr{SQL: sql.String(), Vars: vars}
+}
+
+func (e *Logical) build(sql *strings.Builder, vars *[]interface{}) {
+	sql.WriteString("(")
+	e.Left.build(sql, vars)
+	sql.WriteString(" " + e.Op + " ")
+	e.Right.build(sql, vars)
+	sql.WriteString(")")
+}
+
+func (e *Not) build(sql *strings.Builder, vars *[]interface{}) {
+	sql.WriteString("NOT (")
+	e.Operand.build(sql, vars)
+	sql.WriteString(")")
+}
+
+// likeEscaper escapes the wildcards of a LIKE pattern, whose escape character is the
+// backslash by default.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (e *Comparison) build(sql *strings.Builder, vars *[]interface{}) {
+	switch e.Op {
+	case "~":
+		sql.WriteString(e.Column + " ILIKE ?")
+		*vars = append(*vars, "%"+likeEscaper.Replace(e.Value.(string))+"%")
+	case "^=":
+		sql.WriteString(e.Column + " ILIKE ?")
+		*vars = append(*vars, likeEscaper.Replace(e.Value.(string))+"%")
+	case "$=":
+		sql.WriteString(e.Column + " ILIKE ?")
+		*vars = append(*vars, "%"+likeEscaper.Replace(e.Value.(string)))
+	case "!=":
+		sql.WriteString(e.Column + " <> ?")
+		*vars = append(*vars, e.Value)
+	default:
+		sql.WriteString(e.Column + " " + e.Op + " ?")
+		*vars = append(*vars, e.Value)
+	}
+}
+
+// Eval evaluates the expression against a record in memory. The value function returns
+// the record's value of a field: a string for text fields, an integer or float for
+// numeric ones and a time.Time for times.
+func Eval(e Expr, value func(field string) interface{}) bool {
+	return e.eval(value)
+}
+
+func (e *Logical) eval(value func(field string) interface{}) bool {
+	if e.Op == "AND" {
+		return e.Left.eval(value) && e.Right.eval(value)
+	}
+	return e.Left.eval(value) || e.Right.eval(value)
+}
+
+func (e *Not) eval(value func(field string) interface{}) bool {
+	return !e.Operand.eval(value)
+}
+
+func (e *Comparison) eval(value func(field string) interface{}) bool {
+	actual := value(e.Field)
+	switch e.Kind {
+	case Text:
+		s, want := actual.(string), e.Value.(string)
+		switch e.Op {
+		case "~":
+			return strings.Contains(strings.ToLower(s), strings.ToLower(want))
+		case "^=":
+			return strings.HasPrefix(strings.ToLower(s), strings.ToLower(want))
+		case "$=":
+			return strings.HasSuffix(strings.ToLower(s), strings.ToLower(want))
+		}
+		return holds(e.Op, strings.Compare(s, want))
+	case Time:
+		return holds(e.Op, actual.(time.Time).Compare(e.Value.(time.Time)))
+	default:
+		a, b := toFloat(actual), toFloat(e.Value)
+		switch {
+		case a < b:
+			return holds(e.Op, -1)
+		case a > b:
+			return holds(e.Op, 1)
+		}
+		return holds(e.Op, 0)
+	}
+}
+
+// holds reports whether the ordering operator holds for a comparison result.
+func holds(op string, cmp int) bool {
+	switch op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	default:
+		return cmp >= 0
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,101 @@
+package filterql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQL(t *testing.T) {
+	for _, tc := range []struct {
+		input string
+		sql   string
+		vars  []interface{}
+	}{
+		{`year=1995`, `movies.year = ?`, []interface{}{int64(1995)}},
+		{`year!=1995`, `movies.year <> ?`, []interface{}{int64(1995)}},
+		{`rating>=7.5`, `movies.rating_average >= ?`, []interface{}{7.5}},
+		{`created_at<"2024-01-31"`, `movies.created_at < ?`, []interface{}{time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)}},
+		{`title~"heat"`, `movies.title ILIKE ?`, []interface{}{`%heat%`}},
+		{`title^="the"`, `movies.title ILIKE ?`, []interface{}{`the%`}},
+		{`title$="end"`, `movies.title ILIKE ?`, []interface{}{`%end`}},
+		// LIKE wildcards and the escape character in the value match literally.
+		{`title~"100%_\\"`, `movies.title ILIKE ?`, []interface{}{`%100\%\_\\%`}},
+		{`title^="a_b"`, `movies.title ILIKE ?`, []interface{}{`a\_b%`}},
+		// Only the pattern operators escape; equality compares the value as is.
+		{`title="100%"`, `movies.title = ?`, []interface{}{`100%`}},
+		{
+			`year>=1990 AND NOT (title~"x" OR rating<5)`,
+			`(movies.year >= ? AND NOT ((movies.title ILIKE ? OR movies.rating_average < ?)))`,
+			[]interface{}{int64(1990), `%x%`, 5.0},
+		},
+	} {
+		got := SQL(mustParse(t, tc.input))
+		if got.SQL != tc.sql || !reflect.DeepEqual(got.Vars, tc.vars) {
+			t.Errorf("SQL of %s: expected %s %#v, got %s %#v", tc.input, tc.sql, tc.vars, got.SQL, got.Vars)
+		}
+	}
+}
+
+func TestEval(t *testing.T) {
+	record := map[string]interface{}{
+		"title":      "The Dark Knight",
+		"year":       2008,
+		"rating":     9.0,
+		"created_at": time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+	}
+	value := func(field string) interface{} { return record[field] }
+
+	for _, tc := range []struct {
+		input string
+		want  bool
+	}{
+		{`title="The Dark Knight"`, true},
+		{`title="the dark knight"`, false},
+		{`title~"DARK"`, true},
+		{`title^="the"`, true},
+		{`title$="KNIGHT"`, true},
+		{`title$="dark"`, false},
+		{`title~"%"`, false},
+		{`title<"Z"`, true},
+		{`year=2008`, true},
+		{`year!=2008`, false},
+		{`year<2008`, false},
+		{`year<=2008`, true},
+		{`year>2007`, true},
+		{`year>=2009`, false},
+		{`rating>8.5`, true},
+		{`rating=9`, true},
+		{`created_at>"2024-01-31"`, true},
+		{`created_at<"2024-01-31T12:00:00Z"`, false},
+		{`created_at="2024-01-31T13:00:00+01:00"`, true},
+		{`year=1 OR year=2008 AND rating>5`, true},
+		{`(year=1 OR year=2008) AND rating<5`, false},
+		{`NOT year=2008 OR title~"dark"`, true},
+		{`NOT (year=2008 OR title~"dark")`, false},
+	} {
+		if got := Eval(mustParse(t, tc.input), value); got != tc.want {
+			t.Errorf("eval %s: expected %v, got %v", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestStringRoundTrip(t *testing.T) {
+	for _, input := range []string{
+		`year>=1990 AND year<2000 AND (title~"nolan" OR title^="The")`,
+		`NOT (rating<5 OR rating>=9.25) AND year!=-1`,
+		`not not title$="end" or created_at<="2024-01-31"`,
+		`created_at>"2024-01-31T10:30:00.123456789+02:00"`,
+		`title="say \"hi\" \\ 日本語"`,
+		`rating>0.0000001 AND rating<12345678912345`,
+	} {
+		expr := mustParse(t, input)
+		again := mustParse(t, expr.String())
+		if again.String() != expr.String() {
+			t.Fatalf("round trip of %s: %s became %s", input, expr, again)
+		}
+		if !reflect.DeepEqual(SQL(again), SQL(expr)) {
+			t.Fatalf("round trip of %s changed the expression: %#v became %#v", input, SQL(expr), SQL(again))
+		}
+	}
+}
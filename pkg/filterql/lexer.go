@@ -0,0 +1,113 @@
+package filterql
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+// token is a lexeme of the input. pos is its byte offset; text is the string value for
+// strings, with the quotes removed and escapes resolved, and the source text otherwise.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// operators lists the comparison operators, two-character ones first so they win.
+var operators = []string{"!=", ">=", "<=", "^=", "$=", "=", ">", "<", "~"}
+
+// lex splits the input into tokens, ending with a tokenEOF.
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for pos := 0; ; {
+		for pos < len(input) && isSpace(input[pos]) {
+			pos++
+		}
+		if pos == len(input) {
+			return append(tokens, token{kind: tokenEOF, pos: pos}), nil
+		}
+
+		c := input[pos]
+		switch {
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: pos})
+			pos++
+		case c == '"':
+			text, end, err := lexString(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, text: text, pos: pos})
+			pos = end
+		case isDigit(c) || (c == '-' && pos+1 < len(input) && isDigit(input[pos+1])):
+			end := pos + 1
+			for end < len(input) && (isDigit(input[end]) || input[end] == '.') {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: input[pos:end], pos: pos})
+			pos = end
+		case isIdentStart(c):
+			end := pos + 1
+			for end < len(input) && (isIdentStart(input[end]) || isDigit(input[end])) {
+				end++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[pos:end], pos: pos})
+			pos = end
+		default:
+			operator := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(input[pos:], candidate) {
+					operator = candidate
+					break
+				}
+			}
+			if operator == "" {
+				r, _ := utf8.DecodeRuneInString(input[pos:])
+				return nil, errorAt(input, pos, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+			pos += len(operator)
+		}
+	}
+}
+
+// lexString reads the double-quoted string starting at pos, in which \" and \\ stand for
+// a quote and a backslash, and returns its value and the offset after its closing quote.
+func lexString(input string, pos int) (string, int, error) {
+	var value strings.Builder
+	for i := pos + 1; i < len(input); i++ {
+		switch input[i] {
+		case '"':
+			return value.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(input) && (input[i+1] == '"' || input[i+1] == '\\') {
+				i++
+				value.WriteByte(input[i])
+				continue
+			}
+			return "", 0, errorAt(input, i, `invalid escape; only \" and \\ are allowed`)
+		default:
+			value.WriteByte(input[i])
+		}
+	}
+	return "", 0, errorAt(input, pos, "unterminated string")
+}
+
+func isSpace(c byte) bool      { return c == ' ' || c == '\t' || c == '\n' || c == '\r' }
+func isDigit(c byte) bool      { return c >= '0' && c <= '9' }
+func isIdentStart(c byte) bool { return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
@@ -0,0 +1,283 @@
+// Package filterql parses the filter expressions of list endpoints, such as
+//
+//	year>=1990 AND year<2000 AND (director~"nolan" OR title^="The")
+//
+// and turns them into parameterized SQL or evaluates them against records in memory.
+// Expressions combine comparisons with AND, OR, NOT and parentheses; AND binds tighter
+// than OR. A comparison names a field, an operator and a value:
+//
+//	=  !=  <  <=  >  >=   equality and order, for every kind of field
+//	~                     contains, ignoring case, for text fields
+//	^=  $=                starts with and ends with, ignoring case, for text fields
+//
+// Text and time values are double-quoted, with \" and \\ as the only escapes; times are
+// dates like "2024-01-31" or RFC 3339 timestamps. Numbers are bare. Only the fields the
+// caller declares can be used, and errors report the 1-based character position of the
+// problem.
+package filterql
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits on the size of expressions, so that a request cannot make parsing or the query
+// it compiles to arbitrarily expensive.
+const (
+	MaxLength      = 2000
+	MaxComparisons = 50
+	maxDepth       = 20
+)
+
+// Kind is the type of a field, which decides the values and operators it takes.
+type Kind int
+
+const (
+	Text Kind = iota
+	Integer
+	Number
+	Time
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Integer:
+		return "integer"
+	case Number:
+		return "number"
+	case Time:
+		return "time"
+	default:
+		return "text"
+	}
+}
+
+// Field declares a field expressions can use and the SQL column it compiles to. Columns
+// come from the caller, never from the input.
+type Field struct {
+	Kind   Kind
+	Column string
+}
+
+// Error is a syntax or type error in an expression.
+type Error struct {
+	Position int // 1-based position of the offending character
+	Message  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("position %d: %s", e.Position, e.Message)
+}
+
+// errorAt builds an Error for the byte offset of the input.
+func errorAt(input string, offset int, format string, args ...interface{}) *Error {
+	return &Error{Position: utf8.RuneCountInString(input[:offset]) + 1, Message: fmt.Sprintf(format, args...)}
+}
+
+// Parse parses the input and checks it against the fields. It returns an *Error for
+// invalid expressions.
+func Parse(input string, fields map[string]Field) (Expr, error) {
+	if len(input) > MaxLength {
+		return nil, &Error{Position: MaxLength + 1, Message: fmt.Sprintf("expression is longer than %d bytes", MaxLength)}
+	}
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{input: input, tokens: tokens, fields: fields}
+	expr, err := p.or(0)
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, p.errorAt(next, "unexpected %s", describe(next))
+	}
+	return expr, nil
+}
+
+type parser struct {
+	input       string
+	tokens      []token
+	next        int
+	fields      map[string]Field
+	comparisons int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) errorAt(t token, format string, args ...interface{}) *Error {
+	return errorAt(p.input, t.pos, format, args...)
+}
+
+// keyword reports whether the next token is the keyword, matched ignoring case, and
+// consumes it if so.
+func (p *parser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		p.advance()
+		return true
+	}
+	return false
+}
+
+// or := and { OR and }
+func (p *parser) or(depth int) (Expr, error) {
+	left, err := p.and(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.and(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// and := unary { AND unary }
+func (p *parser) and(depth int) (Expr, error) {
+	left, err := p.unary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.unary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &Logical{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+// unary := NOT unary | "(" or ")" | comparison
+func (p *parser) unary(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, p.errorAt(p.peek(), "expression is nested more than %d levels deep", maxDepth)
+	}
+	if p.keyword("NOT") {
+		operand, err := p.unary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Operand: operand}, nil
+	}
+	if p.peek().kind == tokenLParen {
+		p.advance()
+		inner, err := p.or(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.advance(); t.kind != tokenRParen {
+			return nil, p.errorAt(t, `expected ")" but found %s`, describe(t))
+		}
+		return inner, nil
+	}
+	return p.comparison()
+}
+
+// comparison := field operator value
+func (p *parser) comparison() (Expr, error) {
+	name := p.advance()
+	if name.kind != tokenIdent || isKeyword(name.text) {
+		return nil, p.errorAt(name, "expected a field name but found %s", describe(name))
+	}
+	field, ok := p.fields[name.text]
+	if !ok {
+		return nil, p.errorAt(name, "unknown field %q; use one of %s", name.text, p.fieldNames())
+	}
+	if p.comparisons++; p.comparisons > MaxComparisons {
+		return nil, p.errorAt(name, "expression has more than %d comparisons", MaxComparisons)
+	}
+
+	op := p.advance()
+	if op.kind != tokenOperator {
+		return nil, p.errorAt(op, "expected an operator after %q but found %s", name.text, describe(op))
+	}
+	if (op.text == "~" || op.text == "^=" || op.text == "$=") && field.Kind != Text {
+		return nil, p.errorAt(op, "operator %s only applies to text fields, not to %s field %q", op.text, field.Kind, name.text)
+	}
+
+	value, err := p.value(name.text, field)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Field: name.text, Column: field.Column, Kind: field.Kind, Op: op.text, Value: value}, nil
+}
+
+// value reads the value of a comparison on the field and converts it to the field's kind:
+// string, int64, float64 or time.Time.
+func (p *parser) value(name string, field Field) (interface{}, error) {
+	t := p.advance()
+	switch field.Kind {
+	case Integer:
+		if t.kind == tokenNumber {
+			if value, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+				return value, nil
+			}
+		}
+		return nil, p.errorAt(t, "expected a whole number for %q but found %s", name, describe(t))
+	case Number:
+		if t.kind == tokenNumber {
+			if value, err := strconv.ParseFloat(t.text, 64); err == nil {
+				return value, nil
+			}
+		}
+		return nil, p.errorAt(t, "expected a number for %q but found %s", name, describe(t))
+	case Time:
+		if t.kind == tokenString {
+			for _, layout := range []string{time.DateOnly, time.RFC3339Nano} {
+				if value, err := time.Parse(layout, t.text); err == nil {
+					return value, nil
+				}
+			}
+		}
+		return nil, p.errorAt(t, `expected a quoted date like "2024-01-31" or RFC 3339 time for %q but found %s`, name, describe(t))
+	default:
+		if t.kind == tokenString {
+			return t.text, nil
+		}
+		return nil, p.errorAt(t, "expected a quoted string for %q but found %s", name, describe(t))
+	}
+}
+
+func (p *parser) fieldNames() string {
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+func isKeyword(word string) bool {
+	return strings.EqualFold(word, "AND") || strings.EqualFold(word, "OR") || strings.EqualFold(word, "NOT")
+}
+
+// describe names a token for error messages.
+func describe(t token) string {
+	switch t.kind {
+	case tokenEOF:
+		return "the end of the expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
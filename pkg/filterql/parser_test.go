@@ -0,0 +1,222 @@
+package filterql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testFields = map[string]Field{
+	"title":      {Kind: Text, Column: "movies.title"},
+	"year":       {Kind: Integer, Column: "movies.year"},
+	"rating":     {Kind: Number, Column: "movies.rating_average"},
+	"created_at": {Kind: Time, Column: "movies.created_at"},
+}
+
+func mustParse(t *testing.T, input string) Expr {
+	t.Helper()
+	expr, err := Parse(input, testFields)
+	if err != nil {
+		t.Fatalf("parse %q: %v", input, err)
+	}
+	return expr
+}
+
+// parseError parses an invalid input and returns its *Error.
+func parseError(t *testing.T, input string) *Error {
+	t.Helper()
+	_, err := Parse(input, testFields)
+	var parseErr *Error
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("parse %q: expected an *Error, got %v", input, err)
+	}
+	return parseErr
+}
+
+func TestParsePrecedence(t *testing.T) {
+	for _, tc := range []struct{ input, want string }{
+		{`year=1 OR year=2 AND year=3`, `(year=1 OR (year=2 AND year=3))`},
+		{`year=1 AND year=2 OR year=3`, `((year=1 AND year=2) OR year=3)`},
+		{`(year=1 OR year=2) AND year=3`, `((year=1 OR year=2) AND year=3)`},
+		{`year=1 OR year=2 OR year=3`, `((year=1 OR year=2) OR year=3)`},
+		{`NOT year=1 AND year=2`, `(NOT year=1 AND year=2)`},
+		{`NOT (year=1 OR year=2)`, `NOT (year=1 OR year=2)`},
+		{`NOT NOT year=1`, `NOT NOT year=1`},
+		{`year=1 and year=2 Or not year=3`, `((year=1 AND year=2) OR NOT year=3)`},
+		{"  year=1\tAND\n(((year=2)))  ", `(year=1 AND year=2)`},
+	} {
+		if got := mustParse(t, tc.input).String(); got != tc.want {
+			t.Errorf("parse %q: expected %s, got %s", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestParseOperatorsAndKinds(t *testing.T) {
+	for _, op := range []string{"=", "!=", "<", "<=", ">", ">="} {
+		for _, tc := range []struct {
+			field, value string
+			want         interface{}
+		}{
+			{"title", `"Heat"`, "Heat"},
+			{"year", "1995", int64(1995)},
+			{"year", "-5", int64(-5)},
+			{"rating", "7.5", 7.5},
+			{"rating", "7", 7.0},
+			{"created_at", `"2024-01-31"`, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)},
+			{"created_at", `"2024-01-31T10:30:00.5Z"`, time.Date(2024, 1, 31, 10, 30, 0, 5e8, time.UTC)},
+		} {
+			input := tc.field + op + tc.value
+			comparison, ok := mustParse(t, input).(*Comparison)
+			if !ok {
+				t.Fatalf("parse %q: expected a comparison", input)
+			}
+			if comparison.Field != tc.field || comparison.Column != testFields[tc.field].Column ||
+				comparison.Kind != testFields[tc.field].Kind || comparison.Op != op {
+				t.Fatalf("parse %q: got %+v", input, comparison)
+			}
+			if want, isTime := tc.want.(time.Time); isTime {
+				if !comparison.Value.(time.Time).Equal(want) {
+					t.Fatalf("parse %q: expected %v, got %v", input, want, comparison.Value)
+				}
+			} else if comparison.Value != tc.want {
+				t.Fatalf("parse %q: expected %#v, got %#v", input, tc.want, comparison.Value)
+			}
+		}
+	}
+
+	for _, op := range []string{"~", "^=", "$="} {
+		if comparison := mustParse(t, `title`+op+`"the"`).(*Comparison); comparison.Op != op || comparison.Value != "the" {
+			t.Fatalf("parse title%sthe: got %+v", op, comparison)
+		}
+		for _, field := range []string{"year", "rating", "created_at"} {
+			err := parseError(t, field+op+`1`)
+			if !strings.Contains(err.Message, "only applies to text fields") {
+				t.Fatalf("parse %s%s1: unexpected error %v", field, op, err)
+			}
+		}
+	}
+}
+
+func TestParseRejectsMistypedValues(t *testing.T) {
+	for _, input := range []string{
+		`title=Heat`,
+		`title=1995`,
+		`year="1995"`,
+		`year=19.5`,
+		`year=99999999999999999999`,
+		`rating="7"`,
+		`rating=1.2.3`,
+		`created_at=2024`,
+		`created_at="yesterday"`,
+		`created_at="2024-13-01"`,
+	} {
+		if err := parseError(t, input); !strings.Contains(err.Message, "expected") {
+			t.Errorf("parse %q: unexpected error %v", input, err)
+		}
+	}
+}
+
+func TestParseQuotingAndEscapes(t *testing.T) {
+	for _, tc := range []struct{ input, want string }{
+		{`title=""`, ``},
+		{`title="say \"hi\""`, `say "hi"`},
+		{`title="back\\slash"`, `back\slash`},
+		{`title="\\\""`, `\"`},
+		{`title="AND OR NOT ( ) ="`, `AND OR NOT ( ) =`},
+		{`title="日本語"`, `日本語`},
+	} {
+		if got := mustParse(t, tc.input).(*Comparison).Value; got != tc.want {
+			t.Errorf("parse %s: expected %q, got %q", tc.input, tc.want, got)
+		}
+	}
+}
+
+func TestParseUnknownFields(t *testing.T) {
+	err := parseError(t, `year>=1990 AND director="Mann"`)
+	if err.Position != 16 {
+		t.Fatalf("expected position 16, got %d", err.Position)
+	}
+	want := `unknown field "director"; use one of created_at, rating, title, year`
+	if err.Message != want {
+		t.Fatalf("expected %q, got %q", want, err.Message)
+	}
+
+	// Field names are case-sensitive, and columns cannot be named directly.
+	for _, input := range []string{`Title="Heat"`, `movies.title="Heat"`} {
+		parseError(t, input)
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		position int
+		message  string
+	}{
+		{``, 1, `expected a field name but found the end of the expression`},
+		{`year>=`, 7, `expected a whole number for "year" but found the end of the expression`},
+		{`year 1995`, 6, `expected an operator after "year" but found "1995"`},
+		{`AND=1`, 1, `expected a field name but found "AND"`},
+		{`year=1 year=2`, 8, `unexpected "year"`},
+		{`(year=1`, 8, `expected ")" but found the end of the expression`},
+		{`year=1)`, 7, `unexpected ")"`},
+		{`year=1 # 2`, 8, `unexpected character '#'`},
+		{`title="abc`, 7, `unterminated string`},
+		{`title="a\n"`, 9, `invalid escape; only \" and \\ are allowed`},
+		// Positions count characters, not bytes.
+		{`title="é" AND bogus=1`, 15, `unknown field "bogus"; use one of created_at, rating, title, year`},
+		{`title="日本" ✓`, 12, `unexpected character '✓'`},
+		{`title="ü" OR year="x"`, 19, `expected a whole number for "year" but found "x"`},
+		{`title="ü\t"`, 9, `invalid escape; only \" and \\ are allowed`},
+	} {
+		err := parseError(t, tc.input)
+		if err.Position != tc.position || err.Message != tc.message {
+			t.Errorf("parse %q: expected position %d: %s, got %v", tc.input, tc.position, tc.message, err)
+		}
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	t.Run("length", func(t *testing.T) {
+		fits := `title="` + strings.Repeat("x", MaxLength-len(`title=""`)) + `"`
+		mustParse(t, fits)
+
+		err := parseError(t, fits+" ")
+		if err.Position != MaxLength+1 {
+			t.Fatalf("expected position %d, got %d", MaxLength+1, err.Position)
+		}
+	})
+
+	t.Run("comparisons", func(t *testing.T) {
+		comparisons := make([]string, MaxComparisons)
+		for i := range comparisons {
+			comparisons[i] = "year=1"
+		}
+		mustParse(t, strings.Join(comparisons, " OR "))
+
+		err := parseError(t, strings.Join(append(comparisons, "year=1"), " OR "))
+		// The extra comparison starts after MaxComparisons of "year=1 OR ".
+		if err.Position != MaxComparisons*len("year=1 OR ")+1 || !strings.Contains(err.Message, "more than 50 comparisons") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("parentheses", func(t *testing.T) {
+		mustParse(t, strings.Repeat("(", maxDepth)+"year=1"+strings.Repeat(")", maxDepth))
+
+		err := parseError(t, strings.Repeat("(", maxDepth+1)+"year=1"+strings.Repeat(")", maxDepth+1))
+		if err.Position != maxDepth+2 || !strings.Contains(err.Message, "nested more than 20 levels") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+
+	t.Run("negations", func(t *testing.T) {
+		mustParse(t, strings.Repeat("NOT ", maxDepth)+"year=1")
+
+		err := parseError(t, strings.Repeat("NOT ", maxDepth+1)+"year=1")
+		if err.Position != (maxDepth+1)*len("NOT ")+1 || !strings.Contains(err.Message, "nested more than 20 levels") {
+			t.Fatalf("unexpected error %v", err)
+		}
+	})
+}
@@ -1,7 +1,8 @@
 package utils
 
 import (
-	"itv-task/config"
+	"itv-task/internal/models"
+	"itv-task/internal/repositories"
 	"itv-task/pkg/utils"
 	"log"
 	"net/http"
@@ -10,9 +11,28 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthMiddleware checks the validity of the access token
-func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
+// APIKeyAuthenticator resolves the raw value of an X-API-Key header.
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(rawKey string) (*models.APIKey, error)
+}
+
+// AuthMiddleware authenticates the caller with either a Bearer access token, which must be
+// valid and not revoked, or an API key in the X-API-Key header.
+func AuthMiddleware(keys *utils.KeySet, revocations repositories.TokenRevocationStore, apiKeys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if rawKey := c.GetHeader("X-API-Key"); rawKey != "" {
+			key, err := apiKeys.AuthenticateAPIKey(rawKey)
+			if err != nil {
+				utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Invalid, expired or revoked API key")
+				c.Abort()
+				return
+			}
+
+			c.Set(utils.ContextAPIKeyKey, key)
+			c.Next()
+			return
+		}
+
 		token := c.GetHeader("Authorization")
 		if token == "" {
 			utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Missing Authorization header")
@@ -28,18 +48,99 @@ func AuthMiddleware(cfg *config.Config) gin.HandlerFunc {
 			return
 		}
 
-		claims, err := utils.ValidateToken(parts[1], false, cfg) // Change `nil` if you need to pass config
+		claims, err := utils.ValidateToken(parts[1], false, keys)
 		if err != nil {
 			utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Invalid or expired token")
 			c.Abort()
 			return
 		}
 
-		c.Set("user", claims)
+		jti, _ := claims["jti"].(string)
+		userID, _ := utils.UserIDFromClaims(claims)
+		revoked, err := revocations.IsRevoked(jti, userID, utils.IssuedAtFromClaims(claims))
+		if err != nil {
+			utils.SendErrorResponse(c, http.StatusInternalServerError, "Internal server error", "Failed to check token revocation")
+			c.Abort()
+			return
+		}
+		if revoked {
+			utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Token has been revoked")
+			c.Abort()
+			return
+		}
+
+		c.Set(utils.ContextUserKey, claims)
 		c.Next()
 	}
 }
 
+// RequireRole allows the request only when the caller's role is at least the given role.
+// API keys have no role and are always rejected. It must run after AuthMiddleware.
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := utils.APIKeyFromContext(c); isKey {
+			utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "API keys cannot access this endpoint")
+			c.Abort()
+			return
+		}
+
+		callerRole, ok := roleFromContext(c)
+		if !ok {
+			return
+		}
+
+		if !models.RoleAtLeast(callerRole, role) {
+			utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "Role "+role+" required")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermission allows the request only when the caller's role, or the scopes of the
+// calling API key, grant the permission. It must run after AuthMiddleware.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, isKey := utils.APIKeyFromContext(c); isKey {
+			if !key.HasScope(permission) {
+				utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "API key scope "+permission+" required")
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		callerRole, ok := roleFromContext(c)
+		if !ok {
+			return
+		}
+
+		if !models.RoleHasPermission(callerRole, permission) {
+			utils.SendErrorResponse(c, http.StatusForbidden, "Forbidden", "Permission "+permission+" required")
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// roleFromContext reads the role claim, aborting the request when no claims are present.
+func roleFromContext(c *gin.Context) (string, bool) {
+	claims, ok := utils.ClaimsFromContext(c)
+	if !ok {
+		utils.SendErrorResponse(c, http.StatusUnauthorized, "Unauthorized", "Missing token claims")
+		c.Abort()
+		return "", false
+	}
+
+	role, _ := claims["role"].(string)
+	return role, true
+}
+
 // AuthLogger logs requests for debugging purposes
 func AuthLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"itv-task/pkg/utils"
+	"log"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// RequestID tags every request with an ID, reusing the caller's X-Request-ID when it is
+// well formed, and echoes it back in the response.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			var err error
+			if id, err = utils.RandomHex(16); err != nil {
+				log.Println("❌ Failed to generate request ID:", err)
+				id = ""
+			}
+		}
+
+		c.Set(utils.ContextRequestIDKey, id)
+		if id != "" {
+			c.Header(RequestIDHeader, id)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,302 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"itv-task/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Supported values of config.JWTSigningAlgorithm.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+type verificationKey struct {
+	method jwt.SigningMethod
+	public crypto.PublicKey
+}
+
+// KeySet holds the keys used to sign and verify tokens.
+//
+// With HS256 tokens are signed with the shared access/refresh secrets. With RS256 or
+// EdDSA they are signed by a private key identified by the kid header, and verified by
+// any of the configured public keys so keys can be rotated without logging users out.
+type KeySet struct {
+	algorithm     string
+	accessSecret  []byte
+	refreshSecret []byte
+
+	signingKID    string
+	signingMethod jwt.SigningMethod
+	signingKey    crypto.Signer
+	verification  map[string]verificationKey
+}
+
+// NewKeySet builds the KeySet described by the JWT_* settings.
+func NewKeySet(cfg *config.Config) (*KeySet, error) {
+	keys := &KeySet{
+		algorithm:     cfg.JWTSigningAlgorithm,
+		accessSecret:  []byte(cfg.JWTAccessSecret),
+		refreshSecret: []byte(cfg.JWTRefreshSecret),
+		verification:  make(map[string]verificationKey),
+	}
+	if keys.algorithm == "" {
+		keys.algorithm = AlgorithmHS256
+	}
+
+	switch keys.algorithm {
+	case AlgorithmHS256:
+		return keys, nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing algorithm %q", keys.algorithm)
+	}
+
+	if cfg.JWTSigningKeyFile == "" {
+		return nil, fmt.Errorf("JWT_SIGNING_KEY_FILE is required for %s", keys.algorithm)
+	}
+	signer, err := loadPrivateKey(cfg.JWTSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	method, err := methodForKey(signer.Public())
+	if err != nil {
+		return nil, err
+	}
+	if method.Alg() != keys.algorithm {
+		return nil, fmt.Errorf("signing key is %s but JWT_SIGNING_ALGORITHM is %s", method.Alg(), keys.algorithm)
+	}
+
+	keys.signingKey = signer
+	keys.signingMethod = method
+	keys.signingKID = cfg.JWTSigningKeyID
+	if keys.signingKID == "" {
+		if keys.signingKID, err = keyID(signer.Public()); err != nil {
+			return nil, err
+		}
+	}
+	keys.verification[keys.signingKID] = verificationKey{method: method, public: signer.Public()}
+
+	// JWT_VERIFICATION_KEYS lists additional keys as "kid=path,kid=path"; these are the
+	// previous (or upcoming) keys that tokens may still be signed with.
+	for _, entry := range strings.Split(cfg.JWTVerificationKeys, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, found := strings.Cut(entry, "=")
+		if !found || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_VERIFICATION_KEYS entry %q, expected kid=path", entry)
+		}
+		public, err := loadPublicKey(path)
+		if err != nil {
+			return nil, err
+		}
+		method, err := methodForKey(public)
+		if err != nil {
+			return nil, err
+		}
+		keys.verification[kid] = verificationKey{method: method, public: public}
+	}
+
+	return keys, nil
+}
+
+func (k *KeySet) asymmetric() bool {
+	return k.algorithm != AlgorithmHS256
+}
+
+// Sign signs the claims as an access or refresh token.
+func (k *KeySet) Sign(claims jwt.MapClaims, isRefresh bool) (string, error) {
+	if !k.asymmetric() {
+		secret := k.accessSecret
+		if isRefresh {
+			secret = k.refreshSecret
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	}
+
+	token := jwt.NewWithClaims(k.signingMethod, claims)
+	token.Header["kid"] = k.signingKID
+	return token.SignedString(k.signingKey)
+}
+
+// Parse verifies the token signature, choosing the verification key by the kid header.
+func (k *KeySet) Parse(tokenString string, isRefresh bool) (*jwt.Token, error) {
+	if !k.asymmetric() {
+		secret := k.accessSecret
+		if isRefresh {
+			secret = k.refreshSecret
+		}
+		return jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+			return secret, nil
+		}, jwt.WithValidMethods([]string{AlgorithmHS256}))
+	}
+
+	return jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := k.verification[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+		// Pin the algorithm to the key type so a token cannot pick its own verification method.
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+		}
+		return key.public, nil
+	}, jwt.WithValidMethods([]string{AlgorithmRS256, AlgorithmEdDSA}))
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty" example:"RSA"`
+	Kid string `json:"kid" example:"2024-01"`
+	Use string `json:"use" example:"sig"`
+	Alg string `json:"alg" example:"RS256"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public verification keys. It is empty when tokens are signed with
+// a shared secret, which must never be published.
+func (k *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(k.verification))
+	for kid := range k.verification {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	set := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		if jwk, err := publicJWK(kid, k.verification[kid].public); err == nil {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+func publicJWK(kid string, public crypto.PublicKey) (JWK, error) {
+	switch key := public.(type) {
+	case *rsa.PublicKey:
+		return JWK{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: AlgorithmRS256,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}, nil
+	case ed25519.PublicKey:
+		return JWK{
+			Kty: "OKP",
+			Kid: kid,
+			Use: "sig",
+			Alg: AlgorithmEdDSA,
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key),
+		}, nil
+	default:
+		return JWK{}, fmt.Errorf("unsupported key type %T", public)
+	}
+}
+
+func methodForKey(public crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch public.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", public)
+	}
+}
+
+// keyID derives a stable kid from the public key when none is configured.
+func keyID(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file %s: %w", path, err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("key file %s is not PEM encoded", path)
+	}
+	return block, nil
+}
+
+// loadPrivateKey reads an RSA (PKCS#1 or PKCS#8) or Ed25519 (PKCS#8) private key.
+func loadPrivateKey(path string) (crypto.Signer, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", path, err)
+	}
+	switch signer := key.(type) {
+	case *rsa.PrivateKey:
+		return signer, nil
+	case ed25519.PrivateKey:
+		return signer, nil
+	default:
+		return nil, errors.New("private key must be RSA or Ed25519")
+	}
+}
+
+// loadPublicKey reads a public key, or derives it from a private key file.
+func loadPublicKey(path string) (crypto.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if key, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	signer, err := loadPrivateKey(path)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key %s: %w", path, err)
+	}
+	return signer.Public(), nil
+}
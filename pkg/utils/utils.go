@@ -11,25 +11,52 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateTokens(username string, cfg *config.Config) (string, string, error) {
+// Values of the token_type claim.
+const (
+	TokenTypeAccess    = "access"
+	TokenTypeRefresh   = "refresh"
+	TokenTypeChallenge = "2fa_challenge"
+)
+
+// GenerateTokens issues an access/refresh token pair carrying the user's identity.
+func GenerateTokens(user models.User, keys *KeySet) (string, string, error) {
+	accessJTI, err := RandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
+	// iat has whole seconds only, so iat_ms carries the issue time that revocation
+	// cutoffs are compared against.
+	issuedAt := time.Now()
 	accessTokenClaims := jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(config.AccessTokenTTL).Unix(),
-		"iat":      time.Now().Unix(),
+		"jti":        accessJTI,
+		"token_type": TokenTypeAccess,
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"role":       user.Role,
+		"exp":        issuedAt.Add(config.AccessTokenTTL).Unix(),
+		"iat":        issuedAt.Unix(),
+		"iat_ms":     issuedAt.UnixMilli(),
 	}
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessTokenClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(cfg.JWTAccessSecret))
+	accessTokenString, err := keys.Sign(accessTokenClaims, false)
 	if err != nil {
 		return "", "", err
 	}
 
+	// A random jti also keeps two refresh tokens issued in the same second distinct,
+	// since they are looked up by hash.
+	refreshJTI, err := RandomHex(16)
+	if err != nil {
+		return "", "", err
+	}
 	refreshTokenClaims := jwt.MapClaims{
-		"username": username,
-		"exp":      time.Now().Add(config.AccessTokenTTL).Unix(),
-		"iat":      time.Now().Unix(),
+		"jti":        refreshJTI,
+		"token_type": TokenTypeRefresh,
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"exp":        time.Now().Add(config.RefreshTokenTTL).Unix(),
+		"iat":        time.Now().Unix(),
 	}
-	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, refreshTokenClaims)
-	refreshTokenString, err := refreshToken.SignedString([]byte(cfg.JWTRefreshSecret))
+	refreshTokenString, err := keys.Sign(refreshTokenClaims, true)
 	if err != nil {
 		return "", "", err
 	}
@@ -37,17 +64,48 @@ func GenerateTokens(username string, cfg *config.Config) (string, string, error)
 	return accessTokenString, refreshTokenString, nil
 }
 
-func ValidateToken(tokenString string, isRefresh bool, cfg *config.Config) (jwt.MapClaims, error) {
-	// Choose correct secret
-	secret := []byte(cfg.JWTAccessSecret)
-	if isRefresh {
-		secret = []byte(cfg.JWTRefreshSecret)
+// GenerateChallengeToken issues the short-lived token a user with two-factor authentication
+// enabled receives after the password step. It only proves the password was correct and
+// is rejected everywhere an access token is expected.
+func GenerateChallengeToken(user models.User, keys *KeySet) (string, error) {
+	jti, err := RandomHex(16)
+	if err != nil {
+		return "", err
+	}
+	claims := jwt.MapClaims{
+		"jti":        jti,
+		"token_type": TokenTypeChallenge,
+		"user_id":    user.ID,
+		"username":   user.Username,
+		"exp":        time.Now().Add(config.TwoFactorChallengeTTL).Unix(),
+		"iat":        time.Now().Unix(),
+	}
+	return keys.Sign(claims, false)
+}
+
+// ValidateChallengeToken verifies a token issued by GenerateChallengeToken.
+func ValidateChallengeToken(tokenString string, keys *KeySet) (jwt.MapClaims, error) {
+	token, err := keys.Parse(tokenString, false)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("token expired")
+		}
+		return nil, errors.New("invalid token")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-		return secret, nil
-	})
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+	if tokenType, _ := claims["token_type"].(string); tokenType != TokenTypeChallenge {
+		return nil, errors.New("invalid token type")
+	}
+
+	return claims, nil
+}
 
+func ValidateToken(tokenString string, isRefresh bool, keys *KeySet) (jwt.MapClaims, error) {
+	token, err := keys.Parse(tokenString, isRefresh)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
 			return nil, errors.New("token expired")
@@ -60,9 +118,109 @@ func ValidateToken(tokenString string, isRefresh bool, cfg *config.Config) (jwt.
 		return nil, errors.New("invalid token claims")
 	}
 
+	// Asymmetric keys sign both token types, so the type claim is what keeps a refresh
+	// token from being used as an access token. HS256 tokens issued before the claim
+	// existed are still told apart by their separate secrets.
+	expectedType := TokenTypeAccess
+	if isRefresh {
+		expectedType = TokenTypeRefresh
+	}
+	tokenType, hasType := claims["token_type"].(string)
+	if (hasType || keys.asymmetric()) && tokenType != expectedType {
+		return nil, errors.New("invalid token type")
+	}
+
 	return claims, nil
 }
 
+// Context keys under which AuthMiddleware stores the authenticated caller.
+const (
+	ContextUserKey   = "user"
+	ContextAPIKeyKey = "api_key"
+	// ContextRequestIDKey holds the request ID set by the RequestID middleware.
+	ContextRequestIDKey = "request_id"
+)
+
+// ClaimsFromContext returns the token claims stored by AuthMiddleware.
+func ClaimsFromContext(c *gin.Context) (jwt.MapClaims, bool) {
+	value, exists := c.Get(ContextUserKey)
+	if !exists {
+		return nil, false
+	}
+	claims, ok := value.(jwt.MapClaims)
+	return claims, ok
+}
+
+// APIKeyFromContext returns the API key that authenticated the request, if any.
+func APIKeyFromContext(c *gin.Context) (*models.APIKey, bool) {
+	value, exists := c.Get(ContextAPIKeyKey)
+	if !exists {
+		return nil, false
+	}
+	key, ok := value.(*models.APIKey)
+	return key, ok
+}
+
+// UserIDFromClaims extracts the numeric user ID from token claims.
+func UserIDFromClaims(claims jwt.MapClaims) (uint, bool) {
+	// encoding/json decodes every JSON number in MapClaims as float64
+	id, ok := claims["user_id"].(float64)
+	if !ok || id <= 0 {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// IssuedAtFromClaims returns when the token was issued, to the millisecond when it carries
+// iat_ms and to the second otherwise.
+func IssuedAtFromClaims(claims jwt.MapClaims) time.Time {
+	if ms, ok := claims["iat_ms"].(float64); ok {
+		return time.UnixMilli(int64(ms))
+	}
+	if iat, err := claims.GetIssuedAt(); err == nil && iat != nil {
+		return iat.Time
+	}
+	return time.Time{}
+}
+
+// CurrentUserID returns the ID of the authenticated user for the request.
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	claims, ok := ClaimsFromContext(c)
+	if !ok {
+		return 0, false
+	}
+	return UserIDFromClaims(claims)
+}
+
 func SendErrorResponse(c *gin.Context, code int, message string, detail string) {
 	c.JSON(code, models.NewErrorResponse(code, message, detail))
 }
+
+// CurrentRole returns the role of the authenticated user. API keys have no role.
+func CurrentRole(c *gin.Context) (string, bool) {
+	claims, ok := ClaimsFromContext(c)
+	if !ok {
+		return "", false
+	}
+	role, ok := claims["role"].(string)
+	return role, ok
+}
+
+// ActorFromContext describes who is making the request, for audit records. Requests that
+// reached an unauthenticated route yield an actor with only the request ID.
+func ActorFromContext(c *gin.Context) models.Actor {
+	actor := models.Actor{RequestID: c.GetString(ContextRequestIDKey)}
+	if key, ok := APIKeyFromContext(c); ok {
+		id := key.ID
+		actor.APIKeyID = &id
+		actor.Username = "api-key:" + key.Name
+		return actor
+	}
+	if claims, ok := ClaimsFromContext(c); ok {
+		if id, ok := UserIDFromClaims(claims); ok {
+			actor.UserID = &id
+		}
+		actor.Username, _ = claims["username"].(string)
+	}
+	return actor
+}